@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"pr-reviewer-service/internal/digest"
+	"pr-reviewer-service/internal/grpcapi"
 	"pr-reviewer-service/internal/handlers"
+	"pr-reviewer-service/internal/outbox"
 	"pr-reviewer-service/internal/repository"
 	"pr-reviewer-service/internal/service"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -34,10 +40,71 @@ func main() {
 	// Repository - работа с БД, Service - основная логика, Handlers - HTTP-запросы
 	repo := repository.NewRepository(db)
 	svc := service.NewService(repo)
+	// При деактивации одного пользователя по умолчанию переназначаю его открытые ревью
+	svc.SetReassignOnDeactivate(getEnv("REASSIGN_ON_DEACTIVATE", "true") != "false")
+	// На PR с меткой security обязательно назначаю ревьюера из SECURITY_REVIEW_TEAM, если она задана
+	svc.SetSecurityReviewTeam(os.Getenv("SECURITY_REVIEW_TEAM"))
 	h := handlers.NewHandlers(svc)
 
+	// Ответы на запросы с Idempotency-Key храню сутки (или сколько задано в окружении)
+	idempotencyTTL, err := time.ParseDuration(getEnv("IDEMPOTENCY_TTL", "24h"))
+	if err != nil {
+		log.Fatalf("Некорректный IDEMPOTENCY_TTL: %v", err)
+	}
+	go cleanupIdempotencyKeys(svc, time.Hour)
+
+	// Доменные события из outbox рассылаю по синкам из OUTBOX_SINKS
+	sinks, err := buildOutboxSinks(getEnv("OUTBOX_SINKS", "log"), svc)
+	if err != nil {
+		log.Fatalf("Некорректный OUTBOX_SINKS: %v", err)
+	}
+	outboxInterval, err := time.ParseDuration(getEnv("OUTBOX_POLL_INTERVAL", "1s"))
+	if err != nil {
+		log.Fatalf("Некорректный OUTBOX_POLL_INTERVAL: %v", err)
+	}
+	go outbox.NewRelay(svc, sinks, outboxInterval).Run(context.Background())
+	// Опубликованные события храню неделю (или сколько задано в окружении), потом удаляю
+	outboxRetention, err := time.ParseDuration(getEnv("OUTBOX_RETENTION", "168h"))
+	if err != nil {
+		log.Fatalf("Некорректный OUTBOX_RETENTION: %v", err)
+	}
+	go cleanupOutbox(svc, time.Hour, outboxRetention)
+
+	// Ежедневную сводку по ревью рассылаю, только если настроен SMTP
+	if smtpHost := os.Getenv("SMTP_HOST"); smtpHost != "" {
+		mailer := digest.NewMailer(digest.SMTPConfig{
+			Host:     smtpHost,
+			Port:     getEnv("SMTP_PORT", "25"),
+			From:     getEnv("SMTP_FROM", "pr-reviewer@localhost"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		})
+		job, err := digest.NewJob(svc, mailer.Send, getEnv("DIGEST_SEND_AT", "09:00"))
+		if err != nil {
+			log.Fatalf("Некорректный DIGEST_SEND_AT: %v", err)
+		}
+		go job.Run(context.Background())
+	}
+
+	// Раз в ESCALATION_INTERVAL ищу PR, нарушившие SLA команды на первое ревью
+	escalationInterval, err := time.ParseDuration(getEnv("ESCALATION_INTERVAL", "5m"))
+	if err != nil {
+		log.Fatalf("Некорректный ESCALATION_INTERVAL: %v", err)
+	}
+	go escalateOverdueReviews(svc, escalationInterval)
+
+	// Раз в STALE_SWEEP_INTERVAL предупреждаю о брошенных PR и закрываю совсем заброшенные
+	staleInterval, err := time.ParseDuration(getEnv("STALE_SWEEP_INTERVAL", "1h"))
+	if err != nil {
+		log.Fatalf("Некорректный STALE_SWEEP_INTERVAL: %v", err)
+	}
+	go sweepStalePullRequests(svc, staleInterval)
+
+	// gRPC поднимаю на отдельном порту поверх того же сервиса
+	go serveGRPC(svc, getEnv("GRPC_PORT", "9090"))
+
 	// Настраиваю все эндпоинты
-	router := setupRouter(h)
+	router := setupRouter(h, idempotencyTTL)
 
 	// Запускаю сервер на порту 8080 (или из переменной окружения)
 	port := os.Getenv("PORT")
@@ -120,27 +187,171 @@ func runMigrations() error {
 	return nil
 }
 
-func setupRouter(h *handlers.Handlers) *gin.Engine {
+func setupRouter(h *handlers.Handlers, idempotencyTTL time.Duration) *gin.Engine {
 	router := gin.Default()
+	router.Use(h.Idempotency(idempotencyTTL))
 
 	router.GET("/health", h.HealthCheck)
 
 	router.POST("/team/add", h.CreateTeam)
 	router.GET("/team/get", h.GetTeam)
 	router.POST("/team/bulkDeactivate", h.BulkDeactivateTeam)
+	router.POST("/team/rebalance", h.RebalanceTeam)
+	router.POST("/team/setSLA", h.SetTeamSLA)
+	router.GET("/team/getSLA", h.GetTeamSLA)
+	router.POST("/team/setStalePolicy", h.SetStalePolicy)
+	router.GET("/team/getStalePolicy", h.GetStalePolicy)
+	router.POST("/team/setReviewRules", h.SetReviewRules)
+	router.GET("/team/getReviewRules", h.GetReviewRules)
 
 	router.POST("/users/setIsActive", h.SetUserActive)
+	router.POST("/users/bulkSetIsActive", h.BulkSetUserActive)
 	router.GET("/users/getReview", h.GetReview)
+	router.GET("/users/stream", h.StreamUserEvents)
+	router.GET("/users/digest", h.GetDigest)
+	router.POST("/users/setDigestPreferences", h.SetDigestPreferences)
+	router.GET("/users/get", h.GetUser)
+	router.POST("/users/setProfile", h.SetUserProfile)
 
+	router.GET("/pullRequest/get", h.GetPullRequest)
+	router.GET("/pullRequest/list", h.ListPullRequests)
 	router.POST("/pullRequest/create", h.CreatePullRequest)
+	router.POST("/pullRequest/bulkCreate", h.BulkCreatePullRequests)
 	router.POST("/pullRequest/merge", h.MergePullRequest)
+	router.POST("/pullRequest/update", h.UpdatePullRequest)
 	router.POST("/pullRequest/reassign", h.ReassignReviewer)
+	router.POST("/pullRequest/addReviewer", h.AddReviewer)
+	router.POST("/pullRequest/removeReviewer", h.RemoveReviewer)
+	router.POST("/pullRequest/decline", h.DeclineReview)
+	router.POST("/pullRequest/review", h.ReviewPullRequest)
+	router.GET("/pullRequest/stale", h.ListStalePullRequests)
+
+	router.GET("/admin/export", h.ExportSnapshot)
+	router.POST("/admin/import", h.ImportSnapshot)
+	router.POST("/admin/escalate", h.RunEscalations)
+	router.POST("/admin/sweepStale", h.SweepStalePullRequests)
 
 	router.GET("/statistics", h.GetStatistics)
+	router.GET("/statistics/timeseries", h.GetTimeSeries)
+	router.GET("/statistics/assignments", h.GetReviewerAssignmentSeries)
+	router.GET("/statistics/teams", h.GetTeamStatistics)
+	router.GET("/statistics/turnaround", h.GetTurnaround)
+	router.GET("/statistics/leaderboard", h.GetLeaderboard)
+	router.GET("/statistics/fairness", h.GetFairness)
 
 	return router
 }
 
+// serveGRPC - gRPC-версия API. Если порт занят, падаю сразу, как и для REST.
+func serveGRPC(svc *service.Service, port string) {
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		log.Fatalf("Не удалось открыть порт gRPC %s: %v", port, err)
+	}
+
+	log.Printf("gRPC-сервер запускается на порту %s", port)
+	if err := grpcapi.NewServer(svc).Serve(listener); err != nil {
+		log.Fatalf("Не удалось запустить gRPC-сервер: %v", err)
+	}
+}
+
+// buildOutboxSinks - синки через запятую: log, webhook (OUTBOX_WEBHOOK_URL), file (OUTBOX_FILE_PATH),
+// slack (настройки каналов и шаблонов в JSON-файле SLACK_CONFIG)
+func buildOutboxSinks(names string, svc *service.Service) ([]outbox.Sink, error) {
+	var sinks []outbox.Sink
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case "log":
+			sinks = append(sinks, outbox.LogSink{})
+		case "webhook":
+			url := os.Getenv("OUTBOX_WEBHOOK_URL")
+			if url == "" {
+				return nil, fmt.Errorf("webhook sink requires OUTBOX_WEBHOOK_URL")
+			}
+			sinks = append(sinks, outbox.NewWebhookSink(url))
+		case "file":
+			sinks = append(sinks, outbox.NewFileSink(getEnv("OUTBOX_FILE_PATH", "outbox.ndjson")))
+		case "slack":
+			cfg, err := outbox.LoadSlackConfig(getEnv("SLACK_CONFIG", "slack.json"))
+			if err != nil {
+				return nil, fmt.Errorf("slack sink: %w", err)
+			}
+			sink, err := outbox.NewSlackSink(cfg, svc)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		default:
+			return nil, fmt.Errorf("unknown sink %q", name)
+		}
+	}
+	return sinks, nil
+}
+
+// cleanupIdempotencyKeys - периодически удаляю протухшие Idempotency-Key, чтобы таблица не росла
+func cleanupIdempotencyKeys(svc *service.Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := svc.CleanupIdempotencyKeys()
+		if err != nil {
+			log.Printf("Не получилось почистить Idempotency-Key: %v", err)
+			continue
+		}
+		if deleted > 0 {
+			log.Printf("Удалено протухших Idempotency-Key: %d", deleted)
+		}
+	}
+}
+
+func cleanupOutbox(svc *service.Service, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := svc.CleanupOutbox(retention)
+		if err != nil {
+			log.Printf("Не получилось почистить outbox: %v", err)
+			continue
+		}
+		if deleted > 0 {
+			log.Printf("Удалено старых событий outbox: %d", deleted)
+		}
+	}
+}
+
+func escalateOverdueReviews(svc *service.Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		escalations, err := svc.EscalateOverdueReviews()
+		if err != nil {
+			log.Printf("Не получилось эскалировать просроченные ревью: %v", err)
+		}
+		if len(escalations) > 0 {
+			log.Printf("Эскалаций по SLA: %d", len(escalations))
+		}
+	}
+}
+
+func sweepStalePullRequests(svc *service.Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		result, err := svc.SweepStalePullRequests()
+		if err != nil {
+			log.Printf("Не получилось обработать брошенные PR: %v", err)
+		}
+		if result != nil && len(result.Warned)+len(result.Closed) > 0 {
+			log.Printf("Брошенные PR: предупреждений %d, закрыто %d", len(result.Warned), len(result.Closed))
+		}
+	}
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -0,0 +1,70 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/service"
+	"time"
+)
+
+// Job - раз в день рассылает сводки. Проверяю каждую минуту, наступило ли время отправки:
+// так после рестарта или падения SMTP сводки всё равно уйдут в тот же день.
+type Job struct {
+	service  *service.Service
+	send     func(digest *models.Digest) error
+	hour     int
+	minute   int
+	interval time.Duration
+}
+
+// NewJob - sendAt в формате "ЧЧ:ММ" по UTC
+func NewJob(svc *service.Service, send func(digest *models.Digest) error, sendAt string) (*Job, error) {
+	t, err := time.Parse("15:04", sendAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest send time %q, expected HH:MM", sendAt)
+	}
+	return &Job{
+		service:  svc,
+		send:     send,
+		hour:     t.Hour(),
+		minute:   t.Minute(),
+		interval: time.Minute,
+	}, nil
+}
+
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.runOnce(time.Now().UTC())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *Job) runOnce(now time.Time) {
+	dueAt := j.lastDueAt(now)
+	sent, err := j.service.SendDueDigests(dueAt, j.send)
+	if err != nil {
+		log.Printf("Не все сводки ушли: %v", err)
+	}
+	if sent > 0 {
+		log.Printf("Отправлено сводок: %d", sent)
+	}
+}
+
+// lastDueAt - последний момент отправки, который уже наступил: сегодня или вчера
+func (j *Job) lastDueAt(now time.Time) time.Time {
+	due := time.Date(now.Year(), now.Month(), now.Day(), j.hour, j.minute, 0, 0, time.UTC)
+	if due.After(now) {
+		due = due.AddDate(0, 0, -1)
+	}
+	return due
+}
@@ -0,0 +1,54 @@
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"pr-reviewer-service/internal/models"
+	"strings"
+	"time"
+)
+
+// SMTPConfig - куда слать письма. Username пустой - без авторизации (MailHog, локальный relay).
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	From     string
+	Username string
+	Password string
+}
+
+// Mailer - отправка сводок по SMTP
+type Mailer struct {
+	cfg SMTPConfig
+}
+
+func NewMailer(cfg SMTPConfig) *Mailer {
+	return &Mailer{cfg: cfg}
+}
+
+func (m *Mailer) Send(digest *models.Digest) error {
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+	addr := net.JoinHostPort(m.cfg.Host, m.cfg.Port)
+	return smtp.SendMail(addr, auth, m.cfg.From, []string{digest.Email}, buildMessage(m.cfg.From, digest))
+}
+
+// buildMessage - простое текстовое письмо. Тема по-русски, поэтому кодирую её по RFC 2047.
+func buildMessage(from string, digest *models.Digest) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", digest.Email)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", digest.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", digest.GeneratedAt.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(digest.Body, "\n", "\r\n"))
+	return msg.Bytes()
+}
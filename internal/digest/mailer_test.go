@@ -0,0 +1,125 @@
+package digest
+
+import (
+	"bufio"
+	"mime"
+	"net"
+	"net/textproto"
+	"pr-reviewer-service/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP - минимальный SMTP-сервер: принимает одно письмо и отдаёт его в канал
+type fakeSMTP struct {
+	listener net.Listener
+	rcpt     chan string
+	data     chan string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	s := &fakeSMTP{listener: listener, rcpt: make(chan string, 1), data: make(chan string, 1)}
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTP) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			tp.PrintfLine("250 localhost")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.rcpt <- strings.Trim(line[len("RCPT TO:"):], "<>")
+			tp.PrintfLine("250 OK")
+		case strings.HasPrefix(cmd, "DATA"):
+			tp.PrintfLine("354 go ahead")
+			body, err := tp.ReadDotLines()
+			if err != nil {
+				return
+			}
+			s.data <- strings.Join(body, "\n")
+			tp.PrintfLine("250 OK")
+		case strings.HasPrefix(cmd, "QUIT"):
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+// TestMailerSend - сводка доходит до SMTP-сервера с нужным адресатом, темой и текстом
+func TestMailerSend(t *testing.T) {
+	server := newFakeSMTP(t)
+	defer server.listener.Close()
+
+	host, port, _ := net.SplitHostPort(server.listener.Addr().String())
+	mailer := NewMailer(SMTPConfig{Host: host, Port: port, From: "pr-reviewer@localhost"})
+
+	digest := &models.Digest{
+		UserID:      "u1",
+		Username:    "Alice",
+		Email:       "alice@example.com",
+		GeneratedAt: time.Now().UTC(),
+		Subject:     "Ревью: 1 открытых PR ждут вас",
+		Body:        "Привет, Alice!\n\n- Add search (pr-1) от Bob, ждёт 5 ч\n",
+	}
+	if err := mailer.Send(digest); err != nil {
+		t.Fatalf("Ошибка отправки: %v", err)
+	}
+
+	if rcpt := <-server.rcpt; rcpt != "alice@example.com" {
+		t.Errorf("Неожиданный адресат: %q", rcpt)
+	}
+
+	msg, err := textproto.NewReader(bufio.NewReader(strings.NewReader(<-server.data))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("Ошибка разбора письма: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Get("Subject"))
+	if err != nil || subject != digest.Subject {
+		t.Errorf("Неожиданная тема: %q", msg.Get("Subject"))
+	}
+	if !strings.Contains(msg.Get("Content-Type"), "charset=UTF-8") {
+		t.Errorf("Неожиданный Content-Type: %q", msg.Get("Content-Type"))
+	}
+}
+
+// TestJobLastDueAt - до времени отправки срок ещё вчерашний, после - сегодняшний
+func TestJobLastDueAt(t *testing.T) {
+	job, err := NewJob(nil, nil, "09:30")
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+
+	before := time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC)
+	if got := job.lastDueAt(before); !got.Equal(time.Date(2024, 3, 9, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("До 09:30 ожидался вчерашний срок, получено %v", got)
+	}
+	after := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	if got := job.lastDueAt(after); !got.Equal(time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("После 09:30 ожидался сегодняшний срок, получено %v", got)
+	}
+
+	if _, err := NewJob(nil, nil, "9am"); err == nil {
+		t.Error("Ожидалась ошибка для некорректного времени")
+	}
+}
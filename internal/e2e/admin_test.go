@@ -0,0 +1,260 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// TestSnapshotImportModes - импортирую снимок с новой командой и PR, потом тот же снимок
+// в режимах fail, skip и overwrite
+func TestSnapshotImportModes(t *testing.T) {
+	teamName := generateID("team-snapshot")
+	authorID := generateID("author-snapshot")
+	reviewerID := generateID("reviewer-snapshot")
+	prID := generateID("pr-snapshot")
+
+	snapshot := map[string]interface{}{
+		"format_version": 1,
+		"teams": []map[string]interface{}{
+			{
+				"team_name": teamName,
+				"members": []map[string]interface{}{
+					{"user_id": authorID, "username": "Author", "is_active": true},
+					{"user_id": reviewerID, "username": "Reviewer", "is_active": true},
+				},
+			},
+		},
+		"pull_requests": []map[string]interface{}{
+			{
+				"pull_request_id":    prID,
+				"pull_request_name":  "Imported PR",
+				"author_id":          authorID,
+				"status":             "MERGED",
+				"assigned_reviewers": []string{reviewerID},
+				"createdAt":          "2025-01-10T10:00:00Z",
+				"mergedAt":           "2025-01-11T10:00:00Z",
+			},
+		},
+	}
+
+	importSnapshot := func(mode string) (int, map[string]interface{}) {
+		body, _ := json.Marshal(snapshot)
+		req, _ := http.NewRequest("POST", baseURL+"/admin/import?mode="+mode, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+
+	status, result := importSnapshot("fail")
+	if status != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d: %v", status, result)
+	}
+	if result["teams_created"].(float64) != 1 || result["prs_created"].(float64) != 1 {
+		t.Errorf("Ожидались созданные команда и PR, получено %v", result)
+	}
+
+	// PR сохранился со статусом и ревьюером из снимка
+	resp, err := httpClient.Get(baseURL + "/pullRequest/get?pull_request_id=" + prID)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer resp.Body.Close()
+	var got map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&got)
+	pr := got["pr"].(map[string]interface{})
+	if pr["status"] != "MERGED" || len(pr["assigned_reviewers"].([]interface{})) != 1 {
+		t.Errorf("PR импортирован неверно: %v", pr)
+	}
+
+	if status, _ := importSnapshot("fail"); status != http.StatusConflict {
+		t.Errorf("Повторный импорт в режиме fail: ожидался статус 409, получен %d", status)
+	}
+
+	status, result = importSnapshot("skip")
+	if status != http.StatusOK || result["teams_skipped"].(float64) != 1 || result["prs_skipped"].(float64) != 1 {
+		t.Errorf("В режиме skip ожидались пропущенные команда и PR, получено %d %v", status, result)
+	}
+
+	status, result = importSnapshot("overwrite")
+	if status != http.StatusOK || result["teams_updated"].(float64) != 1 || result["prs_updated"].(float64) != 1 {
+		t.Errorf("В режиме overwrite ожидались обновлённые команда и PR, получено %d %v", status, result)
+	}
+
+	// Выгрузка должна содержать импортированную команду
+	resp, err = httpClient.Get(baseURL + "/admin/export")
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer resp.Body.Close()
+	var exported map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&exported)
+	found := false
+	for _, team := range exported["teams"].([]interface{}) {
+		if team.(map[string]interface{})["team_name"] == teamName {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Команда %s не попала в выгрузку", teamName)
+	}
+}
+
+// TestSnapshotSettings - профили, правила подбора, SLA, политика брошенных PR и подписка на сводку
+// загружаются вместе с командой и снова попадают в выгрузку; правила с чужим пользователем - 400
+func TestSnapshotSettings(t *testing.T) {
+	teamName := generateID("team-snapshot-settings")
+	authorID := generateID("author-snapshot-settings")
+	seniorID := generateID("senior-snapshot-settings")
+	juniorID := generateID("junior-snapshot-settings")
+
+	snapshot := map[string]interface{}{
+		"format_version": 1,
+		"teams": []map[string]interface{}{
+			{
+				"team_name": teamName,
+				"members": []map[string]interface{}{
+					{"user_id": authorID, "username": "Author", "is_active": true},
+					{"user_id": seniorID, "username": "Senior", "is_active": true},
+					{"user_id": juniorID, "username": "Junior", "is_active": true},
+				},
+			},
+		},
+		"pull_requests": []map[string]interface{}{},
+		"profiles": []map[string]interface{}{
+			{"user_id": seniorID, "seniority": "senior", "skills": []string{"go"}},
+		},
+		// require_senior без seniors проходит проверку только благодаря профилю из того же снимка
+		"review_rules": []map[string]interface{}{
+			{"team_name": teamName, "never_review": []map[string]string{{"user_a": authorID, "user_b": juniorID}}, "require_senior": true},
+		},
+		"slas":               []map[string]interface{}{{"team_name": teamName, "first_review_hours": 24, "action": "add_reviewer"}},
+		"stale_policies":     []map[string]interface{}{{"team_name": teamName, "warn_after_days": 7, "close_after_days": 30}},
+		"digest_preferences": []map[string]interface{}{{"user_id": seniorID, "email": "senior@example.com", "digest_enabled": true}},
+	}
+
+	importSnapshot := func() (int, map[string]interface{}) {
+		body, _ := json.Marshal(snapshot)
+		req, _ := http.NewRequest("POST", baseURL+"/admin/import?mode=overwrite", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+	get := func(path string, out interface{}) {
+		resp, err := httpClient.Get(baseURL + path)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Ожидался статус 200 от %s, получен %d", path, resp.StatusCode)
+		}
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+
+	status, result := importSnapshot()
+	if status != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d: %v", status, result)
+	}
+	if result["settings_imported"].(float64) != 5 {
+		t.Errorf("Ожидалось 5 загруженных настроек, получено %v", result["settings_imported"])
+	}
+
+	var user struct {
+		User struct {
+			Seniority string   `json:"seniority"`
+			Skills    []string `json:"skills"`
+		} `json:"user"`
+	}
+	get("/users/get?user_id="+seniorID, &user)
+	if user.User.Seniority != "senior" || len(user.User.Skills) != 1 {
+		t.Errorf("Профиль не загрузился: %+v", user.User)
+	}
+	var rules struct {
+		NeverReview   []interface{} `json:"never_review"`
+		RequireSenior bool          `json:"require_senior"`
+	}
+	get("/team/getReviewRules?team_name="+teamName, &rules)
+	if !rules.RequireSenior || len(rules.NeverReview) != 1 {
+		t.Errorf("Правила не загрузились: %+v", rules)
+	}
+	var sla struct {
+		FirstReviewHours float64 `json:"first_review_hours"`
+		Action           string  `json:"action"`
+	}
+	get("/team/getSLA?team_name="+teamName, &sla)
+	if sla.FirstReviewHours != 24 || sla.Action != "add_reviewer" {
+		t.Errorf("SLA не загрузился: %+v", sla)
+	}
+	var policy struct {
+		WarnAfterDays int `json:"warn_after_days"`
+	}
+	get("/team/getStalePolicy?team_name="+teamName, &policy)
+	if policy.WarnAfterDays != 7 {
+		t.Errorf("Политика брошенных PR не загрузилась: %+v", policy)
+	}
+
+	// Всё загруженное снова есть в выгрузке
+	var exported struct {
+		Profiles []struct {
+			UserID string `json:"user_id"`
+		} `json:"profiles"`
+		ReviewRules []struct {
+			TeamName string `json:"team_name"`
+		} `json:"review_rules"`
+		SLAs []struct {
+			TeamName string `json:"team_name"`
+		} `json:"slas"`
+		StalePolicies []struct {
+			TeamName string `json:"team_name"`
+		} `json:"stale_policies"`
+		DigestPreferences []struct {
+			UserID        string `json:"user_id"`
+			Email         string `json:"email"`
+			DigestEnabled bool   `json:"digest_enabled"`
+		} `json:"digest_preferences"`
+	}
+	get("/admin/export", &exported)
+	found := map[string]bool{}
+	for _, p := range exported.Profiles {
+		found["profile"] = found["profile"] || p.UserID == seniorID
+	}
+	for _, r := range exported.ReviewRules {
+		found["rules"] = found["rules"] || r.TeamName == teamName
+	}
+	for _, s := range exported.SLAs {
+		found["sla"] = found["sla"] || s.TeamName == teamName
+	}
+	for _, p := range exported.StalePolicies {
+		found["stale"] = found["stale"] || p.TeamName == teamName
+	}
+	for _, d := range exported.DigestPreferences {
+		found["digest"] = found["digest"] || (d.UserID == seniorID && d.Email == "senior@example.com" && d.DigestEnabled)
+	}
+	for _, kind := range []string{"profile", "rules", "sla", "stale", "digest"} {
+		if !found[kind] {
+			t.Errorf("В выгрузке нет %s команды %s", kind, teamName)
+		}
+	}
+
+	// Пользователь из чужой команды в правилах - снимок целиком отклоняется
+	snapshot["review_rules"] = []map[string]interface{}{
+		{"team_name": teamName, "preferred_pairs": []map[string]string{{"reviewer_id": "someone-else", "author_id": authorID}}},
+	}
+	if status, result := importSnapshot(); status != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 для правил с чужим пользователем, получен %d: %v", status, result)
+	}
+}
@@ -0,0 +1,211 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// TestBulkSetIsActive - активация добирает ревьюеров на PR с needMoreReviewers,
+// а деактивация по списку переназначает открытые ревью
+func TestBulkSetIsActive(t *testing.T) {
+	teamName := generateID("team-bulk-active")
+	authorID := generateID("author-bulk-active")
+	reviewer1ID := generateID("reviewer1-bulk-active")
+	reviewer2ID := generateID("reviewer2-bulk-active")
+	reviewer3ID := generateID("reviewer3-bulk-active")
+	prID := generateID("pr-bulk-active")
+	teamData := map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": reviewer1ID, "username": "Reviewer 1", "is_active": true},
+			{"user_id": reviewer2ID, "username": "Reviewer 2", "is_active": false},
+			{"user_id": reviewer3ID, "username": "Reviewer 3", "is_active": false},
+		},
+	}
+
+	body, _ := json.Marshal(teamData)
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	prData := map[string]string{
+		"pull_request_id":   prID,
+		"pull_request_name": "Bulk Active PR",
+		"author_id":         authorID,
+	}
+	body, _ = json.Marshal(prData)
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/create", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+
+	bulkSetIsActive := func(data map[string]interface{}) map[string]interface{} {
+		body, _ := json.Marshal(data)
+		req, _ := http.NewRequest("POST", baseURL+"/users/bulkSetIsActive", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Ожидался статус 200, получен %d", resp.StatusCode)
+		}
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return result
+	}
+
+	// Активирую второго и третьего по команде, исключив автора и первого ревьюера
+	result := bulkSetIsActive(map[string]interface{}{
+		"team_name": teamName,
+		"filter":    map[string]interface{}{"exclude_user_ids": []string{authorID, reviewer1ID}},
+		"is_active": true,
+	})
+	if len(result["updated_user_ids"].([]interface{})) != 2 {
+		t.Errorf("Ожидалось 2 активированных, получено %v", result["updated_user_ids"])
+	}
+	backfilled := result["backfilled_reviewers"].([]interface{})
+	if len(backfilled) != 1 {
+		t.Fatalf("Ожидался 1 добавленный ревьюер, получено %v", backfilled)
+	}
+	addedID := backfilled[0].(map[string]interface{})["reviewer_id"].(string)
+
+	resp, err = httpClient.Get(baseURL + "/pullRequest/get?pull_request_id=" + prID)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var got map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	pr := got["pr"].(map[string]interface{})
+	if len(pr["assigned_reviewers"].([]interface{})) != 2 || pr["needMoreReviewers"].(bool) {
+		t.Errorf("После активации ожидалось 2 ревьюера без needMoreReviewers, получено %v", pr)
+	}
+
+	// Деактивирую первого ревьюера вместе с уже неактивным и несуществующим
+	missingID := generateID("missing-bulk-active")
+	result = bulkSetIsActive(map[string]interface{}{
+		"user_ids":  []string{reviewer1ID, missingID},
+		"is_active": false,
+	})
+	if len(result["not_found_user_ids"].([]interface{})) != 1 {
+		t.Errorf("Ожидался 1 ненайденный пользователь, получено %v", result["not_found_user_ids"])
+	}
+	reassignments := result["reassignments"].([]interface{})
+	if len(reassignments) != 1 {
+		t.Fatalf("Ожидалось 1 переназначение, получено %v", reassignments)
+	}
+	newReviewerID := reassignments[0].(map[string]interface{})["new_reviewer_id"].(string)
+	if newReviewerID == reviewer1ID || newReviewerID == addedID || newReviewerID == authorID {
+		t.Errorf("Неожиданный новый ревьюер %s", newReviewerID)
+	}
+}
+
+// TestSetIsActiveReassignsReviews - деактивация одного ревьюера переназначает его открытые ревью,
+// а с reassign_reviews=false оставляет как есть
+func TestSetIsActiveReassignsReviews(t *testing.T) {
+	teamName := generateID("team-set-active")
+	authorID := generateID("author-set-active")
+	reviewer1ID := generateID("reviewer1-set-active")
+	reviewer2ID := generateID("reviewer2-set-active")
+	reviewer3ID := generateID("reviewer3-set-active")
+	prID := generateID("pr-set-active")
+	teamData := map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": reviewer1ID, "username": "Reviewer 1", "is_active": true},
+			{"user_id": reviewer2ID, "username": "Reviewer 2", "is_active": true},
+			{"user_id": reviewer3ID, "username": "Reviewer 3", "is_active": true},
+		},
+	}
+
+	body, _ := json.Marshal(teamData)
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	prData := map[string]string{
+		"pull_request_id":   prID,
+		"pull_request_name": "Set Active PR",
+		"author_id":         authorID,
+	}
+	body, _ = json.Marshal(prData)
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/create", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var created map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	reviewers := created["pr"].(map[string]interface{})["assigned_reviewers"].([]interface{})
+	if len(reviewers) != 2 {
+		t.Fatalf("Ожидалось 2 ревьюера, получено %v", reviewers)
+	}
+
+	setIsActive := func(data map[string]interface{}) map[string]interface{} {
+		body, _ := json.Marshal(data)
+		req, _ := http.NewRequest("POST", baseURL+"/users/setIsActive", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Ожидался статус 200, получен %d", resp.StatusCode)
+		}
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return result
+	}
+
+	// Первый назначенный уходит, его место должен занять единственный свободный
+	firstID := reviewers[0].(string)
+	result := setIsActive(map[string]interface{}{"user_id": firstID, "is_active": false})
+	reassigned := result["reassigned_prs"].([]interface{})
+	if len(reassigned) != 1 || reassigned[0].(string) != prID {
+		t.Fatalf("Ожидался переназначенный %s, получено %v", prID, reassigned)
+	}
+	newReviewerID := result["reassignments"].([]interface{})[0].(map[string]interface{})["new_reviewer_id"].(string)
+	if newReviewerID == firstID || newReviewerID == authorID || newReviewerID == reviewers[1].(string) {
+		t.Errorf("Неожиданный новый ревьюер %s", newReviewerID)
+	}
+
+	// Второй уходит без переназначения - PR остаётся как был
+	secondID := reviewers[1].(string)
+	result = setIsActive(map[string]interface{}{"user_id": secondID, "is_active": false, "reassign_reviews": false})
+	if len(result["reassigned_prs"].([]interface{})) != 0 {
+		t.Errorf("Переназначений быть не должно, получено %v", result["reassigned_prs"])
+	}
+
+	resp, err = httpClient.Get(baseURL + "/pullRequest/get?pull_request_id=" + prID)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var got map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	assigned := got["pr"].(map[string]interface{})["assigned_reviewers"].([]interface{})
+	if len(assigned) != 2 || !containsID(assigned, secondID) || !containsID(assigned, newReviewerID) {
+		t.Errorf("Ожидались ревьюеры %s и %s, получено %v", secondID, newReviewerID, assigned)
+	}
+}
+
+func containsID(ids []interface{}, id string) bool {
+	for _, v := range ids {
+		if v.(string) == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// TestBulkCreatePullRequests - пакет из новых PR, дубликата и PR с несуществующим автором.
+// Ошибочные не должны мешать созданию остальных.
+func TestBulkCreatePullRequests(t *testing.T) {
+	teamName := generateID("team-bulk-create")
+	authorID := generateID("author-bulk-create")
+	teamData := map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": generateID("reviewer1-bulk-create"), "username": "Reviewer 1", "is_active": true},
+			{"user_id": generateID("reviewer2-bulk-create"), "username": "Reviewer 2", "is_active": true},
+		},
+	}
+
+	body, _ := json.Marshal(teamData)
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	var items []map[string]string
+	for i := 0; i < 10; i++ {
+		items = append(items, map[string]string{
+			"pull_request_id":   generateID(fmt.Sprintf("pr%d-bulk-create", i)),
+			"pull_request_name": fmt.Sprintf("Bulk PR %d", i),
+			"author_id":         authorID,
+		})
+	}
+	items = append(items, items[0])
+	items = append(items, map[string]string{
+		"pull_request_id":   generateID("pr-orphan-bulk-create"),
+		"pull_request_name": "Orphan PR",
+		"author_id":         generateID("missing-author-bulk-create"),
+	})
+
+	body, _ = json.Marshal(map[string]interface{}{"pull_requests": items})
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/bulkCreate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", resp.StatusCode)
+	}
+
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	if result["created"].(float64) != 10 || result["failed"].(float64) != 2 {
+		t.Errorf("Ожидалось 10 созданных и 2 ошибки, получено %v/%v", result["created"], result["failed"])
+	}
+
+	results := result["results"].([]interface{})
+	for i, r := range results[:10] {
+		item := r.(map[string]interface{})
+		pr := item["pr"].(map[string]interface{})
+		if len(pr["assigned_reviewers"].([]interface{})) != 2 {
+			t.Errorf("PR %d: ожидалось 2 ревьюера, получено %v", i, pr["assigned_reviewers"])
+		}
+	}
+	if code := results[10].(map[string]interface{})["error_code"]; code != "PR_EXISTS" {
+		t.Errorf("Для дубликата ожидался PR_EXISTS, получено %v", code)
+	}
+	if code := results[11].(map[string]interface{})["error_code"]; code != "NOT_FOUND" {
+		t.Errorf("Для PR без автора ожидался NOT_FOUND, получено %v", code)
+	}
+
+	// Созданные PR видны по одному
+	resp, err = httpClient.Get(baseURL + "/pullRequest/get?pull_request_id=" + items[5]["pull_request_id"])
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Ожидался статус 200, получен %d", resp.StatusCode)
+	}
+}
+
+// TestBulkCreateLabelsAndPriority - метки и приоритет в пакете проверяются и сохраняются, как в /pullRequest/create,
+// и ревьюеры выбираются тем же путём: при match_skills ставится ревьюер с навыком,
+// а urgent-PR из одного пакета расходятся по разным свободным ревьюерам.
+func TestBulkCreateLabelsAndPriority(t *testing.T) {
+	teamName := generateID("team-bulk-labels")
+	authorID := generateID("author-bulk-labels")
+	skilledID := generateID("skilled-bulk-labels")
+	members := []map[string]interface{}{}
+	for _, id := range []string{authorID, skilledID, generateID("reviewer1-bulk-labels"), generateID("reviewer2-bulk-labels"), generateID("reviewer3-bulk-labels")} {
+		members = append(members, map[string]interface{}{"user_id": id, "username": id, "is_active": true})
+	}
+
+	post := func(path string, payload map[string]interface{}) (int, map[string]interface{}) {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", baseURL+path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+
+	post("/team/add", map[string]interface{}{"team_name": teamName, "members": members})
+	if status, _ := post("/users/setProfile", map[string]interface{}{"user_id": skilledID, "skills": []string{"payments"}}); status != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", status)
+	}
+	if status, _ := post("/team/setReviewRules", map[string]interface{}{"team_name": teamName, "match_skills": true}); status != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", status)
+	}
+
+	var items []map[string]interface{}
+	for i := 0; i < 3; i++ {
+		items = append(items, map[string]interface{}{
+			"pull_request_id":   generateID(fmt.Sprintf("pr%d-bulk-labels", i)),
+			"pull_request_name": fmt.Sprintf("Payments %d", i),
+			"author_id":         authorID,
+			"labels":            []string{" payments ", "payments"},
+			"priority":          "urgent",
+		})
+	}
+	items = append(items, map[string]interface{}{
+		"pull_request_id":   generateID("pr-bad-priority-bulk-labels"),
+		"pull_request_name": "Bad priority",
+		"author_id":         authorID,
+		"priority":          "asap",
+	})
+
+	status, result := post("/pullRequest/bulkCreate", map[string]interface{}{"pull_requests": items})
+	if status != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", status)
+	}
+	if result["created"].(float64) != 3 || result["failed"].(float64) != 1 {
+		t.Errorf("Ожидалось 3 созданных и 1 ошибка, получено %v/%v", result["created"], result["failed"])
+	}
+
+	results := result["results"].([]interface{})
+	others := make(map[string]bool)
+	for i, r := range results[:3] {
+		pr := r.(map[string]interface{})["pr"].(map[string]interface{})
+		reviewers := pr["assigned_reviewers"].([]interface{})
+		if len(reviewers) != 2 || !containsID(reviewers, skilledID) {
+			t.Errorf("PR %d: ожидались 2 ревьюера, среди них %s, получено %v", i, skilledID, reviewers)
+			continue
+		}
+		for _, reviewer := range reviewers {
+			if reviewer.(string) != skilledID {
+				others[reviewer.(string)] = true
+			}
+		}
+	}
+	if len(others) != 3 {
+		t.Errorf("urgent-PR пакета должны разойтись по трём разным ревьюерам, получено %v", others)
+	}
+	bad := results[3].(map[string]interface{})
+	if bad["error_code"] != "NOT_FOUND" || bad["message"] != "invalid priority: must be low, normal, high or urgent" {
+		t.Errorf("Для неверного приоритета ожидалась ошибка в результате, получено %v", bad)
+	}
+
+	resp, err := httpClient.Get(baseURL + "/pullRequest/get?pull_request_id=" + items[0]["pull_request_id"].(string))
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var got struct {
+		PR struct {
+			Labels   []string `json:"labels"`
+			Priority string   `json:"priority"`
+		} `json:"pr"`
+	}
+	json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	if len(got.PR.Labels) != 1 || got.PR.Labels[0] != "payments" || got.PR.Priority != "urgent" {
+		t.Errorf("Метки и приоритет не сохранились: %+v", got.PR)
+	}
+}
@@ -0,0 +1,181 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentCreatePullRequest - много параллельных запросов на создание одного PR.
+// Создаться должен ровно один, остальные получают PR_EXISTS.
+func TestConcurrentCreatePullRequest(t *testing.T) {
+	teamName := generateID("team-concurrent-create")
+	authorID := generateID("author-concurrent-create")
+	prID := generateID("pr-concurrent-create")
+	teamData := map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": generateID("reviewer1-concurrent-create"), "username": "Reviewer 1", "is_active": true},
+			{"user_id": generateID("reviewer2-concurrent-create"), "username": "Reviewer 2", "is_active": true},
+		},
+	}
+
+	body, _ := json.Marshal(teamData)
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	prData := map[string]string{
+		"pull_request_id":   prID,
+		"pull_request_name": "Concurrent PR",
+		"author_id":         authorID,
+	}
+	body, _ = json.Marshal(prData)
+
+	const workers = 20
+	statuses := make(chan int, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("POST", baseURL+"/pullRequest/create", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := httpClient.Do(req)
+			if err != nil {
+				statuses <- 0
+				return
+			}
+			resp.Body.Close()
+			statuses <- resp.StatusCode
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	created := 0
+	for status := range statuses {
+		switch status {
+		case http.StatusCreated:
+			created++
+		case http.StatusConflict:
+		default:
+			t.Errorf("Неожиданный статус %d", status)
+		}
+	}
+	if created != 1 {
+		t.Errorf("Ожидался ровно один созданный PR, создано %d", created)
+	}
+}
+
+// TestConcurrentReassign - долблю переназначениями один и тот же PR параллельно.
+// В итоге на PR должно остаться ровно два разных ревьюера, и ни одного 5xx.
+func TestConcurrentReassign(t *testing.T) {
+	teamName := generateID("team-concurrent-reassign")
+	authorID := generateID("author-concurrent-reassign")
+	prID := generateID("pr-concurrent-reassign")
+
+	members := []map[string]interface{}{
+		{"user_id": authorID, "username": "Author", "is_active": true},
+	}
+	var memberIDs []string
+	for i := 1; i <= 6; i++ {
+		userID := generateID(fmt.Sprintf("reviewer%d-concurrent-reassign", i))
+		memberIDs = append(memberIDs, userID)
+		members = append(members, map[string]interface{}{"user_id": userID, "username": fmt.Sprintf("Reviewer %d", i), "is_active": true})
+	}
+	teamData := map[string]interface{}{"team_name": teamName, "members": members}
+
+	body, _ := json.Marshal(teamData)
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	prData := map[string]string{
+		"pull_request_id":   prID,
+		"pull_request_name": "Concurrent Reassign PR",
+		"author_id":         authorID,
+	}
+	body, _ = json.Marshal(prData)
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/create", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Ожидался статус 201, получен %d", resp.StatusCode)
+	}
+
+	// Каждый воркер пытается снять "своего" участника команды. Часть запросов честно
+	// получит NOT_ASSIGNED, но ни один не должен сломать инварианты PR.
+	const rounds = 5
+	var wg sync.WaitGroup
+	serverErrors := make(chan string, rounds*len(memberIDs))
+	for round := 0; round < rounds; round++ {
+		for _, memberID := range memberIDs {
+			wg.Add(1)
+			go func(oldUserID string) {
+				defer wg.Done()
+				data, _ := json.Marshal(map[string]string{
+					"pull_request_id": prID,
+					"old_user_id":     oldUserID,
+				})
+				req, _ := http.NewRequest("POST", baseURL+"/pullRequest/reassign", bytes.NewBuffer(data))
+				req.Header.Set("Content-Type", "application/json")
+				resp, err := httpClient.Do(req)
+				if err != nil {
+					serverErrors <- err.Error()
+					return
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode >= http.StatusInternalServerError {
+					bodyBytes, _ := io.ReadAll(resp.Body)
+					serverErrors <- fmt.Sprintf("%d: %s", resp.StatusCode, string(bodyBytes))
+				}
+			}(memberID)
+		}
+	}
+	wg.Wait()
+	close(serverErrors)
+
+	for e := range serverErrors {
+		t.Errorf("Ошибка сервера при параллельном переназначении: %s", e)
+	}
+
+	// Мерж возвращает итоговое состояние PR
+	body, _ = json.Marshal(map[string]string{"pull_request_id": prID})
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/merge", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	pr := result["pr"].(map[string]interface{})
+	reviewers := pr["assigned_reviewers"].([]interface{})
+
+	if len(reviewers) != 2 {
+		t.Errorf("Ожидалось ровно 2 ревьюера, получено %d: %v", len(reviewers), reviewers)
+	}
+	seen := make(map[string]bool)
+	for _, r := range reviewers {
+		id := r.(string)
+		if id == authorID {
+			t.Error("Автор не должен быть ревьюером")
+		}
+		if seen[id] {
+			t.Errorf("Ревьюер %s назначен дважды", id)
+		}
+		seen[id] = true
+	}
+}
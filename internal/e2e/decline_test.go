@@ -0,0 +1,128 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// TestDeclineReview - ревьюер отказывается от PR, получает замену, а отказавшихся
+// на этот PR больше не назначают. Отказы видны в PR и в статистике.
+func TestDeclineReview(t *testing.T) {
+	teamName := generateID("team-decline")
+	authorID := generateID("author-decline")
+	reviewerIDs := []string{generateID("decline-r1"), generateID("decline-r2"), generateID("decline-r3")}
+	prID := generateID("pr-decline")
+
+	members := []map[string]interface{}{{"user_id": authorID, "username": "Author", "is_active": true}}
+	for _, id := range reviewerIDs {
+		members = append(members, map[string]interface{}{"user_id": id, "username": id, "is_active": true})
+	}
+	body, _ := json.Marshal(map[string]interface{}{"team_name": teamName, "members": members})
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	type declineResponse struct {
+		PR struct {
+			AssignedReviewers []interface{} `json:"assigned_reviewers"`
+			NeedMoreReviewers bool          `json:"needMoreReviewers"`
+		} `json:"pr"`
+		ReplacedBy string `json:"replaced_by"`
+		Error      struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	post := func(path string, payload map[string]interface{}) (int, declineResponse) {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", baseURL+path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		defer resp.Body.Close()
+		var result declineResponse
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+
+	status, created := post("/pullRequest/create", map[string]interface{}{
+		"pull_request_id":   prID,
+		"pull_request_name": "Decline me",
+		"author_id":         authorID,
+	})
+	if status != http.StatusCreated || len(created.PR.AssignedReviewers) != 2 {
+		t.Fatalf("Неожиданный PR (%d): %+v", status, created.PR)
+	}
+	first, second := created.PR.AssignedReviewers[0].(string), created.PR.AssignedReviewers[1].(string)
+	var freeID string
+	for _, id := range reviewerIDs {
+		if id != first && id != second {
+			freeID = id
+		}
+	}
+
+	if status, _ := post("/pullRequest/decline", map[string]interface{}{"pull_request_id": prID, "reviewer_id": first, "reason": "  "}); status != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 для пустой причины, получен %d", status)
+	}
+	if status, result := post("/pullRequest/decline", map[string]interface{}{"pull_request_id": prID, "reviewer_id": freeID, "reason": "busy"}); status != http.StatusConflict || result.Error.Code != "NOT_ASSIGNED" {
+		t.Errorf("Ожидался 409 NOT_ASSIGNED, получен %d %s", status, result.Error.Code)
+	}
+
+	// Первый отказ: единственный свободный становится заменой
+	status, result := post("/pullRequest/decline", map[string]interface{}{"pull_request_id": prID, "reviewer_id": first, "reason": "Не знаю этот модуль"})
+	if status != http.StatusOK || result.ReplacedBy != freeID || containsID(result.PR.AssignedReviewers, first) {
+		t.Fatalf("Неожиданный ответ на первый отказ (%d): %+v", status, result)
+	}
+
+	// Второй отказ: отказавшегося первым обратно не назначают, так что замены нет
+	status, result = post("/pullRequest/decline", map[string]interface{}{"pull_request_id": prID, "reviewer_id": second, "reason": "В отпуске"})
+	if status != http.StatusOK || result.ReplacedBy != "" || len(result.PR.AssignedReviewers) != 1 || !result.PR.NeedMoreReviewers {
+		t.Fatalf("Неожиданный ответ на второй отказ (%d): %+v", status, result)
+	}
+
+	resp, err := httpClient.Get(baseURL + "/pullRequest/get?pull_request_id=" + prID)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var got struct {
+		PR struct {
+			Declines []struct {
+				ReviewerID string `json:"reviewer_id"`
+				Reason     string `json:"reason"`
+				ReplacedBy string `json:"replaced_by"`
+			} `json:"declines"`
+		} `json:"pr"`
+	}
+	json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	if len(got.PR.Declines) != 2 || got.PR.Declines[0].ReviewerID != first || got.PR.Declines[0].ReplacedBy != freeID {
+		t.Errorf("Неожиданные отказы в PR: %+v", got.PR.Declines)
+	}
+
+	resp, err = httpClient.Get(baseURL + "/statistics?team_name=" + teamName)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var stats struct {
+		UserStats []struct {
+			UserID   string `json:"user_id"`
+			Declines int    `json:"declines"`
+		} `json:"user_stats"`
+		PRStats struct {
+			TotalDeclines int `json:"total_declines"`
+		} `json:"pr_stats"`
+	}
+	json.NewDecoder(resp.Body).Decode(&stats)
+	resp.Body.Close()
+	if stats.PRStats.TotalDeclines != 2 {
+		t.Errorf("Ожидалось 2 отказа в pr_stats, получено %d", stats.PRStats.TotalDeclines)
+	}
+	for _, stat := range stats.UserStats {
+		if stat.UserID == first && stat.Declines != 1 {
+			t.Errorf("У %s ожидался 1 отказ, получено %d", first, stat.Declines)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestDigest - превью сводки показывает открытый PR ревьюера, а подписка без почты не включается
+func TestDigest(t *testing.T) {
+	teamName := generateID("team-digest")
+	authorID := generateID("author-digest")
+	reviewerID := generateID("reviewer-digest")
+	prID := generateID("pr-digest")
+	teamData := map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": reviewerID, "username": "Reviewer", "is_active": true},
+		},
+	}
+
+	body, _ := json.Marshal(teamData)
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	prData := map[string]string{
+		"pull_request_id":   prID,
+		"pull_request_name": "Digest PR",
+		"author_id":         authorID,
+	}
+	body, _ = json.Marshal(prData)
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/create", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+
+	// Без почты включить сводку нельзя
+	body, _ = json.Marshal(map[string]interface{}{"user_id": reviewerID, "digest_enabled": true})
+	req, _ = http.NewRequest("POST", baseURL+"/users/setDigestPreferences", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 без почты, получен %d", resp.StatusCode)
+	}
+
+	body, _ = json.Marshal(map[string]interface{}{"user_id": reviewerID, "email": "reviewer@example.com", "digest_enabled": true})
+	req, _ = http.NewRequest("POST", baseURL+"/users/setDigestPreferences", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var prefs map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&prefs)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || prefs["digest_enabled"] != true {
+		t.Fatalf("Ожидалась включённая сводка, получено %d %v", resp.StatusCode, prefs)
+	}
+
+	resp, err = httpClient.Get(baseURL + "/users/digest?user_id=" + reviewerID)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var digest struct {
+		Email        string `json:"email"`
+		Body         string `json:"body"`
+		PullRequests []struct {
+			PullRequestID     string `json:"pull_request_id"`
+			AuthorName        string `json:"author_name"`
+			NeedMoreReviewers bool   `json:"needMoreReviewers"`
+		} `json:"pull_requests"`
+	}
+	json.NewDecoder(resp.Body).Decode(&digest)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", resp.StatusCode)
+	}
+
+	if digest.Email != "reviewer@example.com" || len(digest.PullRequests) != 1 {
+		t.Fatalf("Ожидался один PR в сводке для reviewer@example.com, получено %+v", digest)
+	}
+	item := digest.PullRequests[0]
+	// В команде один кандидат, так что PR ждёт ещё ревьюеров
+	if item.PullRequestID != prID || item.AuthorName != "Author" || !item.NeedMoreReviewers {
+		t.Errorf("Неожиданный PR в сводке: %+v", item)
+	}
+	if !strings.Contains(digest.Body, prID) {
+		t.Errorf("В тексте письма нет %s: %q", prID, digest.Body)
+	}
+
+	resp, err = httpClient.Get(baseURL + "/users/digest?user_id=" + generateID("nobody"))
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Ожидался статус 404 для неизвестного пользователя, получен %d", resp.StatusCode)
+	}
+}
@@ -605,3 +605,376 @@ func TestInactiveUserNotAssigned(t *testing.T) {
 		}
 	}
 }
+
+func TestBulkDeactivateDryRun(t *testing.T) {
+	teamName := generateID("team-dry-run")
+	authorID := generateID("author-dry-run")
+	reviewerID := generateID("reviewer-dry-run")
+	teamData := map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": reviewerID, "username": "Reviewer", "is_active": true},
+		},
+	}
+
+	body, _ := json.Marshal(teamData)
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	bulkData := map[string]interface{}{"team_name": teamName, "dry_run": true}
+	body, _ = json.Marshal(bulkData)
+	req, _ = http.NewRequest("POST", baseURL+"/team/bulkDeactivate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", resp.StatusCode)
+	}
+
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	if result["dry_run"] != true {
+		t.Error("Ответ должен быть помечен dry_run")
+	}
+	if ids, _ := result["deactivated_user_ids"].([]interface{}); len(ids) != 2 {
+		t.Errorf("Ожидалось 2 пользователя к деактивации, получено %d", len(ids))
+	}
+
+	// После dry_run все участники должны остаться активными
+	req, _ = http.NewRequest("GET", baseURL+"/team/get?team_name="+teamName, nil)
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var team map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&team)
+	for _, m := range team["members"].([]interface{}) {
+		member := m.(map[string]interface{})
+		if member["is_active"].(bool) != true {
+			t.Errorf("Пользователь %s не должен быть деактивирован в режиме dry_run", member["user_id"])
+		}
+	}
+}
+
+func TestReassignReviewerDryRun(t *testing.T) {
+	teamName := generateID("team-reassign-dry")
+	authorID := generateID("author-reassign-dry")
+	prID := generateID("pr-reassign-dry")
+	teamData := map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": generateID("reviewer1-reassign-dry"), "username": "Reviewer 1", "is_active": true},
+			{"user_id": generateID("reviewer2-reassign-dry"), "username": "Reviewer 2", "is_active": true},
+			{"user_id": generateID("reviewer3-reassign-dry"), "username": "Reviewer 3", "is_active": true},
+		},
+	}
+
+	body, _ := json.Marshal(teamData)
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	prData := map[string]string{
+		"pull_request_id":   prID,
+		"pull_request_name": "PR reassign dry run",
+		"author_id":         authorID,
+	}
+	body, _ = json.Marshal(prData)
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/create", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var created struct {
+		PR struct {
+			AssignedReviewers []interface{} `json:"assigned_reviewers"`
+			Version           int           `json:"version"`
+		} `json:"pr"`
+	}
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if len(created.PR.AssignedReviewers) != 2 {
+		t.Fatalf("Ожидалось 2 ревьюера, получено %d", len(created.PR.AssignedReviewers))
+	}
+	oldReviewer := created.PR.AssignedReviewers[0].(string)
+
+	reassignData := map[string]interface{}{
+		"pull_request_id": prID,
+		"old_user_id":     oldReviewer,
+		"dry_run":         true,
+	}
+	body, _ = json.Marshal(reassignData)
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/reassign", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", resp.StatusCode)
+	}
+	// Версия в dry_run не меняется, так что и ETag отдавать нечего
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		t.Errorf("В режиме dry_run ETag не должен выставляться, получен %s", etag)
+	}
+
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	if result["dry_run"] != true {
+		t.Error("Ответ должен быть помечен dry_run")
+	}
+	replacedBy, _ := result["replaced_by"].(string)
+	if replacedBy == "" || replacedBy == oldReviewer || replacedBy == authorID {
+		t.Errorf("Ожидалась предложенная замена, получено %q", replacedBy)
+	}
+
+	// После dry_run PR должен остаться как был
+	resp, err = httpClient.Get(baseURL + "/pullRequest/get?pull_request_id=" + prID)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var got struct {
+		PR struct {
+			AssignedReviewers []interface{} `json:"assigned_reviewers"`
+			Version           int           `json:"version"`
+		} `json:"pr"`
+	}
+	json.NewDecoder(resp.Body).Decode(&got)
+	if got.PR.Version != created.PR.Version {
+		t.Errorf("Версия не должна меняться в режиме dry_run: было %d, стало %d", created.PR.Version, got.PR.Version)
+	}
+	if len(got.PR.AssignedReviewers) != 2 ||
+		!containsID(got.PR.AssignedReviewers, oldReviewer) ||
+		!containsID(got.PR.AssignedReviewers, created.PR.AssignedReviewers[1].(string)) {
+		t.Errorf("Ревьюеры не должны меняться в режиме dry_run: было %v, стало %v", created.PR.AssignedReviewers, got.PR.AssignedReviewers)
+	}
+}
+
+func TestPullRequestIfMatch(t *testing.T) {
+	teamName := generateID("team-if-match")
+	authorID := generateID("author-if-match")
+	prID := generateID("pr-if-match")
+	teamData := map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": generateID("reviewer1-if-match"), "username": "Reviewer 1", "is_active": true},
+			{"user_id": generateID("reviewer2-if-match"), "username": "Reviewer 2", "is_active": true},
+			{"user_id": generateID("reviewer3-if-match"), "username": "Reviewer 3", "is_active": true},
+		},
+	}
+
+	body, _ := json.Marshal(teamData)
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	prData := map[string]string{
+		"pull_request_id":   prID,
+		"pull_request_name": "PR If-Match",
+		"author_id":         authorID,
+	}
+	body, _ = json.Marshal(prData)
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/create", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	req, _ = http.NewRequest("GET", baseURL+"/pullRequest/get?pull_request_id="+prID, nil)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer resp.Body.Close()
+
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("GET /pullRequest/get должен отдавать ETag")
+	}
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	pr := result["pr"].(map[string]interface{})
+	reviewers := pr["assigned_reviewers"].([]interface{})
+
+	// Первое изменение с актуальным ETag проходит
+	reassignData := map[string]string{
+		"pull_request_id": prID,
+		"old_user_id":     reviewers[0].(string),
+	}
+	body, _ = json.Marshal(reassignData)
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/reassign", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", etag)
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", resp.StatusCode)
+	}
+
+	// Второе изменение со старым ETag должно упасть с 412
+	body, _ = json.Marshal(map[string]string{"pull_request_id": prID})
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/merge", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", etag)
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Ожидался статус 412, получен %d", resp.StatusCode)
+	}
+
+	var errResult map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&errResult)
+	if errObj, ok := errResult["error"].(map[string]interface{}); !ok || errObj["code"] != "VERSION_MISMATCH" {
+		t.Errorf("Ожидался код VERSION_MISMATCH, получено %v", errResult)
+	}
+}
+
+// TestIfMatchFormats - мусор в If-Match - это 400, а не 412; из списка ETag подходит любой, "*" не проверяет версию
+func TestIfMatchFormats(t *testing.T) {
+	teamName := generateID("team-if-match-formats")
+	authorID := generateID("author-if-match-formats")
+	prID := generateID("pr-if-match-formats")
+	teamData := map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": generateID("reviewer1-if-match-formats"), "username": "Reviewer 1", "is_active": true},
+			{"user_id": generateID("reviewer2-if-match-formats"), "username": "Reviewer 2", "is_active": true},
+			{"user_id": generateID("reviewer3-if-match-formats"), "username": "Reviewer 3", "is_active": true},
+		},
+	}
+	body, _ := json.Marshal(teamData)
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	body, _ = json.Marshal(map[string]string{"pull_request_id": prID, "pull_request_name": "PR If-Match formats", "author_id": authorID})
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/create", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var created struct {
+		PR struct {
+			AssignedReviewers []string `json:"assigned_reviewers"`
+		} `json:"pr"`
+	}
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if len(created.PR.AssignedReviewers) != 2 {
+		t.Fatalf("Ожидалось 2 ревьюера, получено %v", created.PR.AssignedReviewers)
+	}
+
+	post := func(path, ifMatch string, payload map[string]string) int {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", baseURL+path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", ifMatch)
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	reassign := map[string]string{"pull_request_id": prID, "old_user_id": created.PR.AssignedReviewers[0]}
+
+	for _, bad := range []string{`"abc"`, `"1", "x"`, `"0"`} {
+		if status := post("/pullRequest/reassign", bad, reassign); status != http.StatusBadRequest {
+			t.Errorf("If-Match %s: ожидался статус 400, получен %d", bad, status)
+		}
+	}
+
+	// Версия 1 есть в списке - переназначение проходит, и версия становится 2
+	if status := post("/pullRequest/reassign", `"7", "1"`, reassign); status != http.StatusOK {
+		t.Fatalf("Ожидался статус 200 для списка ETag, получен %d", status)
+	}
+	if status := post("/pullRequest/merge", `"1", "3"`, map[string]string{"pull_request_id": prID}); status != http.StatusPreconditionFailed {
+		t.Errorf("Ожидался статус 412, когда ни один ETag не совпал, получен %d", status)
+	}
+	if status := post("/pullRequest/merge", "*", map[string]string{"pull_request_id": prID}); status != http.StatusOK {
+		t.Errorf("Ожидался статус 200 для If-Match: *, получен %d", status)
+	}
+}
+
+// TestTeamIfMatch - включение и выключение участника меняет версию команды,
+// поэтому /users/setIsActive со старым ETag команды получает 412
+func TestTeamIfMatch(t *testing.T) {
+	teamName := generateID("team-team-if-match")
+	firstID := generateID("user1-team-if-match")
+	secondID := generateID("user2-team-if-match")
+	teamData := map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": firstID, "username": "User 1", "is_active": true},
+			{"user_id": secondID, "username": "User 2", "is_active": true},
+		},
+	}
+	body, _ := json.Marshal(teamData)
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("POST /team/add должен отдавать ETag \"1\", получено %q", etag)
+	}
+
+	setActive := func(userID, ifMatch string) int {
+		body, _ := json.Marshal(map[string]interface{}{"user_id": userID, "is_active": false})
+		req, _ := http.NewRequest("POST", baseURL+"/users/setIsActive", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", ifMatch)
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := setActive(firstID, etag); status != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", status)
+	}
+	if status := setActive(secondID, etag); status != http.StatusPreconditionFailed {
+		t.Errorf("Ожидался статус 412 со старым ETag команды, получен %d", status)
+	}
+
+	// По списку пользователей версию команды не проверить
+	body, _ = json.Marshal(map[string]interface{}{"user_ids": []string{secondID}, "is_active": false})
+	req, _ = http.NewRequest("POST", baseURL+"/users/bulkSetIsActive", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"2"`)
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 для If-Match без team_name, получен %d", resp.StatusCode)
+	}
+}
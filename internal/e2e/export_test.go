@@ -0,0 +1,98 @@
+package e2e
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestExportFormats - ревью пользователя в CSV через format и список PR в NDJSON через Accept
+func TestExportFormats(t *testing.T) {
+	teamName := generateID("team-export")
+	authorID := generateID("author-export")
+	reviewerID := generateID("reviewer-export")
+	prID := generateID("pr-export")
+	teamData := map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": reviewerID, "username": "Reviewer", "is_active": true},
+		},
+	}
+
+	body, _ := json.Marshal(teamData)
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	prData := map[string]string{
+		"pull_request_id":   prID,
+		"pull_request_name": "PR, with comma",
+		"author_id":         authorID,
+	}
+	body, _ = json.Marshal(prData)
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/create", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	resp, err := httpClient.Get(baseURL + "/users/getReview?format=csv&user_id=" + reviewerID)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv") {
+		t.Errorf("Ожидался text/csv, получен %s", resp.Header.Get("Content-Type"))
+	}
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Некорректный CSV: %v", err)
+	}
+	if len(records) != 2 || records[0][0] != "pull_request_id" {
+		t.Fatalf("Ожидались заголовок и одна строка, получено %v", records)
+	}
+	if records[1][0] != prID || records[1][1] != "PR, with comma" {
+		t.Errorf("Неожиданная строка CSV: %v", records[1])
+	}
+
+	req, _ = http.NewRequest("GET", baseURL+"/pullRequest/list?team_name="+teamName, nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Errorf("Ожидался application/x-ndjson, получен %s", resp.Header.Get("Content-Type"))
+	}
+	lines := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var pr map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &pr); err != nil {
+			t.Fatalf("Строка не JSON: %s", scanner.Text())
+		}
+		if pr["pull_request_id"] != prID || len(pr["assigned_reviewers"].([]interface{})) != 1 {
+			t.Errorf("Неожиданный PR в выгрузке: %v", pr)
+		}
+		lines++
+	}
+	if lines != 1 {
+		t.Errorf("Ожидалась одна строка NDJSON, получено %d", lines)
+	}
+
+	// Ошибка до первой строки отдаётся обычным JSON
+	resp, err = httpClient.Get(baseURL + "/users/getReview?format=csv&user_id=" + generateID("missing-export"))
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Ожидался статус 404, получен %d", resp.StatusCode)
+	}
+}
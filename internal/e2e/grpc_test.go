@@ -0,0 +1,95 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+
+	"pr-reviewer-service/internal/grpcapi/reviewerpb"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+const grpcAddr = "localhost:9090"
+
+// errorReason - ErrorCode из google.rpc.ErrorInfo, как его видит gRPC-клиент
+func errorReason(err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.GetReason()
+		}
+	}
+	return ""
+}
+
+// TestGRPCAPI - через gRPC работает то же, что и через REST, и коды ошибок совпадают
+func TestGRPCAPI(t *testing.T) {
+	conn, err := grpc.NewClient(grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Ошибка подключения: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	teams := reviewerpb.NewTeamServiceClient(conn)
+	prs := reviewerpb.NewPullRequestServiceClient(conn)
+
+	teamName := generateID("team-grpc")
+	authorID := generateID("author-grpc")
+	reviewer1ID := generateID("reviewer1-grpc")
+	reviewer2ID := generateID("reviewer2-grpc")
+	prID := generateID("pr-grpc")
+
+	_, err = teams.AddTeam(ctx, &reviewerpb.AddTeamRequest{Team: &reviewerpb.Team{
+		TeamName: teamName,
+		Members: []*reviewerpb.TeamMember{
+			{UserId: authorID, Username: "Author", IsActive: true},
+			{UserId: reviewer1ID, Username: "Reviewer 1", IsActive: true},
+			{UserId: reviewer2ID, Username: "Reviewer 2", IsActive: true},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("Ошибка создания команды: %v", err)
+	}
+
+	// Повторное создание - ALREADY_EXISTS с кодом TEAM_EXISTS
+	_, err = teams.AddTeam(ctx, &reviewerpb.AddTeamRequest{Team: &reviewerpb.Team{TeamName: teamName}})
+	if status.Code(err) != codes.AlreadyExists || errorReason(err) != "TEAM_EXISTS" {
+		t.Errorf("Ожидался ALREADY_EXISTS/TEAM_EXISTS, получено %v", err)
+	}
+
+	created, err := prs.CreatePullRequest(ctx, &reviewerpb.CreatePullRequestRequest{
+		PullRequestId:   prID,
+		PullRequestName: "gRPC PR",
+		AuthorId:        authorID,
+	})
+	if err != nil {
+		t.Fatalf("Ошибка создания PR: %v", err)
+	}
+	if len(created.GetPr().GetAssignedReviewers()) != 2 {
+		t.Errorf("Ожидалось 2 ревьюера, получено %v", created.GetPr().GetAssignedReviewers())
+	}
+
+	if _, err := prs.MergePullRequest(ctx, &reviewerpb.MergePullRequestRequest{PullRequestId: prID}); err != nil {
+		t.Fatalf("Ошибка мержа: %v", err)
+	}
+
+	// После мержа менять ревьюеров нельзя - FAILED_PRECONDITION с кодом PR_MERGED
+	_, err = prs.ReassignReviewer(ctx, &reviewerpb.ReassignReviewerRequest{
+		PullRequestId: prID,
+		OldUserId:     created.GetPr().GetAssignedReviewers()[0],
+	})
+	if status.Code(err) != codes.FailedPrecondition || errorReason(err) != "PR_MERGED" {
+		t.Errorf("Ожидался FAILED_PRECONDITION/PR_MERGED, получено %v", err)
+	}
+
+	_, err = prs.GetPullRequest(ctx, &reviewerpb.GetPullRequestRequest{PullRequestId: generateID("missing-grpc")})
+	if status.Code(err) != codes.NotFound || errorReason(err) != "NOT_FOUND" {
+		t.Errorf("Ожидался NOT_FOUND, получено %v", err)
+	}
+}
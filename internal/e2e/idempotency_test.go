@@ -0,0 +1,74 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestIdempotencyKeyReplay(t *testing.T) {
+	teamName := generateID("team-idempotency")
+	authorID := generateID("author-idempotency")
+	prID := generateID("pr-idempotency")
+	idempotencyKey := generateID("key-idempotency")
+	teamData := map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": generateID("reviewer1-idempotency"), "username": "Reviewer 1", "is_active": true},
+			{"user_id": generateID("reviewer2-idempotency"), "username": "Reviewer 2", "is_active": true},
+		},
+	}
+
+	body, _ := json.Marshal(teamData)
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	prData := map[string]string{
+		"pull_request_id":   prID,
+		"pull_request_name": "Idempotent PR",
+		"author_id":         authorID,
+	}
+	body, _ = json.Marshal(prData)
+
+	send := func(payload []byte) (*http.Response, []byte) {
+		req, _ := http.NewRequest("POST", baseURL+"/pullRequest/create", bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return resp, respBody
+	}
+
+	first, firstBody := send(body)
+	if first.StatusCode != http.StatusCreated {
+		t.Fatalf("Ожидался статус 201, получен %d. Тело: %s", first.StatusCode, string(firstBody))
+	}
+
+	// Повтор с тем же ключом - тот же ответ, а не PR_EXISTS
+	second, secondBody := send(body)
+	if second.StatusCode != http.StatusCreated {
+		t.Errorf("Повтор должен вернуть исходный статус 201, получен %d", second.StatusCode)
+	}
+	if second.Header.Get("Idempotent-Replayed") != "true" {
+		t.Error("Повтор должен быть помечен заголовком Idempotent-Replayed")
+	}
+	if !bytes.Equal(firstBody, secondBody) {
+		t.Errorf("Тело повтора должно совпадать с исходным.\nБыло: %s\nСтало: %s", firstBody, secondBody)
+	}
+
+	// Тот же ключ с другим телом - 422
+	prData["pull_request_name"] = "Another name"
+	otherBody, _ := json.Marshal(prData)
+	third, _ := send(otherBody)
+	if third.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Ожидался статус 422, получен %d", third.StatusCode)
+	}
+}
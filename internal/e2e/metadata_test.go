@@ -0,0 +1,119 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// TestPullRequestMetadata - название, описание, ссылка и целевая ветка меняются через
+// /pullRequest/update, каждая правка попадает в журнал, смерженный PR править нельзя
+func TestPullRequestMetadata(t *testing.T) {
+	teamName := generateID("team-metadata")
+	authorID := generateID("author-metadata")
+	reviewerID := generateID("reviewer-metadata")
+	prID := generateID("pr-metadata")
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": reviewerID, "username": "Reviewer", "is_active": true},
+		},
+	})
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	type pullRequest struct {
+		PullRequestName string `json:"pull_request_name"`
+		Description     string `json:"description"`
+		URL             string `json:"url"`
+		TargetBranch    string `json:"target_branch"`
+		Version         int    `json:"version"`
+		Audit           []struct {
+			ChangedBy string                            `json:"changed_by"`
+			Changes   map[string]map[string]interface{} `json:"changes"`
+		} `json:"audit"`
+	}
+	post := func(path string, payload map[string]interface{}) (int, pullRequest) {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", baseURL+path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		defer resp.Body.Close()
+		var result struct {
+			PR pullRequest `json:"pr"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result.PR
+	}
+
+	status, created := post("/pullRequest/create", map[string]interface{}{
+		"pull_request_id":   prID,
+		"pull_request_name": "Draft",
+		"author_id":         authorID,
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("Ожидался статус 201, получен %d", status)
+	}
+
+	status, updated := post("/pullRequest/update", map[string]interface{}{
+		"pull_request_id":   prID,
+		"pull_request_name": "Add search endpoint",
+		"description":       "Full-text search over teams",
+		"url":               "https://git.example.com/backend/pulls/42",
+		"target_branch":     "release/1.2",
+		"updated_by":        authorID,
+	})
+	if status != http.StatusOK || updated.PullRequestName != "Add search endpoint" || updated.URL != "https://git.example.com/backend/pulls/42" ||
+		updated.TargetBranch != "release/1.2" || updated.Version <= created.Version {
+		t.Fatalf("Неожиданный PR после update (%d): %+v", status, updated)
+	}
+
+	// Те же значения ещё раз - версия не растёт
+	status, same := post("/pullRequest/update", map[string]interface{}{
+		"pull_request_id": prID,
+		"target_branch":   "release/1.2",
+	})
+	if status != http.StatusOK || same.Version != updated.Version {
+		t.Errorf("Повторный update без изменений не должен поднимать версию (%d): %d -> %d", status, updated.Version, same.Version)
+	}
+
+	for field, value := range map[string]interface{}{
+		"url":               "ftp://git.example.com/42",
+		"target_branch":     "feature/bad..name",
+		"pull_request_name": "   ",
+	} {
+		status, _ := post("/pullRequest/update", map[string]interface{}{"pull_request_id": prID, field: value})
+		if status != http.StatusBadRequest {
+			t.Errorf("Ожидался статус 400 для %s=%v, получен %d", field, value, status)
+		}
+	}
+
+	resp, err := httpClient.Get(baseURL + "/pullRequest/get?pull_request_id=" + prID)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var got struct {
+		PR pullRequest `json:"pr"`
+	}
+	json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	if len(got.PR.Audit) != 1 {
+		t.Fatalf("Ожидалась одна запись в журнале, получено %+v", got.PR.Audit)
+	}
+	entry := got.PR.Audit[0]
+	if entry.ChangedBy != authorID || len(entry.Changes) != 4 || entry.Changes["pull_request_name"]["old"] != "Draft" {
+		t.Errorf("Неожиданная запись в журнале: %+v", entry)
+	}
+
+	post("/pullRequest/merge", map[string]interface{}{"pull_request_id": prID})
+	if status, _ := post("/pullRequest/update", map[string]interface{}{"pull_request_id": prID, "description": "late"}); status != http.StatusConflict {
+		t.Errorf("Ожидался статус 409 для смерженного PR, получен %d", status)
+	}
+}
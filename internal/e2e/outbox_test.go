@@ -0,0 +1,318 @@
+package e2e
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+// outboxFilePath - где на хосте лежит NDJSON синка file. В docker-compose каталог outbox
+// примонтирован в контейнер, а тесты запускаются из internal/e2e.
+func outboxFilePath() string {
+	if path := os.Getenv("E2E_OUTBOX_FILE"); path != "" {
+		return path
+	}
+	return "../../outbox/outbox.ndjson"
+}
+
+type outboxLine struct {
+	ID          int64           `json:"id"`
+	AggregateID string          `json:"aggregate_id"`
+	EventType   string          `json:"event_type"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// readOutboxEvents - события одного PR из файла синка в порядке записи
+func readOutboxEvents(t *testing.T, path, prID string) ([]outboxLine, bool) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, false
+	}
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer f.Close()
+
+	var events []outboxLine
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var line outboxLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("Строка outbox не JSON: %v", err)
+		}
+		if line.AggregateID == prID {
+			events = append(events, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	return events, true
+}
+
+// TestOutboxFileSink - создание, переназначение и мерж PR доходят до синка file
+// по одному разу и в том же порядке. Нужен сервис с OUTBOX_SINKS=file (так в docker-compose).
+func TestOutboxFileSink(t *testing.T) {
+	teamName := generateID("team-outbox")
+	authorID := generateID("author-outbox")
+	prID := generateID("pr-outbox")
+	members := []map[string]interface{}{{"user_id": authorID, "username": "Author", "is_active": true}}
+	for _, id := range []string{generateID("reviewer1-outbox"), generateID("reviewer2-outbox"), generateID("reviewer3-outbox")} {
+		members = append(members, map[string]interface{}{"user_id": id, "username": id, "is_active": true})
+	}
+	body, _ := json.Marshal(map[string]interface{}{"team_name": teamName, "members": members})
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	type outboxResponse struct {
+		PR struct {
+			AssignedReviewers []interface{} `json:"assigned_reviewers"`
+		} `json:"pr"`
+		ReplacedBy string `json:"replaced_by"`
+	}
+	post := func(path string, payload map[string]interface{}) (int, outboxResponse) {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", baseURL+path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		defer resp.Body.Close()
+		var result outboxResponse
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+
+	status, created := post("/pullRequest/create", map[string]interface{}{
+		"pull_request_id":   prID,
+		"pull_request_name": "Outbox",
+		"author_id":         authorID,
+	})
+	if status != http.StatusCreated || len(created.PR.AssignedReviewers) != 2 {
+		t.Fatalf("Ожидался статус 201 и 2 ревьюера, получен %d %v", status, created.PR.AssignedReviewers)
+	}
+	oldReviewer := created.PR.AssignedReviewers[0].(string)
+
+	status, reassigned := post("/pullRequest/reassign", map[string]interface{}{
+		"pull_request_id": prID,
+		"old_user_id":     oldReviewer,
+	})
+	if status != http.StatusOK || reassigned.ReplacedBy == "" {
+		t.Fatalf("Ожидался статус 200 с заменой, получен %d %+v", status, reassigned)
+	}
+	if status, _ := post("/pullRequest/merge", map[string]interface{}{"pull_request_id": prID}); status != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", status)
+	}
+
+	// Relay публикует раз в OUTBOX_POLL_INTERVAL, так что жду, пока допишутся все три события
+	path := outboxFilePath()
+	var events []outboxLine
+	exists := false
+	for i := 0; i < 20; i++ {
+		events, exists = readOutboxEvents(t, path, prID)
+		if len(events) >= 3 {
+			break
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	if !exists {
+		t.Skipf("Файла %s нет: сервис запущен без OUTBOX_SINKS=file", path)
+	}
+
+	wantTypes := []string{"pr.created", "pr.reviewer_reassigned", "pr.merged"}
+	if len(events) != len(wantTypes) {
+		t.Fatalf("Ожидалось %d события PR в outbox, получено %d: %+v", len(wantTypes), len(events), events)
+	}
+	for i, event := range events {
+		if event.EventType != wantTypes[i] {
+			t.Errorf("Событие %d: ожидался %s, получен %s", i, wantTypes[i], event.EventType)
+		}
+		if i > 0 && event.ID <= events[i-1].ID {
+			t.Errorf("id событий не растут: %d после %d", event.ID, events[i-1].ID)
+		}
+	}
+
+	var createdPayload struct {
+		PullRequestID     string   `json:"pull_request_id"`
+		AuthorID          string   `json:"author_id"`
+		Status            string   `json:"status"`
+		AssignedReviewers []string `json:"assigned_reviewers"`
+	}
+	json.Unmarshal(events[0].Payload, &createdPayload)
+	if createdPayload.PullRequestID != prID || createdPayload.AuthorID != authorID || createdPayload.Status != "OPEN" ||
+		len(createdPayload.AssignedReviewers) != 2 || createdPayload.AssignedReviewers[0] != oldReviewer {
+		t.Errorf("Неожиданный payload pr.created: %+v", createdPayload)
+	}
+
+	var reassignedPayload struct {
+		PullRequestID string `json:"pull_request_id"`
+		OldReviewerID string `json:"old_reviewer_id"`
+		NewReviewerID string `json:"new_reviewer_id"`
+	}
+	json.Unmarshal(events[1].Payload, &reassignedPayload)
+	if reassignedPayload.PullRequestID != prID || reassignedPayload.OldReviewerID != oldReviewer || reassignedPayload.NewReviewerID != reassigned.ReplacedBy {
+		t.Errorf("Неожиданный payload pr.reviewer_reassigned: %+v", reassignedPayload)
+	}
+
+	var mergedPayload struct {
+		Status            string        `json:"status"`
+		AssignedReviewers []interface{} `json:"assigned_reviewers"`
+	}
+	json.Unmarshal(events[2].Payload, &mergedPayload)
+	if mergedPayload.Status != "MERGED" || containsID(mergedPayload.AssignedReviewers, oldReviewer) ||
+		!containsID(mergedPayload.AssignedReviewers, reassigned.ReplacedBy) {
+		t.Errorf("Неожиданный payload pr.merged: %+v", mergedPayload)
+	}
+}
+
+// TestOutboxBackfillEvents - добор ревьюера при активации пользователя тоже попадает в outbox,
+// как ручное добавление: pr.reviewer_added с новым ревьюером
+func TestOutboxBackfillEvents(t *testing.T) {
+	teamName := generateID("team-outbox-backfill")
+	authorID := generateID("author-outbox-backfill")
+	activeID := generateID("active-outbox-backfill")
+	inactiveID := generateID("inactive-outbox-backfill")
+	prID := generateID("pr-outbox-backfill")
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": activeID, "username": "Active", "is_active": true},
+			{"user_id": inactiveID, "username": "Inactive", "is_active": false},
+		},
+	})
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	post := func(path string, payload map[string]interface{}) int {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", baseURL+path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := post("/pullRequest/create", map[string]interface{}{"pull_request_id": prID, "pull_request_name": "Backfill", "author_id": authorID}); status != http.StatusCreated {
+		t.Fatalf("Ожидался статус 201, получен %d", status)
+	}
+	if status := post("/users/bulkSetIsActive", map[string]interface{}{"user_ids": []string{inactiveID}, "is_active": true}); status != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", status)
+	}
+
+	path := outboxFilePath()
+	var events []outboxLine
+	exists := false
+	for i := 0; i < 20; i++ {
+		events, exists = readOutboxEvents(t, path, prID)
+		if len(events) >= 2 {
+			break
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	if !exists {
+		t.Skipf("Файла %s нет: сервис запущен без OUTBOX_SINKS=file", path)
+	}
+
+	if len(events) != 2 || events[0].EventType != "pr.created" || events[1].EventType != "pr.reviewer_added" {
+		t.Fatalf("Ожидались pr.created и pr.reviewer_added, получено %+v", events)
+	}
+	var added struct {
+		ReviewerID        string `json:"reviewer_id"`
+		NeedMoreReviewers bool   `json:"needMoreReviewers"`
+	}
+	json.Unmarshal(events[1].Payload, &added)
+	if added.ReviewerID != inactiveID || added.NeedMoreReviewers {
+		t.Errorf("Неожиданный payload pr.reviewer_added: %+v", added)
+	}
+}
+
+// TestOutboxDeclineWithoutCandidate - отказ без замены снимает ревьюера так же, как ручное снятие:
+// кроме pr.review_declined в outbox уходит pr.reviewer_removed с needMoreReviewers
+func TestOutboxDeclineWithoutCandidate(t *testing.T) {
+	teamName := generateID("team-outbox-decline")
+	authorID := generateID("author-outbox-decline")
+	prID := generateID("pr-outbox-decline")
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": generateID("outbox-decline-r1"), "username": "R1", "is_active": true},
+			{"user_id": generateID("outbox-decline-r2"), "username": "R2", "is_active": true},
+		},
+	})
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	post := func(path string, payload map[string]interface{}, out interface{}) int {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", baseURL+path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		defer resp.Body.Close()
+		if out != nil {
+			json.NewDecoder(resp.Body).Decode(out)
+		}
+		return resp.StatusCode
+	}
+
+	// Свободных в команде нет: оба участника кроме автора уже на PR
+	var created struct {
+		PR struct {
+			AssignedReviewers []string `json:"assigned_reviewers"`
+		} `json:"pr"`
+	}
+	if status := post("/pullRequest/create", map[string]interface{}{"pull_request_id": prID, "pull_request_name": "Decline", "author_id": authorID}, &created); status != http.StatusCreated || len(created.PR.AssignedReviewers) != 2 {
+		t.Fatalf("Неожиданный PR (%d): %+v", status, created.PR)
+	}
+	declinedID := created.PR.AssignedReviewers[0]
+	var declined struct {
+		ReplacedBy string `json:"replaced_by"`
+	}
+	if status := post("/pullRequest/decline", map[string]interface{}{"pull_request_id": prID, "reviewer_id": declinedID, "reason": "busy"}, &declined); status != http.StatusOK || declined.ReplacedBy != "" {
+		t.Fatalf("Ожидался отказ без замены, получено %d %+v", status, declined)
+	}
+
+	path := outboxFilePath()
+	var events []outboxLine
+	exists := false
+	for i := 0; i < 20; i++ {
+		events, exists = readOutboxEvents(t, path, prID)
+		if len(events) >= 3 {
+			break
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	if !exists {
+		t.Skipf("Файла %s нет: сервис запущен без OUTBOX_SINKS=file", path)
+	}
+
+	if len(events) != 3 || events[0].EventType != "pr.created" || events[1].EventType != "pr.reviewer_removed" || events[2].EventType != "pr.review_declined" {
+		t.Fatalf("Ожидались pr.created, pr.reviewer_removed и pr.review_declined, получено %+v", events)
+	}
+	var removed struct {
+		ReviewerID        string `json:"reviewer_id"`
+		NeedMoreReviewers bool   `json:"needMoreReviewers"`
+	}
+	json.Unmarshal(events[1].Payload, &removed)
+	if removed.ReviewerID != declinedID || !removed.NeedMoreReviewers {
+		t.Errorf("Неожиданный payload pr.reviewer_removed: %+v", removed)
+	}
+}
@@ -0,0 +1,130 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// TestPullRequestPriority - на urgent PR назначаются самые свободные ревьюеры,
+// метки и приоритет меняются через /pullRequest/update и работают как фильтры
+func TestPullRequestPriority(t *testing.T) {
+	teamName := generateID("team-priority")
+	authorID := generateID("author-priority")
+	reviewerIDs := []string{generateID("priority-r1"), generateID("priority-r2"), generateID("priority-r3")}
+	members := []map[string]interface{}{{"user_id": authorID, "username": "Author", "is_active": true}}
+	for _, id := range reviewerIDs {
+		members = append(members, map[string]interface{}{"user_id": id, "username": id, "is_active": true})
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"team_name": teamName, "members": members})
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	type pullRequest struct {
+		AssignedReviewers []interface{} `json:"assigned_reviewers"`
+		Labels            []string      `json:"labels"`
+		Priority          string        `json:"priority"`
+		Version           int           `json:"version"`
+	}
+	post := func(path string, payload map[string]interface{}) (int, pullRequest) {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", baseURL+path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		defer resp.Body.Close()
+		var result struct {
+			PR pullRequest `json:"pr"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result.PR
+	}
+
+	// Неизвестный приоритет не принимаю
+	status, _ := post("/pullRequest/create", map[string]interface{}{
+		"pull_request_id":   generateID("pr-priority-bad"),
+		"pull_request_name": "Bad priority",
+		"author_id":         authorID,
+		"priority":          "asap",
+	})
+	if status != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 для неизвестного приоритета, получен %d", status)
+	}
+
+	// Обычный PR занимает двоих из трёх, третий остаётся свободным
+	normalID := generateID("pr-priority-normal")
+	status, normal := post("/pullRequest/create", map[string]interface{}{
+		"pull_request_id":   normalID,
+		"pull_request_name": "Normal PR",
+		"author_id":         authorID,
+	})
+	if status != http.StatusCreated || normal.Priority != "normal" || len(normal.AssignedReviewers) != 2 {
+		t.Fatalf("Неожиданный обычный PR (%d): %+v", status, normal)
+	}
+	var freeID string
+	for _, id := range reviewerIDs {
+		if !containsID(normal.AssignedReviewers, id) {
+			freeID = id
+		}
+	}
+
+	urgentID := generateID("pr-priority-urgent")
+	status, urgent := post("/pullRequest/create", map[string]interface{}{
+		"pull_request_id":   urgentID,
+		"pull_request_name": "Urgent PR",
+		"author_id":         authorID,
+		"priority":          "urgent",
+		"labels":            []string{"hotfix"},
+	})
+	if status != http.StatusCreated || urgent.Priority != "urgent" {
+		t.Fatalf("Неожиданный urgent PR (%d): %+v", status, urgent)
+	}
+	if !containsID(urgent.AssignedReviewers, freeID) {
+		t.Errorf("На urgent PR должен был попасть самый свободный ревьюер %s, назначены %v", freeID, urgent.AssignedReviewers)
+	}
+
+	// Меняю только метки, приоритет остаётся прежним
+	status, updated := post("/pullRequest/update", map[string]interface{}{
+		"pull_request_id": urgentID,
+		"labels":          []string{"hotfix", "backend"},
+	})
+	if status != http.StatusOK || updated.Priority != "urgent" || len(updated.Labels) != 2 || updated.Version <= urgent.Version {
+		t.Errorf("Неожиданный PR после update (%d): %+v", status, updated)
+	}
+	if status, _ := post("/pullRequest/update", map[string]interface{}{"pull_request_id": urgentID}); status != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 для пустого update, получен %d", status)
+	}
+
+	// Фильтр по метке в списке
+	resp, err := httpClient.Get(baseURL + "/pullRequest/list?team_name=" + teamName + "&label=backend")
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var list struct {
+		PullRequests []map[string]interface{} `json:"pull_requests"`
+	}
+	json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	if len(list.PullRequests) != 1 || list.PullRequests[0]["pull_request_id"] != urgentID {
+		t.Errorf("Ожидался один PR с меткой backend, получено %v", list.PullRequests)
+	}
+
+	// Фильтр по приоритету в ревью пользователя
+	resp, err = httpClient.Get(baseURL + "/users/getReview?user_id=" + freeID + "&priority=normal")
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var review struct {
+		PullRequests []map[string]interface{} `json:"pull_requests"`
+	}
+	json.NewDecoder(resp.Body).Decode(&review)
+	resp.Body.Close()
+	if len(review.PullRequests) != 0 {
+		t.Errorf("У %s не должно быть normal PR на ревью, получено %v", freeID, review.PullRequests)
+	}
+}
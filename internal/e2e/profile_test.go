@@ -0,0 +1,103 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// TestUserProfile - уровень и навыки: старший по уровню закрывает require_senior без списка seniors,
+// а при match_skills на PR с меткой ставится тот, у кого есть такой навык.
+func TestUserProfile(t *testing.T) {
+	teamName := generateID("team-profile")
+	authorID := generateID("author-profile")
+	seniorID := generateID("senior-profile")
+	skilledID := generateID("skilled-profile")
+	juniorIDs := []string{generateID("junior-profile-1"), generateID("junior-profile-2")}
+
+	members := []map[string]interface{}{}
+	for _, id := range append([]string{authorID, seniorID, skilledID}, juniorIDs...) {
+		members = append(members, map[string]interface{}{"user_id": id, "username": id, "is_active": true})
+	}
+	body, _ := json.Marshal(map[string]interface{}{"team_name": teamName, "members": members})
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	type profileResponse struct {
+		User struct {
+			Seniority string   `json:"seniority"`
+			Skills    []string `json:"skills"`
+		} `json:"user"`
+		PR struct {
+			AssignedReviewers []interface{} `json:"assigned_reviewers"`
+		} `json:"pr"`
+	}
+	post := func(path string, payload map[string]interface{}) (int, profileResponse) {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", baseURL+path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		defer resp.Body.Close()
+		var result profileResponse
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+
+	if status, _ := post("/users/setProfile", map[string]interface{}{"user_id": seniorID, "seniority": "guru"}); status != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 для неизвестного уровня, получен %d", status)
+	}
+	if status, _ := post("/users/setProfile", map[string]interface{}{"user_id": generateID("nobody"), "seniority": "senior"}); status != http.StatusNotFound {
+		t.Errorf("Ожидался статус 404 для неизвестного пользователя, получен %d", status)
+	}
+
+	// Без старших в команде require_senior без seniors включить нельзя
+	status, _ := post("/team/setReviewRules", map[string]interface{}{"team_name": teamName, "require_senior": true})
+	if status != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 для require_senior без старших, получен %d", status)
+	}
+
+	status, result := post("/users/setProfile", map[string]interface{}{"user_id": seniorID, "seniority": "senior", "skills": []string{" go ", "go"}})
+	if status != http.StatusOK || result.User.Seniority != "senior" || len(result.User.Skills) != 1 || result.User.Skills[0] != "go" {
+		t.Fatalf("Неожиданный профиль (%d): %+v", status, result.User)
+	}
+	if status, _ := post("/users/setProfile", map[string]interface{}{"user_id": skilledID, "seniority": "middle", "skills": []string{"payments"}}); status != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", status)
+	}
+
+	resp, err := httpClient.Get(baseURL + "/users/get?user_id=" + skilledID)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var got profileResponse
+	json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	if got.User.Seniority != "middle" || len(got.User.Skills) != 1 || got.User.Skills[0] != "payments" {
+		t.Errorf("Неожиданный профиль в /users/get: %+v", got.User)
+	}
+
+	status, _ = post("/team/setReviewRules", map[string]interface{}{"team_name": teamName, "require_senior": true, "match_skills": true})
+	if status != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", status)
+	}
+
+	// Старший обязателен, а второй - с навыком из меток PR, хотя кандидатов четверо
+	for i := 0; i < 3; i++ {
+		status, created := post("/pullRequest/create", map[string]interface{}{
+			"pull_request_id":   generateID("pr-profile"),
+			"pull_request_name": "Payments fix",
+			"author_id":         authorID,
+			"labels":            []string{"payments"},
+		})
+		if status != http.StatusCreated {
+			t.Fatalf("Ожидался статус 201, получен %d", status)
+		}
+		if !containsID(created.PR.AssignedReviewers, seniorID) || !containsID(created.PR.AssignedReviewers, skilledID) {
+			t.Errorf("Ожидались старший и ревьюер с навыком, назначены %v", created.PR.AssignedReviewers)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// TestRebalanceTeam - все PR достались двум ревьюерам, потом в команду вернулись ещё двое.
+// Перебалансировка должна выровнять открытые ревью, а dry_run - ничего не менять.
+func TestRebalanceTeam(t *testing.T) {
+	teamName := generateID("team-rebalance")
+	authorID := generateID("author-rebalance")
+	var reviewerIDs []string
+	members := []map[string]interface{}{
+		{"user_id": authorID, "username": "Author", "is_active": true},
+	}
+	for i := 1; i <= 4; i++ {
+		userID := generateID(fmt.Sprintf("reviewer%d-rebalance", i))
+		reviewerIDs = append(reviewerIDs, userID)
+		// Третий и четвёртый пока неактивны, поэтому все PR уйдут первым двум
+		members = append(members, map[string]interface{}{"user_id": userID, "username": fmt.Sprintf("Reviewer %d", i), "is_active": i <= 2})
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"team_name": teamName, "members": members})
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	for i := 0; i < 3; i++ {
+		body, _ = json.Marshal(map[string]string{
+			"pull_request_id":   generateID(fmt.Sprintf("pr%d-rebalance", i)),
+			"pull_request_name": "Rebalance PR",
+			"author_id":         authorID,
+		})
+		req, _ = http.NewRequest("POST", baseURL+"/pullRequest/create", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	for _, userID := range reviewerIDs[2:] {
+		body, _ = json.Marshal(map[string]interface{}{"user_id": userID, "is_active": true})
+		req, _ = http.NewRequest("POST", baseURL+"/users/setIsActive", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	rebalance := func(dryRun bool) map[string]interface{} {
+		body, _ := json.Marshal(map[string]interface{}{"team_name": teamName, "dry_run": dryRun})
+		req, _ := http.NewRequest("POST", baseURL+"/team/rebalance", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Ожидался статус 200, получен %d", resp.StatusCode)
+		}
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return result
+	}
+
+	dryRun := rebalance(true)
+	if len(dryRun["reassignments"].([]interface{})) == 0 {
+		t.Fatal("В dry_run ожидались предложенные переносы")
+	}
+
+	result := rebalance(false)
+	if len(result["reassignments"].([]interface{})) != len(dryRun["reassignments"].([]interface{})) {
+		t.Errorf("dry_run не должен был ничего менять: предложено %d переносов, выполнено %d",
+			len(dryRun["reassignments"].([]interface{})), len(result["reassignments"].([]interface{})))
+	}
+
+	loadBefore := result["load_before"].(map[string]interface{})
+	if loadBefore[reviewerIDs[0]].(float64) != 3 || loadBefore[reviewerIDs[2]].(float64) != 0 {
+		t.Errorf("Неожиданная нагрузка до перебалансировки: %v", loadBefore)
+	}
+
+	loadAfter := result["load_after"].(map[string]interface{})
+	minLoad, maxLoad := 100.0, 0.0
+	for _, userID := range reviewerIDs {
+		load := loadAfter[userID].(float64)
+		if load < minLoad {
+			minLoad = load
+		}
+		if load > maxLoad {
+			maxLoad = load
+		}
+	}
+	if maxLoad-minLoad > 1 {
+		t.Errorf("После перебалансировки разброс должен быть не больше 1, нагрузка: %v", loadAfter)
+	}
+
+	// Повторный запуск уже нечего переносить
+	if again := rebalance(false); len(again["reassignments"].([]interface{})) != 0 {
+		t.Errorf("Повторная перебалансировка не должна ничего переносить, получено %v", again["reassignments"])
+	}
+
+	resp, err := httpClient.Get(baseURL + "/statistics/fairness?team_name=" + teamName)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", resp.StatusCode)
+	}
+	var fairness map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&fairness)
+	teams := fairness["teams"].([]interface{})
+	if len(teams) != 1 || len(teams[0].(map[string]interface{})["members"].([]interface{})) != 5 {
+		t.Errorf("Ожидалась одна команда из 5 участников, получено %v", teams)
+	}
+}
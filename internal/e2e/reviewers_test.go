@@ -0,0 +1,111 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// TestManualReviewers - ревьюера можно снять без замены, добавить выбранного
+// и заменить на конкретного пользователя, с проверкой, что его можно назначить
+func TestManualReviewers(t *testing.T) {
+	teamName := generateID("team-manual")
+	otherTeam := generateID("team-manual-other")
+	authorID := generateID("author-manual")
+	reviewerIDs := []string{generateID("manual-r1"), generateID("manual-r2")}
+	outsiderID := generateID("manual-outsider")
+	inactiveID := generateID("manual-inactive")
+	prID := generateID("pr-manual")
+
+	teams := []map[string]interface{}{
+		{"team_name": teamName, "members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": reviewerIDs[0], "username": "R1", "is_active": true},
+			{"user_id": reviewerIDs[1], "username": "R2", "is_active": true},
+		}},
+		{"team_name": otherTeam, "members": []map[string]interface{}{
+			{"user_id": outsiderID, "username": "Outsider", "is_active": true},
+			{"user_id": inactiveID, "username": "Inactive", "is_active": false},
+		}},
+	}
+	for _, team := range teams {
+		body, _ := json.Marshal(team)
+		req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		httpClient.Do(req)
+	}
+
+	type pullRequest struct {
+		AssignedReviewers []interface{} `json:"assigned_reviewers"`
+		NeedMoreReviewers bool          `json:"needMoreReviewers"`
+	}
+	post := func(path string, payload map[string]interface{}) (int, string, pullRequest) {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", baseURL+path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		defer resp.Body.Close()
+		var result struct {
+			PR    pullRequest `json:"pr"`
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result.Error.Code, result.PR
+	}
+
+	status, _, pr := post("/pullRequest/create", map[string]interface{}{
+		"pull_request_id":   prID,
+		"pull_request_name": "Manual reviewers",
+		"author_id":         authorID,
+	})
+	if status != http.StatusCreated || len(pr.AssignedReviewers) != 2 {
+		t.Fatalf("Неожиданный PR (%d): %+v", status, pr)
+	}
+
+	// Третьего не добавить
+	if status, code, _ := post("/pullRequest/addReviewer", map[string]interface{}{"pull_request_id": prID, "reviewer_id": outsiderID}); status != http.StatusConflict || code != "REVIEWERS_FULL" {
+		t.Errorf("Ожидался 409 REVIEWERS_FULL, получен %d %s", status, code)
+	}
+
+	status, _, pr = post("/pullRequest/removeReviewer", map[string]interface{}{"pull_request_id": prID, "reviewer_id": reviewerIDs[0]})
+	if status != http.StatusOK || len(pr.AssignedReviewers) != 1 || !pr.NeedMoreReviewers {
+		t.Fatalf("Неожиданный PR после removeReviewer (%d): %+v", status, pr)
+	}
+	if status, code, _ := post("/pullRequest/removeReviewer", map[string]interface{}{"pull_request_id": prID, "reviewer_id": reviewerIDs[0]}); status != http.StatusConflict || code != "NOT_ASSIGNED" {
+		t.Errorf("Ожидался 409 NOT_ASSIGNED, получен %d %s", status, code)
+	}
+
+	for _, userID := range []string{authorID, inactiveID, reviewerIDs[1]} {
+		if status, code, _ := post("/pullRequest/addReviewer", map[string]interface{}{"pull_request_id": prID, "reviewer_id": userID}); status != http.StatusConflict || code != "NOT_ELIGIBLE" {
+			t.Errorf("Ожидался 409 NOT_ELIGIBLE для %s, получен %d %s", userID, status, code)
+		}
+	}
+
+	// Человек из другой команды подходит, если его выбрали руками
+	status, _, pr = post("/pullRequest/addReviewer", map[string]interface{}{"pull_request_id": prID, "reviewer_id": outsiderID})
+	if status != http.StatusOK || !containsID(pr.AssignedReviewers, outsiderID) || pr.NeedMoreReviewers {
+		t.Fatalf("Неожиданный PR после addReviewer (%d): %+v", status, pr)
+	}
+
+	status, _, pr = post("/pullRequest/reassign", map[string]interface{}{
+		"pull_request_id": prID,
+		"old_user_id":     outsiderID,
+		"new_reviewer_id": reviewerIDs[0],
+	})
+	if status != http.StatusOK || !containsID(pr.AssignedReviewers, reviewerIDs[0]) || containsID(pr.AssignedReviewers, outsiderID) {
+		t.Errorf("Неожиданный PR после reassign на выбранного (%d): %+v", status, pr)
+	}
+	if status, code, _ := post("/pullRequest/reassign", map[string]interface{}{
+		"pull_request_id": prID,
+		"old_user_id":     reviewerIDs[0],
+		"new_reviewer_id": inactiveID,
+	}); status != http.StatusConflict || code != "NOT_ELIGIBLE" {
+		t.Errorf("Ожидался 409 NOT_ELIGIBLE при reassign на неактивного, получен %d %s", status, code)
+	}
+}
@@ -0,0 +1,194 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestReviewRules - правила команды автора: never_review отсеивает ревьюера и при автоподборе,
+// и при ручном назначении, ментор ставится первым, а без старшего PR не создаётся.
+func TestReviewRules(t *testing.T) {
+	teamName := generateID("team-rules")
+	authorID := generateID("author-rules")
+	mentorID := generateID("mentor-rules")
+	rivalID := generateID("rival-rules")
+	otherID := generateID("other-rules")
+
+	members := []map[string]interface{}{}
+	for _, id := range []string{authorID, mentorID, rivalID, otherID} {
+		members = append(members, map[string]interface{}{"user_id": id, "username": id, "is_active": true})
+	}
+	body, _ := json.Marshal(map[string]interface{}{"team_name": teamName, "members": members})
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	type rulesResponse struct {
+		PR struct {
+			AssignedReviewers []interface{} `json:"assigned_reviewers"`
+		} `json:"pr"`
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	post := func(path string, payload map[string]interface{}) (int, rulesResponse) {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", baseURL+path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		defer resp.Body.Close()
+		var result rulesResponse
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+
+	// Одна пара и в never_review, и в preferred_pairs - так нельзя
+	status, _ := post("/team/setReviewRules", map[string]interface{}{
+		"team_name":       teamName,
+		"never_review":    []map[string]string{{"user_a": mentorID, "user_b": authorID}},
+		"preferred_pairs": []map[string]string{{"reviewer_id": mentorID, "author_id": authorID}},
+	})
+	if status != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 для противоречивых правил, получен %d", status)
+	}
+
+	status, _ = post("/team/setReviewRules", map[string]interface{}{
+		"team_name":       teamName,
+		"never_review":    []map[string]string{{"user_a": authorID, "user_b": rivalID}},
+		"preferred_pairs": []map[string]string{{"reviewer_id": mentorID, "author_id": authorID}},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", status)
+	}
+
+	// Кандидатов трое, но rival запрещён, так что на PR всегда ментор и other
+	prID := generateID("pr-rules")
+	status, created := post("/pullRequest/create", map[string]interface{}{
+		"pull_request_id":   prID,
+		"pull_request_name": "Rules",
+		"author_id":         authorID,
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("Ожидался статус 201, получен %d", status)
+	}
+	if containsID(created.PR.AssignedReviewers, rivalID) || !containsID(created.PR.AssignedReviewers, mentorID) {
+		t.Errorf("Правила не применились при создании PR: %v", created.PR.AssignedReviewers)
+	}
+
+	// Вручную запрещённого тоже не поставить
+	status, result := post("/pullRequest/reassign", map[string]interface{}{
+		"pull_request_id": prID,
+		"old_user_id":     otherID,
+		"new_reviewer_id": rivalID,
+	})
+	if status != http.StatusConflict || result.Error.Code != "NOT_ELIGIBLE" {
+		t.Errorf("Ожидался 409 NOT_ELIGIBLE, получен %d %s", status, result.Error.Code)
+	}
+
+	// Старший только rival, а ему нельзя - PR не создаётся, и видно из-за какого правила
+	status, _ = post("/team/setReviewRules", map[string]interface{}{
+		"team_name":      teamName,
+		"never_review":   []map[string]string{{"user_a": authorID, "user_b": rivalID}},
+		"seniors":        []string{rivalID},
+		"require_senior": true,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", status)
+	}
+	status, result = post("/pullRequest/create", map[string]interface{}{
+		"pull_request_id":   generateID("pr-rules-senior"),
+		"pull_request_name": "Needs senior",
+		"author_id":         authorID,
+	})
+	if status != http.StatusConflict || result.Error.Code != "NO_CANDIDATE" || result.Error.Message != "NO_CANDIDATE: blocked by rule require_senior" {
+		t.Errorf("Ожидался 409 NO_CANDIDATE из-за require_senior, получен %d %+v", status, result.Error)
+	}
+
+	resp, err := httpClient.Get(baseURL + "/team/getReviewRules?team_name=" + teamName)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var rules struct {
+		Seniors       []string `json:"seniors"`
+		RequireSenior bool     `json:"require_senior"`
+	}
+	json.NewDecoder(resp.Body).Decode(&rules)
+	resp.Body.Close()
+	if !rules.RequireSenior || len(rules.Seniors) != 1 || rules.Seniors[0] != rivalID {
+		t.Errorf("Неожиданные правила: %+v", rules)
+	}
+}
+
+// TestReviewRulesTeamMembership - в правилах команды можно упоминать только её участников:
+// пользователь из другой команды в never_review, preferred_pairs или seniors - это 400
+func TestReviewRulesTeamMembership(t *testing.T) {
+	teamName := generateID("team-rules-members")
+	memberA := generateID("rules-member-a")
+	memberB := generateID("rules-member-b")
+	otherTeam := generateID("team-rules-other")
+	outsiderID := generateID("rules-outsider")
+
+	addTeam := func(name string, userIDs ...string) {
+		members := []map[string]interface{}{}
+		for _, id := range userIDs {
+			members = append(members, map[string]interface{}{"user_id": id, "username": id, "is_active": true})
+		}
+		body, _ := json.Marshal(map[string]interface{}{"team_name": name, "members": members})
+		req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		resp.Body.Close()
+	}
+	addTeam(teamName, memberA, memberB)
+	addTeam(otherTeam, outsiderID)
+
+	setRules := func(rules map[string]interface{}) (int, string) {
+		rules["team_name"] = teamName
+		body, _ := json.Marshal(rules)
+		req, _ := http.NewRequest("POST", baseURL+"/team/setReviewRules", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		defer resp.Body.Close()
+		var result struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result.Error.Message
+	}
+
+	cases := map[string]map[string]interface{}{
+		"never_review":    {"never_review": []map[string]string{{"user_a": memberA, "user_b": outsiderID}}},
+		"preferred_pairs": {"preferred_pairs": []map[string]string{{"reviewer_id": outsiderID, "author_id": memberA}}},
+		"seniors":         {"seniors": []string{outsiderID}, "require_senior": true},
+	}
+	for name, rules := range cases {
+		status, message := setRules(rules)
+		if status != http.StatusBadRequest || !strings.Contains(message, outsiderID) {
+			t.Errorf("%s: ожидался 400 с упоминанием %s, получено %d %q", name, outsiderID, status, message)
+		}
+	}
+
+	// Те же правила на участниках команды принимаются
+	status, message := setRules(map[string]interface{}{
+		"never_review": []map[string]string{{"user_a": memberA, "user_b": memberB}},
+		"seniors":      []string{memberB},
+	})
+	if status != http.StatusOK {
+		t.Errorf("Ожидался статус 200 для правил на участниках команды, получено %d %q", status, message)
+	}
+}
@@ -0,0 +1,218 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestReviewSLAEscalation - PR без первого ревью дольше SLA команды получает новых ревьюеров,
+// а эскалации видны в /pullRequest/get
+func TestReviewSLAEscalation(t *testing.T) {
+	teamName := generateID("team-sla")
+	authorID := generateID("author-sla")
+	prID := generateID("pr-sla")
+	teamData := map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": generateID("sla-r1"), "username": "R1", "is_active": true},
+			{"user_id": generateID("sla-r2"), "username": "R2", "is_active": true},
+			{"user_id": generateID("sla-r3"), "username": "R3", "is_active": true},
+		},
+	}
+
+	body, _ := json.Marshal(teamData)
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	// Неизвестное действие не принимаю
+	body, _ = json.Marshal(map[string]interface{}{"team_name": teamName, "first_review_hours": 1, "action": "panic"})
+	req, _ = http.NewRequest("POST", baseURL+"/team/setSLA", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 для неизвестного действия, получен %d", resp.StatusCode)
+	}
+
+	// SLA в доли секунды, чтобы не ждать часами
+	body, _ = json.Marshal(map[string]interface{}{"team_name": teamName, "first_review_hours": 0.0001, "action": "reassign"})
+	req, _ = http.NewRequest("POST", baseURL+"/team/setSLA", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", resp.StatusCode)
+	}
+
+	prData := map[string]string{
+		"pull_request_id":   prID,
+		"pull_request_name": "SLA PR",
+		"author_id":         authorID,
+	}
+	body, _ = json.Marshal(prData)
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/create", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var created struct {
+		PR struct {
+			AssignedReviewers []interface{} `json:"assigned_reviewers"`
+		} `json:"pr"`
+	}
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if len(created.PR.AssignedReviewers) != 2 {
+		t.Fatalf("Ожидалось 2 ревьюера, получено %v", created.PR.AssignedReviewers)
+	}
+
+	time.Sleep(time.Second)
+
+	req, _ = http.NewRequest("POST", baseURL+"/admin/escalate", nil)
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", resp.StatusCode)
+	}
+
+	resp, err = httpClient.Get(baseURL + "/pullRequest/get?pull_request_id=" + prID)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var got struct {
+		PR struct {
+			AssignedReviewers []interface{} `json:"assigned_reviewers"`
+			Escalations       []struct {
+				Action        string `json:"action"`
+				ReviewerID    string `json:"reviewer_id"`
+				NewReviewerID string `json:"new_reviewer_id"`
+			} `json:"escalations"`
+		} `json:"pr"`
+	}
+	json.NewDecoder(resp.Body).Decode(&got)
+	pr := got.PR
+	resp.Body.Close()
+
+	// Оба ревьюера ничего не сделали - обоих и меняю
+	if len(pr.Escalations) != 2 {
+		t.Fatalf("Ожидалось 2 эскалации, получено %+v", pr.Escalations)
+	}
+	for _, escalation := range pr.Escalations {
+		if escalation.Action != "reassign" || escalation.NewReviewerID == "" {
+			t.Errorf("Неожиданная эскалация: %+v", escalation)
+		}
+		if !containsID(created.PR.AssignedReviewers, escalation.ReviewerID) {
+			t.Errorf("Заменён ревьюер %s, которого не было на PR", escalation.ReviewerID)
+		}
+	}
+	if len(pr.AssignedReviewers) != 2 || containsID(pr.AssignedReviewers, authorID) {
+		t.Errorf("Неожиданные ревьюеры после эскалации: %v", pr.AssignedReviewers)
+	}
+}
+
+// TestSLAAddReviewerRespectsLimit - add_reviewer на двух нарушениях подряд не ставит на PR
+// больше двух ревьюеров: второй раз вместо добавления идёт замена, и она записана в эскалации
+func TestSLAAddReviewerRespectsLimit(t *testing.T) {
+	teamName := generateID("team-sla-full")
+	authorID := generateID("author-sla-full")
+	r1 := generateID("sla-full-r1")
+	r2 := generateID("sla-full-r2")
+	r3 := generateID("sla-full-r3")
+	prID := generateID("pr-sla-full")
+
+	post := func(path string, data interface{}) int {
+		body, _ := json.Marshal(data)
+		req, _ := http.NewRequest("POST", baseURL+path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	escalate := func() {
+		time.Sleep(time.Second)
+		if status := post("/admin/escalate", nil); status != http.StatusOK {
+			t.Fatalf("Ожидался статус 200 от /admin/escalate, получен %d", status)
+		}
+	}
+	type escalation struct {
+		Action        string `json:"action"`
+		NewReviewerID string `json:"new_reviewer_id"`
+	}
+	getPR := func() ([]interface{}, []escalation) {
+		resp, err := httpClient.Get(baseURL + "/pullRequest/get?pull_request_id=" + prID)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		defer resp.Body.Close()
+		var got struct {
+			PR struct {
+				AssignedReviewers []interface{} `json:"assigned_reviewers"`
+				Escalations       []escalation  `json:"escalations"`
+			} `json:"pr"`
+		}
+		json.NewDecoder(resp.Body).Decode(&got)
+		return got.PR.AssignedReviewers, got.PR.Escalations
+	}
+
+	// Сначала активен только r1, чтобы PR создался с одним ревьюером
+	post("/team/add", map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": r1, "username": "R1", "is_active": true},
+			{"user_id": r2, "username": "R2", "is_active": false},
+			{"user_id": r3, "username": "R3", "is_active": false},
+		},
+	})
+	if status := post("/team/setSLA", map[string]interface{}{"team_name": teamName, "first_review_hours": 0.0001, "action": "add_reviewer"}); status != http.StatusOK {
+		t.Fatalf("Ожидался статус 200 от /team/setSLA, получен %d", status)
+	}
+	if status := post("/pullRequest/create", map[string]string{"pull_request_id": prID, "pull_request_name": "SLA full PR", "author_id": authorID}); status != http.StatusCreated {
+		t.Fatalf("Ожидался статус 201, получен %d", status)
+	}
+	post("/users/setIsActive", map[string]interface{}{"user_id": r2, "is_active": true})
+	post("/users/setIsActive", map[string]interface{}{"user_id": r3, "is_active": true})
+
+	// Первое нарушение: место есть, ревьюер добавляется
+	escalate()
+	reviewers, escalations := getPR()
+	if len(reviewers) != 2 {
+		t.Fatalf("После первой эскалации ожидалось 2 ревьюера, получено %v", reviewers)
+	}
+	if len(escalations) != 1 || escalations[0].Action != "add_reviewer" || escalations[0].NewReviewerID == "" {
+		t.Fatalf("Неожиданные эскалации после первого нарушения: %+v", escalations)
+	}
+
+	// Второе нарушение: мест нет, добавлять уже нельзя
+	escalate()
+	reviewers, escalations = getPR()
+	if len(reviewers) > 2 {
+		t.Fatalf("На PR больше двух ревьюеров после второй эскалации: %v", reviewers)
+	}
+	if len(escalations) < 2 {
+		t.Fatalf("Ожидалась ещё одна эскалация, получено %+v", escalations)
+	}
+	for _, e := range escalations[1:] {
+		if e.Action == "add_reviewer" {
+			t.Errorf("При полном PR записано add_reviewer: %+v", e)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestStalePolicy - PR без активности дольше политики команды получают предупреждение,
+// совсем заброшенные закрываются, а PR с исключённой меткой не трогаются.
+// Давность PR задаю через импорт снимка, ждать неделями не нужно.
+func TestStalePolicy(t *testing.T) {
+	teamName := generateID("team-stale")
+	authorID := generateID("author-stale")
+	reviewerID := generateID("reviewer-stale")
+	warnID := generateID("pr-stale-warn")
+	closeID := generateID("pr-stale-close")
+	keepID := generateID("pr-stale-keep")
+
+	daysAgo := func(days int) string {
+		return time.Now().UTC().AddDate(0, 0, -days).Format(time.RFC3339)
+	}
+	stalePR := func(prID string, days int, labels []string) map[string]interface{} {
+		return map[string]interface{}{
+			"pull_request_id":    prID,
+			"pull_request_name":  "Stale PR",
+			"author_id":          authorID,
+			"status":             "OPEN",
+			"assigned_reviewers": []string{reviewerID},
+			"createdAt":          daysAgo(days),
+			"lastActivityAt":     daysAgo(days),
+			"labels":             labels,
+		}
+	}
+
+	snapshot := map[string]interface{}{
+		"format_version": 1,
+		"teams": []map[string]interface{}{
+			{
+				"team_name": teamName,
+				"members": []map[string]interface{}{
+					{"user_id": authorID, "username": "Author", "is_active": true},
+					{"user_id": reviewerID, "username": "Reviewer", "is_active": true},
+				},
+			},
+		},
+		"pull_requests": []map[string]interface{}{
+			stalePR(warnID, 10, nil),
+			stalePR(closeID, 40, []string{"backend"}),
+			stalePR(keepID, 40, []string{"do-not-close"}),
+		},
+	}
+	body, _ := json.Marshal(snapshot)
+	req, _ := http.NewRequest("POST", baseURL+"/admin/import?mode=fail", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200 на импорт, получен %d", resp.StatusCode)
+	}
+
+	setPolicy := func(policy map[string]interface{}) int {
+		body, _ := json.Marshal(policy)
+		req, _ := http.NewRequest("POST", baseURL+"/team/setStalePolicy", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Закрывать раньше, чем предупредить, нельзя
+	if status := setPolicy(map[string]interface{}{"team_name": teamName, "warn_after_days": 7, "close_after_days": 5}); status != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 для close_after_days <= warn_after_days, получен %d", status)
+	}
+
+	status := setPolicy(map[string]interface{}{
+		"team_name":        teamName,
+		"warn_after_days":  7,
+		"close_after_days": 30,
+		"excluded_labels":  []string{"do-not-close"},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", status)
+	}
+
+	// В списке брошенных оба PR без исключённой метки, самый старый первым
+	resp, err = httpClient.Get(baseURL + "/pullRequest/stale?team_name=" + teamName)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var list struct {
+		PullRequests []struct {
+			PullRequestID string  `json:"pull_request_id"`
+			IdleDays      float64 `json:"idle_days"`
+		} `json:"pull_requests"`
+	}
+	json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	if len(list.PullRequests) != 2 || list.PullRequests[0].PullRequestID != closeID || list.PullRequests[1].PullRequestID != warnID {
+		t.Fatalf("Неожиданный список брошенных PR: %+v", list.PullRequests)
+	}
+	if list.PullRequests[1].IdleDays < 9.9 {
+		t.Errorf("Ожидалось около 10 дней простоя, получено %v", list.PullRequests[1].IdleDays)
+	}
+
+	req, _ = http.NewRequest("POST", baseURL+"/admin/sweepStale", nil)
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var sweep struct {
+		Warned []interface{} `json:"warned"`
+		Closed []interface{} `json:"closed"`
+	}
+	json.NewDecoder(resp.Body).Decode(&sweep)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", resp.StatusCode)
+	}
+	if !containsID(sweep.Warned, warnID) || !containsID(sweep.Closed, closeID) {
+		t.Errorf("Неожиданный итог прохода: %+v", sweep)
+	}
+	if containsID(sweep.Warned, keepID) || containsID(sweep.Closed, keepID) {
+		t.Errorf("PR с исключённой меткой не должен был попасть в проход: %+v", sweep)
+	}
+
+	// Закрытый PR нельзя смержить
+	body, _ = json.Marshal(map[string]string{"pull_request_id": closeID})
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/merge", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var mergeErr map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&mergeErr)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict || mergeErr["error"].(map[string]interface{})["code"] != "PR_CLOSED" {
+		t.Errorf("Ожидался 409 PR_CLOSED, получен %d: %v", resp.StatusCode, mergeErr)
+	}
+
+	// Повторно о том же PR не предупреждаю, пока на нём ничего не произошло
+	req, _ = http.NewRequest("POST", baseURL+"/admin/sweepStale", nil)
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	sweep.Warned, sweep.Closed = nil, nil
+	json.NewDecoder(resp.Body).Decode(&sweep)
+	resp.Body.Close()
+	if containsID(sweep.Warned, warnID) {
+		t.Errorf("Повторное предупреждение о %s", warnID)
+	}
+}
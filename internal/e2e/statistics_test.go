@@ -0,0 +1,200 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestStatisticsTimeSeries(t *testing.T) {
+	teamName := generateID("team-timeseries")
+	authorID := generateID("author-timeseries")
+	teamData := map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": generateID("reviewer1-timeseries"), "username": "Reviewer 1", "is_active": true},
+			{"user_id": generateID("reviewer2-timeseries"), "username": "Reviewer 2", "is_active": true},
+		},
+	}
+
+	body, _ := json.Marshal(teamData)
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	prData := map[string]string{
+		"pull_request_id":   generateID("pr-timeseries"),
+		"pull_request_name": "Time Series PR",
+		"author_id":         authorID,
+	}
+	body, _ = json.Marshal(prData)
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/create", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	// Статистика по команде должна видеть ровно один PR
+	resp, err := httpClient.Get(baseURL + "/statistics?team_name=" + teamName)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var stats map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&stats)
+	prStats := stats["pr_stats"].(map[string]interface{})
+	if prStats["total_prs"].(float64) != 1 {
+		t.Errorf("Ожидался 1 PR в команде, получено %v", prStats["total_prs"])
+	}
+
+	resp, err = httpClient.Get(baseURL + "/statistics/timeseries?interval=day&team_name=" + teamName)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", resp.StatusCode)
+	}
+
+	var series map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&series)
+	buckets := series["buckets"].([]interface{})
+	if len(buckets) < 30 {
+		t.Errorf("Ожидалось не меньше 30 дневных бакетов, получено %d", len(buckets))
+	}
+
+	created, assignments := 0.0, 0.0
+	for _, b := range buckets {
+		bucket := b.(map[string]interface{})
+		created += bucket["prs_created"].(float64)
+		assignments += bucket["assignments"].(float64)
+	}
+	if created != 1 {
+		t.Errorf("Ожидался 1 созданный PR в ряду, получено %v", created)
+	}
+	if assignments != 2 {
+		t.Errorf("Ожидалось 2 назначения в ряду, получено %v", assignments)
+	}
+
+	resp, err = httpClient.Get(baseURL + "/statistics/timeseries?interval=month")
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Неизвестный интервал должен давать 400, получен %d", resp.StatusCode)
+	}
+}
+
+// TestReviewTurnaround - ревьюер оставляет вердикт, после мержа в метриках есть
+// время до мержа по команде и время ответа ревьюера
+func TestReviewTurnaround(t *testing.T) {
+	teamName := generateID("team-turnaround")
+	authorID := generateID("author-turnaround")
+	prID := generateID("pr-turnaround")
+	teamData := map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": generateID("reviewer1-turnaround"), "username": "Reviewer 1", "is_active": true},
+		},
+	}
+
+	body, _ := json.Marshal(teamData)
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	prData := map[string]string{
+		"pull_request_id":   prID,
+		"pull_request_name": "Turnaround PR",
+		"author_id":         authorID,
+	}
+	body, _ = json.Marshal(prData)
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/create", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	var created map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	reviewerID := created["pr"].(map[string]interface{})["assigned_reviewers"].([]interface{})[0].(string)
+
+	// Автор не назначен ревьюером, его вердикт не принимается
+	body, _ = json.Marshal(map[string]string{"pull_request_id": prID, "reviewer_id": authorID, "verdict": "APPROVED"})
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/review", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Ожидался статус 409, получен %d", resp.StatusCode)
+	}
+
+	body, _ = json.Marshal(map[string]string{"pull_request_id": prID, "reviewer_id": reviewerID, "verdict": "APPROVED"})
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/review", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Ожидался статус 201, получен %d", resp.StatusCode)
+	}
+
+	body, _ = json.Marshal(map[string]string{"pull_request_id": prID})
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/merge", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+
+	resp, err = httpClient.Get(baseURL + "/statistics/turnaround?team_name=" + teamName)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", resp.StatusCode)
+	}
+
+	var turnaround map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&turnaround)
+	timeToMerge := turnaround["time_to_merge"].(map[string]interface{})
+	if timeToMerge["count"].(float64) != 1 || timeToMerge["median_hours"] == nil {
+		t.Errorf("Ожидался один смерженный PR с медианой, получено %v", timeToMerge)
+	}
+
+	var reviewerStats map[string]interface{}
+	for _, r := range turnaround["by_reviewer"].([]interface{}) {
+		stat := r.(map[string]interface{})
+		if stat["user_id"] == reviewerID {
+			reviewerStats = stat
+		}
+	}
+	if reviewerStats == nil || reviewerStats["count"].(float64) != 1 {
+		t.Errorf("Ожидался ответ ревьюера %s в метриках, получено %v", reviewerID, reviewerStats)
+	}
+
+	resp, err = httpClient.Get(baseURL + "/statistics/leaderboard?team_name=" + teamName)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var leaderboard map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&leaderboard)
+	entries := leaderboard["leaderboard"].([]interface{})
+	if len(entries) != 1 || entries[0].(map[string]interface{})["user_id"] != reviewerID {
+		t.Errorf("Ожидался один ревьюер %s в рейтинге, получено %v", reviewerID, entries)
+	}
+}
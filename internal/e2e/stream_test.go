@@ -0,0 +1,137 @@
+package e2e
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  map[string]interface{}
+}
+
+// openStream - подключаюсь к /users/stream и читаю события в канал, пока не закроют контекст
+func openStream(ctx context.Context, t *testing.T, userID, lastEventID string) <-chan sseEvent {
+	req, _ := http.NewRequestWithContext(ctx, "GET", baseURL+"/users/stream?user_id="+userID, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		t.Fatalf("Ожидался статус 200, получен %d", resp.StatusCode)
+	}
+
+	events := make(chan sseEvent, 16)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+
+		scanner := bufio.NewScanner(resp.Body)
+		var event sseEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				event.ID = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				event.Event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.Data)
+			case line == "" && event.ID != "":
+				events <- event
+				event = sseEvent{}
+			}
+		}
+	}()
+
+	// Do возвращается, когда пришли заголовки, а сервер шлёт их уже после подписки
+	return events
+}
+
+func nextEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("Поток закрылся раньше времени")
+		}
+		return event
+	case <-time.After(timeout):
+		t.Fatal("Не дождался события")
+	}
+	return sseEvent{}
+}
+
+// TestUserEventStream - ревьюер видит назначение и мерж, а после переподключения
+// с Last-Event-ID получает пропущенное
+func TestUserEventStream(t *testing.T) {
+	teamName := generateID("team-stream")
+	authorID := generateID("author-stream")
+	reviewerID := generateID("reviewer-stream")
+	prID := generateID("pr-stream")
+	teamData := map[string]interface{}{
+		"team_name": teamName,
+		"members": []map[string]interface{}{
+			{"user_id": authorID, "username": "Author", "is_active": true},
+			{"user_id": reviewerID, "username": "Reviewer", "is_active": true},
+		},
+	}
+
+	body, _ := json.Marshal(teamData)
+	req, _ := http.NewRequest("POST", baseURL+"/team/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	httpClient.Do(req)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := openStream(ctx, t, reviewerID, "")
+
+	prData := map[string]string{
+		"pull_request_id":   prID,
+		"pull_request_name": "Stream PR",
+		"author_id":         authorID,
+	}
+	body, _ = json.Marshal(prData)
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/create", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+
+	assigned := nextEvent(t, events)
+	if assigned.Event != "assigned" || assigned.Data["pull_request_id"] != prID {
+		t.Fatalf("Ожидалось событие assigned по %s, получено %+v", prID, assigned)
+	}
+
+	// Отключаюсь, мержу PR и переподключаюсь с Last-Event-ID
+	cancel()
+
+	body, _ = json.Marshal(map[string]string{"pull_request_id": prID})
+	req, _ = http.NewRequest("POST", baseURL+"/pullRequest/merge", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	resp.Body.Close()
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	events = openStream(ctx, t, reviewerID, assigned.ID)
+
+	merged := nextEvent(t, events)
+	if merged.Event != "merged" || merged.Data["pull_request_id"] != prID {
+		t.Errorf("Ожидалось пропущенное событие merged по %s, получено %+v", prID, merged)
+	}
+}
@@ -0,0 +1,167 @@
+package grpcapi
+
+import (
+	"pr-reviewer-service/internal/grpcapi/reviewerpb"
+	"pr-reviewer-service/internal/models"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Перекладываю модели сервиса в protobuf-сообщения и обратно.
+// Поля называются так же, как в JSON у REST.
+
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func fromTimestamp(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+func toExpectedVersion(v *int32) []int {
+	if v == nil {
+		return nil
+	}
+	return []int{int(*v)}
+}
+
+func toTeam(team *models.Team) *reviewerpb.Team {
+	members := make([]*reviewerpb.TeamMember, 0, len(team.Members))
+	for _, m := range team.Members {
+		members = append(members, &reviewerpb.TeamMember{
+			UserId:   m.UserID,
+			Username: m.Username,
+			IsActive: m.IsActive,
+		})
+	}
+	return &reviewerpb.Team{TeamName: team.TeamName, Members: members, Version: int32(team.Version)}
+}
+
+func fromTeam(team *reviewerpb.Team) *models.Team {
+	members := make([]models.TeamMember, 0, len(team.GetMembers()))
+	for _, m := range team.GetMembers() {
+		members = append(members, models.TeamMember{
+			UserID:   m.GetUserId(),
+			Username: m.GetUsername(),
+			IsActive: m.GetIsActive(),
+		})
+	}
+	return &models.Team{TeamName: team.GetTeamName(), Members: members}
+}
+
+func toUser(user *models.User) *reviewerpb.User {
+	if user == nil {
+		return nil
+	}
+	return &reviewerpb.User{
+		UserId:    user.UserID,
+		Username:  user.Username,
+		TeamName:  user.TeamName,
+		IsActive:  user.IsActive,
+		Seniority: string(user.Seniority),
+		Skills:    user.Skills,
+	}
+}
+
+func toPullRequest(pr *models.PullRequest) *reviewerpb.PullRequest {
+	if pr == nil {
+		return nil
+	}
+	return &reviewerpb.PullRequest{
+		PullRequestId:     pr.PullRequestID,
+		PullRequestName:   pr.PullRequestName,
+		AuthorId:          pr.AuthorID,
+		Status:            string(pr.Status),
+		AssignedReviewers: pr.AssignedReviewers,
+		NeedMoreReviewers: pr.NeedMoreReviewers,
+		CreatedAt:         toTimestamp(pr.CreatedAt),
+		MergedAt:          toTimestamp(pr.MergedAt),
+		Version:           int32(pr.Version),
+		Labels:            pr.Labels,
+		ClosedAt:          toTimestamp(pr.ClosedAt),
+		LastActivityAt:    toTimestamp(pr.LastActivityAt),
+		Priority:          string(pr.Priority),
+		Description:       pr.Description,
+		Url:               pr.URL,
+		TargetBranch:      pr.TargetBranch,
+	}
+}
+
+func toPullRequests(prs []*models.PullRequest) []*reviewerpb.PullRequest {
+	result := make([]*reviewerpb.PullRequest, 0, len(prs))
+	for _, pr := range prs {
+		result = append(result, toPullRequest(pr))
+	}
+	return result
+}
+
+func toPullRequestShorts(prs []*models.PullRequestShort) []*reviewerpb.PullRequestShort {
+	result := make([]*reviewerpb.PullRequestShort, 0, len(prs))
+	for _, pr := range prs {
+		result = append(result, &reviewerpb.PullRequestShort{
+			PullRequestId:   pr.PullRequestID,
+			PullRequestName: pr.PullRequestName,
+			AuthorId:        pr.AuthorID,
+			Status:          string(pr.Status),
+			Priority:        string(pr.Priority),
+			Labels:          pr.Labels,
+		})
+	}
+	return result
+}
+
+func toReassignments(items []models.ReviewerReassignment) []*reviewerpb.ReviewerReassignment {
+	result := make([]*reviewerpb.ReviewerReassignment, 0, len(items))
+	for _, r := range items {
+		result = append(result, &reviewerpb.ReviewerReassignment{
+			PullRequestId: r.PullRequestID,
+			OldReviewerId: r.OldReviewerID,
+			NewReviewerId: r.NewReviewerID,
+			BlockedBy:     r.BlockedBy,
+		})
+	}
+	return result
+}
+
+func toAssignments(items []models.ReviewerAssignment) []*reviewerpb.ReviewerAssignment {
+	result := make([]*reviewerpb.ReviewerAssignment, 0, len(items))
+	for _, a := range items {
+		result = append(result, &reviewerpb.ReviewerAssignment{
+			PullRequestId: a.PullRequestID,
+			ReviewerId:    a.ReviewerID,
+		})
+	}
+	return result
+}
+
+func toLoad(load map[string]int) map[string]int32 {
+	result := make(map[string]int32, len(load))
+	for userID, n := range load {
+		result[userID] = int32(n)
+	}
+	return result
+}
+
+func fromStatisticsFilter(filter *reviewerpb.StatisticsFilter) models.StatisticsFilter {
+	return models.StatisticsFilter{
+		From:     fromTimestamp(filter.GetFrom()),
+		To:       fromTimestamp(filter.GetTo()),
+		TeamName: filter.GetTeamName(),
+	}
+}
+
+func toDurationStats(stats models.DurationStats) *reviewerpb.DurationStats {
+	return &reviewerpb.DurationStats{
+		Count:       int32(stats.Count),
+		MedianHours: stats.MedianHours,
+		P90Hours:    stats.P90Hours,
+	}
+}
@@ -0,0 +1,62 @@
+package grpcapi
+
+import (
+	"fmt"
+	"net/http"
+	"pr-reviewer-service/internal/models"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain - домен в google.rpc.ErrorInfo, по нему клиент понимает, что reason - наш ErrorCode
+const errorDomain = "pr-reviewer-service"
+
+// toStatus - перевожу ошибку сервиса в gRPC-статус.
+// Код ErrorCode и HTTP-статус беру из той же таблицы, что и REST (models.ErrorStatus),
+// а ErrorCode кладу в ErrorInfo.reason, чтобы клиенту не нужно было разбирать текст.
+func toStatus(err error) error {
+	code, httpStatus := models.ErrorStatus(err)
+
+	st := status.New(grpcCode(code, httpStatus), err.Error())
+	withInfo, detailsErr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: string(code),
+		Domain: errorDomain,
+	})
+	if detailsErr != nil {
+		return st.Err()
+	}
+	return withInfo.Err()
+}
+
+func grpcCode(code models.ErrorCode, httpStatus int) codes.Code {
+	switch code {
+	case models.ErrorTeamExists, models.ErrorPRExists:
+		return codes.AlreadyExists
+	case models.ErrorPRMerged, models.ErrorPRClosed, models.ErrorNotAssigned, models.ErrorNoCandidate,
+		models.ErrorNotEligible, models.ErrorReviewersFull:
+		return codes.FailedPrecondition
+	case models.ErrorVersionMismatch:
+		// Как и 412 в REST: ресурс успели поменять, клиенту нужно перечитать и повторить
+		return codes.Aborted
+	}
+
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusNotFound:
+		return codes.NotFound
+	}
+	return codes.Internal
+}
+
+// requireFields - аналог binding:"required" из REST-хендлеров: пары "имя поля", значение
+func requireFields(fields ...string) error {
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i+1] == "" {
+			return toStatus(fmt.Errorf("invalid request: %s is required", fields[i]))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package grpcapi
+
+import (
+	"context"
+	"pr-reviewer-service/internal/grpcapi/reviewerpb"
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/service"
+)
+
+type pullRequestServer struct {
+	reviewerpb.UnimplementedPullRequestServiceServer
+	service *service.Service
+}
+
+func (s *pullRequestServer) CreatePullRequest(ctx context.Context, req *reviewerpb.CreatePullRequestRequest) (*reviewerpb.CreatePullRequestResponse, error) {
+	if err := requireFields(
+		"pull_request_id", req.GetPullRequestId(),
+		"pull_request_name", req.GetPullRequestName(),
+		"author_id", req.GetAuthorId(),
+	); err != nil {
+		return nil, err
+	}
+
+	pr, err := s.service.CreatePullRequest(req.GetPullRequestId(), req.GetPullRequestName(), req.GetAuthorId(), req.GetLabels(),
+		models.PullRequestPriority(req.GetPriority()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerpb.CreatePullRequestResponse{Pr: toPullRequest(pr)}, nil
+}
+
+func (s *pullRequestServer) BulkCreatePullRequests(ctx context.Context, req *reviewerpb.BulkCreatePullRequestsRequest) (*reviewerpb.BulkCreatePullRequestsResponse, error) {
+	items := make([]models.BulkCreateItem, 0, len(req.GetPullRequests()))
+	for _, item := range req.GetPullRequests() {
+		items = append(items, models.BulkCreateItem{
+			PullRequestID:   item.GetPullRequestId(),
+			PullRequestName: item.GetPullRequestName(),
+			AuthorID:        item.GetAuthorId(),
+			Labels:          item.GetLabels(),
+			Priority:        models.PullRequestPriority(item.GetPriority()),
+		})
+	}
+
+	results, err := s.service.BulkCreatePullRequests(items)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &reviewerpb.BulkCreatePullRequestsResponse{
+		Results: make([]*reviewerpb.BulkCreateResult, 0, len(results)),
+	}
+	for _, result := range results {
+		resp.Results = append(resp.Results, &reviewerpb.BulkCreateResult{
+			PullRequestId: result.PullRequestID,
+			Created:       result.Created,
+			Pr:            toPullRequest(result.PR),
+			ErrorCode:     string(result.ErrorCode),
+			Message:       result.Message,
+		})
+		if result.Created {
+			resp.Created++
+		}
+	}
+	resp.Failed = int32(len(results)) - resp.Created
+	return resp, nil
+}
+
+func (s *pullRequestServer) GetPullRequest(ctx context.Context, req *reviewerpb.GetPullRequestRequest) (*reviewerpb.GetPullRequestResponse, error) {
+	if err := requireFields("pull_request_id", req.GetPullRequestId()); err != nil {
+		return nil, err
+	}
+
+	pr, err := s.service.GetPullRequest(req.GetPullRequestId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerpb.GetPullRequestResponse{Pr: toPullRequest(pr)}, nil
+}
+
+func (s *pullRequestServer) ListPullRequests(ctx context.Context, req *reviewerpb.ListPullRequestsRequest) (*reviewerpb.ListPullRequestsResponse, error) {
+	prs, err := s.service.ListPullRequests(models.PullRequestFilter{
+		Status:   req.GetStatus(),
+		AuthorID: req.GetAuthorId(),
+		TeamName: req.GetTeamName(),
+		Priority: req.GetPriority(),
+		Label:    req.GetLabel(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerpb.ListPullRequestsResponse{PullRequests: toPullRequests(prs)}, nil
+}
+
+func (s *pullRequestServer) UpdatePullRequest(ctx context.Context, req *reviewerpb.UpdatePullRequestRequest) (*reviewerpb.UpdatePullRequestResponse, error) {
+	if err := requireFields("pull_request_id", req.GetPullRequestId()); err != nil {
+		return nil, err
+	}
+
+	update := models.PullRequestUpdate{
+		Name:         req.PullRequestName,
+		Description:  req.Description,
+		URL:          req.Url,
+		TargetBranch: req.TargetBranch,
+		ChangedBy:    req.GetUpdatedBy(),
+	}
+	if req.Labels != nil {
+		labels := req.GetLabels().GetLabels()
+		update.Labels = &labels
+	}
+	if req.Priority != nil {
+		priority := models.PullRequestPriority(req.GetPriority())
+		update.Priority = &priority
+	}
+
+	pr, err := s.service.UpdatePullRequest(req.GetPullRequestId(), update, toExpectedVersion(req.ExpectedVersion))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerpb.UpdatePullRequestResponse{Pr: toPullRequest(pr)}, nil
+}
+
+func (s *pullRequestServer) MergePullRequest(ctx context.Context, req *reviewerpb.MergePullRequestRequest) (*reviewerpb.MergePullRequestResponse, error) {
+	if err := requireFields("pull_request_id", req.GetPullRequestId()); err != nil {
+		return nil, err
+	}
+
+	pr, err := s.service.MergePullRequest(req.GetPullRequestId(), toExpectedVersion(req.ExpectedVersion))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerpb.MergePullRequestResponse{Pr: toPullRequest(pr)}, nil
+}
+
+func (s *pullRequestServer) ReassignReviewer(ctx context.Context, req *reviewerpb.ReassignReviewerRequest) (*reviewerpb.ReassignReviewerResponse, error) {
+	if err := requireFields(
+		"pull_request_id", req.GetPullRequestId(),
+		"old_user_id", req.GetOldUserId(),
+	); err != nil {
+		return nil, err
+	}
+
+	pr, newReviewerID, err := s.service.ReassignReviewer(req.GetPullRequestId(), req.GetOldUserId(), req.GetNewReviewerId(), req.GetDryRun(), toExpectedVersion(req.ExpectedVersion))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerpb.ReassignReviewerResponse{
+		Pr:         toPullRequest(pr),
+		ReplacedBy: newReviewerID,
+		DryRun:     req.GetDryRun(),
+	}, nil
+}
+
+func (s *pullRequestServer) AddReviewer(ctx context.Context, req *reviewerpb.ChangeReviewerRequest) (*reviewerpb.ChangeReviewerResponse, error) {
+	return s.changeReviewer(req, s.service.AddReviewer)
+}
+
+func (s *pullRequestServer) RemoveReviewer(ctx context.Context, req *reviewerpb.ChangeReviewerRequest) (*reviewerpb.ChangeReviewerResponse, error) {
+	return s.changeReviewer(req, s.service.RemoveReviewer)
+}
+
+func (s *pullRequestServer) DeclineReview(ctx context.Context, req *reviewerpb.DeclineReviewRequest) (*reviewerpb.DeclineReviewResponse, error) {
+	if err := requireFields(
+		"pull_request_id", req.GetPullRequestId(),
+		"reviewer_id", req.GetReviewerId(),
+		"reason", req.GetReason(),
+	); err != nil {
+		return nil, err
+	}
+
+	pr, replacedBy, err := s.service.DeclineReview(req.GetPullRequestId(), req.GetReviewerId(), req.GetReason(), toExpectedVersion(req.ExpectedVersion))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerpb.DeclineReviewResponse{Pr: toPullRequest(pr), ReplacedBy: replacedBy}, nil
+}
+
+func (s *pullRequestServer) changeReviewer(req *reviewerpb.ChangeReviewerRequest, change func(prID, reviewerID string, expectedVersions []int) (*models.PullRequest, error)) (*reviewerpb.ChangeReviewerResponse, error) {
+	if err := requireFields(
+		"pull_request_id", req.GetPullRequestId(),
+		"reviewer_id", req.GetReviewerId(),
+	); err != nil {
+		return nil, err
+	}
+
+	pr, err := change(req.GetPullRequestId(), req.GetReviewerId(), toExpectedVersion(req.ExpectedVersion))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerpb.ChangeReviewerResponse{Pr: toPullRequest(pr)}, nil
+}
+
+func (s *pullRequestServer) ReviewPullRequest(ctx context.Context, req *reviewerpb.ReviewPullRequestRequest) (*reviewerpb.ReviewPullRequestResponse, error) {
+	if err := requireFields(
+		"pull_request_id", req.GetPullRequestId(),
+		"reviewer_id", req.GetReviewerId(),
+		"verdict", req.GetVerdict(),
+	); err != nil {
+		return nil, err
+	}
+
+	action, err := s.service.ReviewPullRequest(req.GetPullRequestId(), req.GetReviewerId(), models.ReviewVerdict(req.GetVerdict()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerpb.ReviewPullRequestResponse{
+		Review: &reviewerpb.ReviewAction{
+			PullRequestId: action.PullRequestID,
+			ReviewerId:    action.ReviewerID,
+			Verdict:       string(action.Verdict),
+			CreatedAt:     toTimestamp(&action.CreatedAt),
+		},
+	}, nil
+}
@@ -0,0 +1,39 @@
+package grpcapi
+
+import (
+	"context"
+	"log"
+	"pr-reviewer-service/internal/grpcapi/reviewerpb"
+	"pr-reviewer-service/internal/service"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// NewServer - gRPC-сервер поверх того же service.Service, что и REST.
+// Регистрирую все четыре сервиса из proto/reviewer.proto, health-check и reflection
+// (чтобы можно было ходить через grpcurl без .proto под рукой).
+func NewServer(svc *service.Service) *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(logErrors))
+
+	reviewerpb.RegisterTeamServiceServer(server, &teamServer{service: svc})
+	reviewerpb.RegisterUserServiceServer(server, &userServer{service: svc})
+	reviewerpb.RegisterPullRequestServiceServer(server, &pullRequestServer{service: svc})
+	reviewerpb.RegisterStatisticsServiceServer(server, &statisticsServer{service: svc})
+
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	reflection.Register(server)
+
+	return server
+}
+
+// logErrors - пишу в лог ошибки вызовов, как gin пишет запросы REST
+func logErrors(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		log.Printf("gRPC %s: %v", info.FullMethod, err)
+	}
+	return resp, err
+}
@@ -0,0 +1,232 @@
+package grpcapi
+
+import (
+	"context"
+	"pr-reviewer-service/internal/grpcapi/reviewerpb"
+	"pr-reviewer-service/internal/service"
+)
+
+// defaultLeaderboardLimit - как limit по умолчанию в REST
+const defaultLeaderboardLimit = 10
+
+type statisticsServer struct {
+	reviewerpb.UnimplementedStatisticsServiceServer
+	service *service.Service
+}
+
+// intervalOrDefault - в REST interval по умолчанию day, здесь так же
+func intervalOrDefault(interval string) string {
+	if interval == "" {
+		return "day"
+	}
+	return interval
+}
+
+func (s *statisticsServer) GetStatistics(ctx context.Context, req *reviewerpb.GetStatisticsRequest) (*reviewerpb.GetStatisticsResponse, error) {
+	stats, err := s.service.GetStatistics(fromStatisticsFilter(req.GetFilter()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &reviewerpb.GetStatisticsResponse{
+		UserStats: make([]*reviewerpb.UserReviewStats, 0, len(stats.UserStats)),
+		PrStats: &reviewerpb.PRStats{
+			TotalPrs:         int32(stats.PRStats.TotalPRs),
+			OpenPrs:          int32(stats.PRStats.OpenPRs),
+			MergedPrs:        int32(stats.PRStats.MergedPRs),
+			TotalAssignments: int32(stats.PRStats.TotalAssignments),
+			TotalDeclines:    int32(stats.PRStats.TotalDeclines),
+		},
+	}
+	for _, stat := range stats.UserStats {
+		resp.UserStats = append(resp.UserStats, &reviewerpb.UserReviewStats{
+			UserId:            stat.UserID,
+			Username:          stat.Username,
+			TotalAssignments:  int32(stat.TotalAssignments),
+			OpenAssignments:   int32(stat.OpenAssignments),
+			MergedAssignments: int32(stat.MergedAssignments),
+			Declines:          int32(stat.Declines),
+		})
+	}
+	return resp, nil
+}
+
+func (s *statisticsServer) GetTimeSeries(ctx context.Context, req *reviewerpb.GetTimeSeriesRequest) (*reviewerpb.GetTimeSeriesResponse, error) {
+	series, err := s.service.GetTimeSeries(intervalOrDefault(req.GetInterval()), fromStatisticsFilter(req.GetFilter()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &reviewerpb.GetTimeSeriesResponse{
+		Interval: series.Interval,
+		From:     toTimestamp(&series.From),
+		To:       toTimestamp(&series.To),
+		TeamName: series.TeamName,
+		Buckets:  make([]*reviewerpb.TimeSeriesBucket, 0, len(series.Buckets)),
+	}
+	for i := range series.Buckets {
+		bucket := &series.Buckets[i]
+		resp.Buckets = append(resp.Buckets, &reviewerpb.TimeSeriesBucket{
+			BucketStart:   toTimestamp(&bucket.BucketStart),
+			PrsCreated:    int32(bucket.PRsCreated),
+			PrsMerged:     int32(bucket.PRsMerged),
+			Assignments:   int32(bucket.Assignments),
+			Reassignments: int32(bucket.Reassignments),
+		})
+	}
+	return resp, nil
+}
+
+func (s *statisticsServer) GetReviewerAssignments(ctx context.Context, req *reviewerpb.GetReviewerAssignmentsRequest) (*reviewerpb.GetReviewerAssignmentsResponse, error) {
+	series, err := s.service.GetReviewerAssignmentSeries(intervalOrDefault(req.GetInterval()), fromStatisticsFilter(req.GetFilter()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &reviewerpb.GetReviewerAssignmentsResponse{
+		Interval:  series.Interval,
+		From:      toTimestamp(&series.From),
+		To:        toTimestamp(&series.To),
+		TeamName:  series.TeamName,
+		Reviewers: make([]*reviewerpb.ReviewerAssignmentSeries, 0, len(series.Reviewers)),
+	}
+	for _, reviewer := range series.Reviewers {
+		buckets := make([]*reviewerpb.AssignmentBucket, 0, len(reviewer.Buckets))
+		for i := range reviewer.Buckets {
+			bucket := &reviewer.Buckets[i]
+			buckets = append(buckets, &reviewerpb.AssignmentBucket{
+				BucketStart: toTimestamp(&bucket.BucketStart),
+				Assignments: int32(bucket.Assignments),
+			})
+		}
+		resp.Reviewers = append(resp.Reviewers, &reviewerpb.ReviewerAssignmentSeries{
+			UserId:           reviewer.UserID,
+			Username:         reviewer.Username,
+			TotalAssignments: int32(reviewer.TotalAssignments),
+			Buckets:          buckets,
+		})
+	}
+	return resp, nil
+}
+
+func (s *statisticsServer) GetTeamStatistics(ctx context.Context, req *reviewerpb.GetTeamStatisticsRequest) (*reviewerpb.GetTeamStatisticsResponse, error) {
+	teams, err := s.service.GetTeamStatistics(fromStatisticsFilter(req.GetFilter()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &reviewerpb.GetTeamStatisticsResponse{Teams: make([]*reviewerpb.TeamStats, 0, len(teams))}
+	for _, team := range teams {
+		resp.Teams = append(resp.Teams, &reviewerpb.TeamStats{
+			TeamName:      team.TeamName,
+			Members:       int32(team.Members),
+			ActiveMembers: int32(team.ActiveMembers),
+			PrsCreated:    int32(team.PRsCreated),
+			PrsMerged:     int32(team.PRsMerged),
+			Assignments:   int32(team.Assignments),
+			Reassignments: int32(team.Reassignments),
+		})
+	}
+	return resp, nil
+}
+
+func (s *statisticsServer) GetTurnaround(ctx context.Context, req *reviewerpb.GetTurnaroundRequest) (*reviewerpb.GetTurnaroundResponse, error) {
+	turnaround, err := s.service.GetTurnaround(fromStatisticsFilter(req.GetFilter()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &reviewerpb.GetTurnaroundResponse{
+		From:        toTimestamp(turnaround.From),
+		To:          toTimestamp(turnaround.To),
+		TeamName:    turnaround.TeamName,
+		TimeToMerge: toDurationStats(turnaround.TimeToMerge),
+		ByTeam:      make([]*reviewerpb.TeamMergeTime, 0, len(turnaround.ByTeam)),
+		ByAuthor:    make([]*reviewerpb.AuthorMergeTime, 0, len(turnaround.ByAuthor)),
+		ByReviewer:  make([]*reviewerpb.ReviewerResponseTime, 0, len(turnaround.ByReviewer)),
+	}
+	for _, team := range turnaround.ByTeam {
+		resp.ByTeam = append(resp.ByTeam, &reviewerpb.TeamMergeTime{
+			TeamName: team.TeamName,
+			Stats:    toDurationStats(team.DurationStats),
+		})
+	}
+	for _, author := range turnaround.ByAuthor {
+		resp.ByAuthor = append(resp.ByAuthor, &reviewerpb.AuthorMergeTime{
+			AuthorId: author.AuthorID,
+			Username: author.Username,
+			Stats:    toDurationStats(author.DurationStats),
+		})
+	}
+	for _, reviewer := range turnaround.ByReviewer {
+		resp.ByReviewer = append(resp.ByReviewer, &reviewerpb.ReviewerResponseTime{
+			UserId:   reviewer.UserID,
+			Username: reviewer.Username,
+			Pending:  int32(reviewer.Pending),
+			Stats:    toDurationStats(reviewer.DurationStats),
+		})
+	}
+	return resp, nil
+}
+
+func (s *statisticsServer) GetLeaderboard(ctx context.Context, req *reviewerpb.GetLeaderboardRequest) (*reviewerpb.GetLeaderboardResponse, error) {
+	limit := int(req.GetLimit())
+	if limit == 0 {
+		limit = defaultLeaderboardLimit
+	}
+
+	entries, err := s.service.GetLeaderboard(fromStatisticsFilter(req.GetFilter()), limit)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &reviewerpb.GetLeaderboardResponse{Leaderboard: make([]*reviewerpb.LeaderboardEntry, 0, len(entries))}
+	for _, entry := range entries {
+		resp.Leaderboard = append(resp.Leaderboard, &reviewerpb.LeaderboardEntry{
+			Rank:                int32(entry.Rank),
+			UserId:              entry.UserID,
+			Username:            entry.Username,
+			TeamName:            entry.TeamName,
+			Reviews:             int32(entry.Reviews),
+			MedianResponseHours: entry.MedianResponseHours,
+		})
+	}
+	return resp, nil
+}
+
+func (s *statisticsServer) GetFairness(ctx context.Context, req *reviewerpb.GetFairnessRequest) (*reviewerpb.GetFairnessResponse, error) {
+	fairness, err := s.service.GetFairness(fromStatisticsFilter(req.GetFilter()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &reviewerpb.GetFairnessResponse{
+		From:  toTimestamp(&fairness.From),
+		To:    toTimestamp(&fairness.To),
+		Teams: make([]*reviewerpb.TeamFairness, 0, len(fairness.Teams)),
+	}
+	for _, team := range fairness.Teams {
+		members := make([]*reviewerpb.MemberFairness, 0, len(team.Members))
+		for _, m := range team.Members {
+			members = append(members, &reviewerpb.MemberFairness{
+				UserId:              m.UserID,
+				Username:            m.Username,
+				IsActive:            m.IsActive,
+				Assignments:         int32(m.Assignments),
+				ActiveHours:         m.ActiveHours,
+				AssignmentShare:     m.AssignmentShare,
+				ActiveTimeShare:     m.ActiveTimeShare,
+				ExpectedAssignments: m.ExpectedAssignments,
+				LoadRatio:           m.LoadRatio,
+				Outlier:             m.Outlier,
+			})
+		}
+		resp.Teams = append(resp.Teams, &reviewerpb.TeamFairness{
+			TeamName:         team.TeamName,
+			TotalAssignments: int32(team.TotalAssignments),
+			Gini:             team.Gini,
+			Members:          members,
+		})
+	}
+	return resp, nil
+}
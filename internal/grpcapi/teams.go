@@ -0,0 +1,72 @@
+package grpcapi
+
+import (
+	"context"
+	"pr-reviewer-service/internal/grpcapi/reviewerpb"
+	"pr-reviewer-service/internal/service"
+)
+
+type teamServer struct {
+	reviewerpb.UnimplementedTeamServiceServer
+	service *service.Service
+}
+
+func (s *teamServer) AddTeam(ctx context.Context, req *reviewerpb.AddTeamRequest) (*reviewerpb.AddTeamResponse, error) {
+	if err := requireFields("team.team_name", req.GetTeam().GetTeamName()); err != nil {
+		return nil, err
+	}
+
+	team := fromTeam(req.GetTeam())
+	if err := s.service.CreateTeam(team); err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerpb.AddTeamResponse{Team: toTeam(team)}, nil
+}
+
+func (s *teamServer) GetTeam(ctx context.Context, req *reviewerpb.GetTeamRequest) (*reviewerpb.GetTeamResponse, error) {
+	if err := requireFields("team_name", req.GetTeamName()); err != nil {
+		return nil, err
+	}
+
+	team, err := s.service.GetTeam(req.GetTeamName())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerpb.GetTeamResponse{Team: toTeam(team)}, nil
+}
+
+func (s *teamServer) BulkDeactivateTeam(ctx context.Context, req *reviewerpb.BulkDeactivateTeamRequest) (*reviewerpb.BulkDeactivateTeamResponse, error) {
+	if err := requireFields("team_name", req.GetTeamName()); err != nil {
+		return nil, err
+	}
+
+	result, err := s.service.BulkDeactivateTeam(req.GetTeamName(), req.GetDryRun(), toExpectedVersion(req.ExpectedVersion))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerpb.BulkDeactivateTeamResponse{
+		DeactivatedUserIds: result.DeactivatedUserIDs,
+		ReassignedPrs:      result.ReassignedPRs,
+		Reassignments:      toReassignments(result.Reassignments),
+		WithoutCandidate:   toReassignments(result.WithoutCandidate),
+		DryRun:             result.DryRun,
+	}, nil
+}
+
+func (s *teamServer) RebalanceTeam(ctx context.Context, req *reviewerpb.RebalanceTeamRequest) (*reviewerpb.RebalanceTeamResponse, error) {
+	if err := requireFields("team_name", req.GetTeamName()); err != nil {
+		return nil, err
+	}
+
+	result, err := s.service.RebalanceTeam(req.GetTeamName(), req.GetDryRun())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerpb.RebalanceTeamResponse{
+		TeamName:      result.TeamName,
+		LoadBefore:    toLoad(result.LoadBefore),
+		LoadAfter:     toLoad(result.LoadAfter),
+		Reassignments: toReassignments(result.Reassignments),
+		DryRun:        result.DryRun,
+	}, nil
+}
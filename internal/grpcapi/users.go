@@ -0,0 +1,101 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"pr-reviewer-service/internal/grpcapi/reviewerpb"
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/service"
+)
+
+type userServer struct {
+	reviewerpb.UnimplementedUserServiceServer
+	service *service.Service
+}
+
+func (s *userServer) SetIsActive(ctx context.Context, req *reviewerpb.SetIsActiveRequest) (*reviewerpb.SetIsActiveResponse, error) {
+	if err := requireFields("user_id", req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	result, err := s.service.SetUserActive(req.GetUserId(), req.GetIsActive(), req.ReassignReviews, toExpectedVersion(req.ExpectedVersion))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerpb.SetIsActiveResponse{
+		User:             toUser(result.User),
+		ReassignedPrs:    result.ReassignedPRs,
+		Reassignments:    toReassignments(result.Reassignments),
+		WithoutCandidate: toReassignments(result.WithoutCandidate),
+	}, nil
+}
+
+func (s *userServer) BulkSetIsActive(ctx context.Context, req *reviewerpb.BulkSetIsActiveRequest) (*reviewerpb.BulkSetIsActiveResponse, error) {
+	// Как и в REST, is_active обязателен: случайно выключить всех по умолчанию не хочу
+	if req.IsActive == nil {
+		return nil, toStatus(fmt.Errorf("invalid request: is_active is required"))
+	}
+
+	filter := models.UserFilter{
+		UsernamePrefix: req.GetFilter().GetUsernamePrefix(),
+		ExcludeUserIDs: req.GetFilter().GetExcludeUserIds(),
+	}
+	result, err := s.service.BulkSetUsersActive(req.GetUserIds(), req.GetTeamName(), filter, req.GetIsActive(), req.GetDryRun(), toExpectedVersion(req.ExpectedVersion))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerpb.BulkSetIsActiveResponse{
+		IsActive:            result.IsActive,
+		UpdatedUserIds:      result.UpdatedUserIDs,
+		UnchangedUserIds:    result.UnchangedUserIDs,
+		NotFoundUserIds:     result.NotFoundUserIDs,
+		ReassignedPrs:       result.ReassignedPRs,
+		Reassignments:       toReassignments(result.Reassignments),
+		WithoutCandidate:    toReassignments(result.WithoutCandidate),
+		BackfilledReviewers: toAssignments(result.BackfilledReviewers),
+		DryRun:              result.DryRun,
+	}, nil
+}
+
+func (s *userServer) GetReview(ctx context.Context, req *reviewerpb.GetReviewRequest) (*reviewerpb.GetReviewResponse, error) {
+	if err := requireFields("user_id", req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	prs, err := s.service.GetPullRequestsByReviewer(req.GetUserId(), models.PullRequestFilter{
+		Status:   req.GetStatus(),
+		Priority: req.GetPriority(),
+		Label:    req.GetLabel(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerpb.GetReviewResponse{
+		UserId:       req.GetUserId(),
+		PullRequests: toPullRequestShorts(prs),
+	}, nil
+}
+
+func (s *userServer) GetUser(ctx context.Context, req *reviewerpb.GetUserRequest) (*reviewerpb.GetUserResponse, error) {
+	if err := requireFields("user_id", req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	user, err := s.service.GetUser(req.GetUserId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerpb.GetUserResponse{User: toUser(user)}, nil
+}
+
+func (s *userServer) SetProfile(ctx context.Context, req *reviewerpb.SetProfileRequest) (*reviewerpb.SetProfileResponse, error) {
+	if err := requireFields("user_id", req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	user, err := s.service.SetUserProfile(req.GetUserId(), models.Seniority(req.GetSeniority()), req.GetSkills())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerpb.SetProfileResponse{User: toUser(user)}, nil
+}
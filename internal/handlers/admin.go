@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"pr-reviewer-service/internal/models"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportSnapshot - выгрузка всех команд, пользователей, PR и их настроек одним JSON
+func (h *Handlers) ExportSnapshot(c *gin.Context) {
+	snapshot, err := h.service.ExportSnapshot()
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("snapshot-%s.json", snapshot.ExportedAt.Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// ImportSnapshot - загрузка выгрузки. Режим конфликтов задаю параметром mode: fail (по умолчанию), skip, overwrite.
+func (h *Handlers) ImportSnapshot(c *gin.Context) {
+	var snapshot models.Snapshot
+	if err := c.ShouldBindJSON(&snapshot); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	mode := models.ImportMode(c.DefaultQuery("mode", string(models.ImportModeFail)))
+	result, err := h.service.ImportSnapshot(&snapshot, mode)
+	if err != nil {
+		// В режиме fail конфликт приходит как "TEAM_EXISTS: <имя>" или "PR_EXISTS: <id>"
+		if strings.HasPrefix(err.Error(), "TEAM_EXISTS") {
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error: struct {
+					Code    models.ErrorCode `json:"code"`
+					Message string           `json:"message"`
+				}{
+					Code:    models.ErrorTeamExists,
+					Message: "team already exists: " + strings.TrimPrefix(err.Error(), "TEAM_EXISTS: "),
+				},
+			})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "PR_EXISTS") {
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error: struct {
+					Code    models.ErrorCode `json:"code"`
+					Message string           `json:"message"`
+				}{
+					Code:    models.ErrorPRExists,
+					Message: "pull request already exists: " + strings.TrimPrefix(err.Error(), "PR_EXISTS: "),
+				},
+			})
+			return
+		}
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"pr-reviewer-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetDigestPreferences - почта пользователя и подписка на ежедневную сводку по ревью
+func (h *Handlers) SetDigestPreferences(c *gin.Context) {
+	var req struct {
+		UserID        string `json:"user_id" binding:"required"`
+		Email         string `json:"email"`
+		DigestEnabled bool   `json:"digest_enabled"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	prefs, err := h.service.SetDigestPreferences(req.UserID, req.Email, req.DigestEnabled)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// GetDigest - превью сводки: ровно то письмо, которое пользователь получил бы сейчас.
+// Работает и без подписки, чтобы можно было посмотреть перед тем, как её включать.
+func (h *Handlers) GetDigest(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: "user_id is required",
+			},
+		})
+		return
+	}
+
+	digest, err := h.service.BuildDigest(userID)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, digest)
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"pr-reviewer-service/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	formatJSON   = "json"
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+
+	// flushEvery - раз в столько строк проталкиваю накопленное клиенту
+	flushEvery = 100
+)
+
+// negotiateFormat - формат ответа: параметр format важнее заголовка Accept.
+// По умолчанию, как и раньше, JSON.
+func negotiateFormat(c *gin.Context) (string, error) {
+	switch format := c.Query("format"); format {
+	case formatJSON, formatCSV, formatNDJSON:
+		return format, nil
+	case "":
+	default:
+		return "", fmt.Errorf("invalid format: must be json, csv or ndjson")
+	}
+
+	accept := c.GetHeader("Accept")
+	switch {
+	case strings.Contains(accept, "text/csv"):
+		return formatCSV, nil
+	case strings.Contains(accept, "application/x-ndjson"):
+		return formatNDJSON, nil
+	}
+	return formatJSON, nil
+}
+
+// rowStream - пишет строки в CSV или NDJSON прямо в ответ по мере того, как они приходят из базы.
+// Заголовки ответа отправляю только на первой строке (или в конце), чтобы ошибку до первой
+// строки можно было отдать нормальным JSON с нужным статусом.
+type rowStream struct {
+	c        *gin.Context
+	format   string
+	filename string
+	header   []string
+	csv      *csv.Writer
+	json     *json.Encoder
+	rows     int
+}
+
+func newRowStream(c *gin.Context, format, filename string, header []string) *rowStream {
+	return &rowStream{c: c, format: format, filename: filename, header: header}
+}
+
+func (s *rowStream) start() error {
+	if s.csv != nil || s.json != nil {
+		return nil
+	}
+
+	if s.format == formatCSV {
+		s.c.Header("Content-Type", "text/csv; charset=utf-8")
+		s.c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", s.filename+".csv"))
+		s.c.Status(http.StatusOK)
+		s.csv = csv.NewWriter(s.c.Writer)
+		return s.csv.Write(s.header)
+	}
+
+	s.c.Header("Content-Type", "application/x-ndjson")
+	s.c.Status(http.StatusOK)
+	s.json = json.NewEncoder(s.c.Writer)
+	return nil
+}
+
+// Write - одна строка: record для CSV, value для NDJSON
+func (s *rowStream) Write(record []string, value interface{}) error {
+	if err := s.start(); err != nil {
+		return err
+	}
+
+	if s.csv != nil {
+		if err := s.csv.Write(record); err != nil {
+			return err
+		}
+	} else if err := s.json.Encode(value); err != nil {
+		return err
+	}
+
+	s.rows++
+	if s.rows%flushEvery == 0 {
+		s.flush()
+	}
+	return nil
+}
+
+func (s *rowStream) flush() {
+	if s.csv != nil {
+		s.csv.Flush()
+	}
+	s.c.Writer.Flush()
+}
+
+// Finish - дописываю хвост. Если выгрузка упала на середине, статус уже не поменять,
+// так что просто обрываю ответ и пишу в лог.
+// Возвращаю true, если ошибку ещё можно отдать клиенту обычным ответом.
+func (s *rowStream) Finish(err error) bool {
+	if err != nil {
+		if !s.c.Writer.Written() {
+			// Строки ещё в буфере CSV, их просто выбрасываю вместе с заголовками выгрузки
+			s.c.Writer.Header().Del("Content-Type")
+			s.c.Writer.Header().Del("Content-Disposition")
+			return true
+		}
+		log.Printf("Выгрузка %s оборвалась после %d строк: %v", s.filename, s.rows, err)
+		s.flush()
+		return false
+	}
+
+	if err := s.start(); err != nil {
+		log.Printf("Выгрузка %s: %v", s.filename, err)
+	}
+	s.flush()
+	return false
+}
+
+var userStatsCSVHeader = []string{"user_id", "username", "total_assignments", "open_assignments", "merged_assignments", "declines"}
+
+func userStatsRecord(stat *models.UserReviewStats) []string {
+	return []string{
+		stat.UserID,
+		stat.Username,
+		strconv.Itoa(stat.TotalAssignments),
+		strconv.Itoa(stat.OpenAssignments),
+		strconv.Itoa(stat.MergedAssignments),
+		strconv.Itoa(stat.Declines),
+	}
+}
+
+var pullRequestShortCSVHeader = []string{"pull_request_id", "pull_request_name", "author_id", "status", "priority", "labels"}
+
+func pullRequestShortRecord(pr *models.PullRequestShort) []string {
+	return []string{pr.PullRequestID, pr.PullRequestName, pr.AuthorID, string(pr.Status), string(pr.Priority), strings.Join(pr.Labels, ";")}
+}
+
+var pullRequestCSVHeader = []string{
+	"pull_request_id", "pull_request_name", "author_id", "status", "assigned_reviewers",
+	"need_more_reviewers", "created_at", "merged_at", "version", "priority", "labels",
+	"target_branch", "url",
+}
+
+// pullRequestRecord - ревьюеров и метки в CSV пишу через ";" в одной ячейке
+func pullRequestRecord(pr *models.PullRequest) []string {
+	return []string{
+		pr.PullRequestID,
+		pr.PullRequestName,
+		pr.AuthorID,
+		string(pr.Status),
+		strings.Join(pr.AssignedReviewers, ";"),
+		strconv.FormatBool(pr.NeedMoreReviewers),
+		formatCSVTime(pr.CreatedAt),
+		formatCSVTime(pr.MergedAt),
+		strconv.Itoa(pr.Version),
+		string(pr.Priority),
+		strings.Join(pr.Labels, ";"),
+		pr.TargetBranch,
+		pr.URL,
+	}
+}
+
+func formatCSVTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"pr-reviewer-service/internal/models"
 	"pr-reviewer-service/internal/service"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -49,18 +51,11 @@ func (h *Handlers) CreateTeam(c *gin.Context) {
 			})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: struct {
-				Code    models.ErrorCode `json:"code"`
-				Message string           `json:"message"`
-			}{
-				Code:    models.ErrorNotFound,
-				Message: err.Error(),
-			},
-		})
+		respondServiceError(c, err)
 		return
 	}
 
+	setETag(c, team.Version)
 	c.JSON(http.StatusCreated, gin.H{"team": team})
 }
 
@@ -81,18 +76,11 @@ func (h *Handlers) GetTeam(c *gin.Context) {
 
 	team, err := h.service.GetTeam(teamName)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{
-			Error: struct {
-				Code    models.ErrorCode `json:"code"`
-				Message string           `json:"message"`
-			}{
-				Code:    models.ErrorNotFound,
-				Message: "team not found",
-			},
-		})
+		respondServiceError(c, err)
 		return
 	}
 
+	setETag(c, team.Version)
 	c.JSON(http.StatusOK, team)
 }
 
@@ -101,6 +89,8 @@ func (h *Handlers) SetUserActive(c *gin.Context) {
 	var req struct {
 		UserID   string `json:"user_id" binding:"required"`
 		IsActive bool   `json:"is_active"`
+		// ReassignReviews - переназначать ли открытые ревью при деактивации, по умолчанию как настроен сервис
+		ReassignReviews *bool `json:"reassign_reviews"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -116,21 +106,58 @@ func (h *Handlers) SetUserActive(c *gin.Context) {
 		return
 	}
 
-	user, err := h.service.SetUserActive(req.UserID, req.IsActive)
+	ifMatch, err := parseIfMatch(c)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	result, err := h.service.SetUserActive(req.UserID, req.IsActive, req.ReassignReviews, ifMatch)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// BulkSetUserActive - массовая активация/деактивация по списку user_ids или по команде с фильтром.
+// С dry_run только показываю, что бы изменилось.
+func (h *Handlers) BulkSetUserActive(c *gin.Context) {
+	var req struct {
+		UserIDs  []string          `json:"user_ids"`
+		TeamName string            `json:"team_name"`
+		Filter   models.UserFilter `json:"filter"`
+		IsActive *bool             `json:"is_active" binding:"required"`
+		DryRun   bool              `json:"dry_run"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Error: struct {
 				Code    models.ErrorCode `json:"code"`
 				Message string           `json:"message"`
 			}{
 				Code:    models.ErrorNotFound,
-				Message: "user not found",
+				Message: err.Error(),
 			},
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"user": user})
+	ifMatch, err := parseIfMatch(c)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	result, err := h.service.BulkSetUsersActive(req.UserIDs, req.TeamName, req.Filter, *req.IsActive, req.DryRun, ifMatch)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
 }
 
 func (h *Handlers) GetReview(c *gin.Context) {
@@ -148,21 +175,9 @@ func (h *Handlers) GetReview(c *gin.Context) {
 		return
 	}
 
-	prs, err := h.service.GetPullRequestsByReviewer(userID)
+	format, err := negotiateFormat(c)
 	if err != nil {
-		if err.Error() == "user not found" {
-			c.JSON(http.StatusNotFound, models.ErrorResponse{
-				Error: struct {
-					Code    models.ErrorCode `json:"code"`
-					Message string           `json:"message"`
-				}{
-					Code:    models.ErrorNotFound,
-					Message: "user not found",
-				},
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Error: struct {
 				Code    models.ErrorCode `json:"code"`
 				Message string           `json:"message"`
@@ -174,6 +189,29 @@ func (h *Handlers) GetReview(c *gin.Context) {
 		return
 	}
 
+	filter := models.PullRequestFilter{
+		Status:   c.Query("status"),
+		Priority: c.Query("priority"),
+		Label:    c.Query("label"),
+	}
+
+	var prs []*models.PullRequestShort
+	if format == formatJSON {
+		prs, err = h.service.GetPullRequestsByReviewer(userID, filter)
+	} else {
+		stream := newRowStream(c, format, "reviews-"+userID, pullRequestShortCSVHeader)
+		err = h.service.StreamPullRequestsByReviewer(userID, filter, func(pr *models.PullRequestShort) error {
+			return stream.Write(pullRequestShortRecord(pr), pr)
+		})
+		if !stream.Finish(err) {
+			return
+		}
+	}
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"user_id":       userID,
 		"pull_requests": prs,
@@ -188,6 +226,10 @@ func (h *Handlers) CreatePullRequest(c *gin.Context) {
 		PullRequestID   string `json:"pull_request_id" binding:"required"`
 		PullRequestName string `json:"pull_request_name" binding:"required"`
 		AuthorID        string `json:"author_id" binding:"required"`
+		// Labels - метки PR, например для исключения из политики брошенных PR
+		Labels []string `json:"labels"`
+		// Priority - low, normal (по умолчанию), high или urgent
+		Priority models.PullRequestPriority `json:"priority"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -203,7 +245,7 @@ func (h *Handlers) CreatePullRequest(c *gin.Context) {
 		return
 	}
 
-	pr, err := h.service.CreatePullRequest(req.PullRequestID, req.PullRequestName, req.AuthorID)
+	pr, err := h.service.CreatePullRequest(req.PullRequestID, req.PullRequestName, req.AuthorID, req.Labels, req.Priority)
 	if err != nil {
 		// Обрабатываю разные ошибки от сервиса
 		if err.Error() == "PR_EXISTS" {
@@ -230,7 +272,24 @@ func (h *Handlers) CreatePullRequest(c *gin.Context) {
 			})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		// Остальное, в том числе NO_CANDIDATE с причиной (нет security-ревьюера или мешает правило
+		// команды), - по общей таблице
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"pr": pr})
+}
+
+// BulkCreatePullRequests - создание пачки PR за один запрос.
+// Отвечаю 200 с результатом по каждому PR, даже если часть из них не создалась.
+func (h *Handlers) BulkCreatePullRequests(c *gin.Context) {
+	var req struct {
+		PullRequests []models.BulkCreateItem `json:"pull_requests" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Error: struct {
 				Code    models.ErrorCode `json:"code"`
 				Message string           `json:"message"`
@@ -242,7 +301,95 @@ func (h *Handlers) CreatePullRequest(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"pr": pr})
+	results, err := h.service.BulkCreatePullRequests(req.PullRequests)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	created := 0
+	for _, result := range results {
+		if result.Created {
+			created++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+		"created": created,
+		"failed":  len(results) - created,
+	})
+}
+
+// GetPullRequest - полный PR по ID, версия отдаётся в ETag
+func (h *Handlers) GetPullRequest(c *gin.Context) {
+	prID := c.Query("pull_request_id")
+	if prID == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: "pull_request_id is required",
+			},
+		})
+		return
+	}
+
+	pr, err := h.service.GetPullRequest(prID)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	setETag(c, pr.Version)
+	c.JSON(http.StatusOK, gin.H{"pr": pr})
+}
+
+// ListPullRequests - список PR с фильтрами status, author_id, team_name (команда автора), priority и label.
+// Как и статистику, можно выгрузить в CSV или NDJSON.
+func (h *Handlers) ListPullRequests(c *gin.Context) {
+	filter := models.PullRequestFilter{
+		Status:   c.Query("status"),
+		AuthorID: c.Query("author_id"),
+		TeamName: c.Query("team_name"),
+		Priority: c.Query("priority"),
+		Label:    c.Query("label"),
+	}
+
+	format, err := negotiateFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	var prs []*models.PullRequest
+	if format == formatJSON {
+		prs, err = h.service.ListPullRequests(filter)
+	} else {
+		stream := newRowStream(c, format, "pull-requests", pullRequestCSVHeader)
+		err = h.service.StreamPullRequests(filter, func(pr *models.PullRequest) error {
+			return stream.Write(pullRequestRecord(pr), pr)
+		})
+		if !stream.Finish(err) {
+			return
+		}
+	}
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pull_requests": prs})
 }
 
 func (h *Handlers) MergePullRequest(c *gin.Context) {
@@ -263,27 +410,171 @@ func (h *Handlers) MergePullRequest(c *gin.Context) {
 		return
 	}
 
-	pr, err := h.service.MergePullRequest(req.PullRequestID)
+	ifMatch, err := parseIfMatch(c)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{
+		respondServiceError(c, err)
+		return
+	}
+
+	pr, err := h.service.MergePullRequest(req.PullRequestID, ifMatch)
+	if err != nil {
+		if err.Error() == "VERSION_MISMATCH" {
+			c.JSON(http.StatusPreconditionFailed, models.ErrorResponse{
+				Error: struct {
+					Code    models.ErrorCode `json:"code"`
+					Message string           `json:"message"`
+				}{
+					Code:    models.ErrorVersionMismatch,
+					Message: "pull request version does not match If-Match",
+				},
+			})
+			return
+		}
+		if err.Error() == "PR_CLOSED" {
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error: struct {
+					Code    models.ErrorCode `json:"code"`
+					Message string           `json:"message"`
+				}{
+					Code:    models.ErrorPRClosed,
+					Message: "cannot merge closed PR",
+				},
+			})
+			return
+		}
+		respondServiceError(c, err)
+		return
+	}
+
+	setETag(c, pr.Version)
+	c.JSON(http.StatusOK, gin.H{"pr": pr})
+}
+
+// UpdatePullRequest - правлю название, описание, ссылку, целевую ветку, метки и/или приоритет
+// открытого PR. Не переданное поле не трогаю, updated_by попадает в журнал правок.
+func (h *Handlers) UpdatePullRequest(c *gin.Context) {
+	var req struct {
+		PullRequestID   string                      `json:"pull_request_id" binding:"required"`
+		PullRequestName *string                     `json:"pull_request_name"`
+		Description     *string                     `json:"description"`
+		URL             *string                     `json:"url"`
+		TargetBranch    *string                     `json:"target_branch"`
+		Labels          *[]string                   `json:"labels"`
+		Priority        *models.PullRequestPriority `json:"priority"`
+		UpdatedBy       string                      `json:"updated_by"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Error: struct {
 				Code    models.ErrorCode `json:"code"`
 				Message string           `json:"message"`
 			}{
 				Code:    models.ErrorNotFound,
-				Message: "pull request not found",
+				Message: err.Error(),
 			},
 		})
 		return
 	}
 
+	ifMatch, err := parseIfMatch(c)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	pr, err := h.service.UpdatePullRequest(req.PullRequestID, models.PullRequestUpdate{
+		Name:         req.PullRequestName,
+		Description:  req.Description,
+		URL:          req.URL,
+		TargetBranch: req.TargetBranch,
+		Labels:       req.Labels,
+		Priority:     req.Priority,
+		ChangedBy:    req.UpdatedBy,
+	}, ifMatch)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	setETag(c, pr.Version)
 	c.JSON(http.StatusOK, gin.H{"pr": pr})
 }
 
+// ReviewPullRequest - ревьюер оставляет вердикт: APPROVED, CHANGES_REQUESTED или COMMENTED
+func (h *Handlers) ReviewPullRequest(c *gin.Context) {
+	var req struct {
+		PullRequestID string `json:"pull_request_id" binding:"required"`
+		ReviewerID    string `json:"reviewer_id" binding:"required"`
+		Verdict       string `json:"verdict" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	action, err := h.service.ReviewPullRequest(req.PullRequestID, req.ReviewerID, models.ReviewVerdict(req.Verdict))
+	if err != nil {
+		if err.Error() == "PR_MERGED" {
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error: struct {
+					Code    models.ErrorCode `json:"code"`
+					Message string           `json:"message"`
+				}{
+					Code:    models.ErrorPRMerged,
+					Message: "cannot review merged PR",
+				},
+			})
+			return
+		}
+		if err.Error() == "PR_CLOSED" {
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error: struct {
+					Code    models.ErrorCode `json:"code"`
+					Message string           `json:"message"`
+				}{
+					Code:    models.ErrorPRClosed,
+					Message: "cannot review closed PR",
+				},
+			})
+			return
+		}
+		if err.Error() == "NOT_ASSIGNED" {
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error: struct {
+					Code    models.ErrorCode `json:"code"`
+					Message string           `json:"message"`
+				}{
+					Code:    models.ErrorNotAssigned,
+					Message: "reviewer is not assigned to this PR",
+				},
+			})
+			return
+		}
+		// Остальное - по общей таблице: не найденный PR или ревьюер - 404, неверный вердикт - 400,
+		// а ошибка базы - 500, а не "not found"
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"review": action})
+}
+
 func (h *Handlers) ReassignReviewer(c *gin.Context) {
 	var req struct {
 		PullRequestID string `json:"pull_request_id" binding:"required"`
 		OldUserID     string `json:"old_user_id" binding:"required"`
+		NewReviewerID string `json:"new_reviewer_id"`
+		DryRun        bool   `json:"dry_run"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -299,8 +590,26 @@ func (h *Handlers) ReassignReviewer(c *gin.Context) {
 		return
 	}
 
-	pr, newReviewerID, err := h.service.ReassignReviewer(req.PullRequestID, req.OldUserID)
+	ifMatch, err := parseIfMatch(c)
 	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	pr, newReviewerID, err := h.service.ReassignReviewer(req.PullRequestID, req.OldUserID, req.NewReviewerID, req.DryRun, ifMatch)
+	if err != nil {
+		if err.Error() == "VERSION_MISMATCH" {
+			c.JSON(http.StatusPreconditionFailed, models.ErrorResponse{
+				Error: struct {
+					Code    models.ErrorCode `json:"code"`
+					Message string           `json:"message"`
+				}{
+					Code:    models.ErrorVersionMismatch,
+					Message: "pull request version does not match If-Match",
+				},
+			})
+			return
+		}
 		if err.Error() == "PR_MERGED" {
 			c.JSON(http.StatusConflict, models.ErrorResponse{
 				Error: struct {
@@ -313,6 +622,18 @@ func (h *Handlers) ReassignReviewer(c *gin.Context) {
 			})
 			return
 		}
+		if err.Error() == "PR_CLOSED" {
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error: struct {
+					Code    models.ErrorCode `json:"code"`
+					Message string           `json:"message"`
+				}{
+					Code:    models.ErrorPRClosed,
+					Message: "cannot reassign on closed PR",
+				},
+			})
+			return
+		}
 		if err.Error() == "NOT_ASSIGNED" {
 			c.JSON(http.StatusConflict, models.ErrorResponse{
 				Error: struct {
@@ -325,45 +646,96 @@ func (h *Handlers) ReassignReviewer(c *gin.Context) {
 			})
 			return
 		}
-		if err.Error() == "NO_CANDIDATE" {
-			c.JSON(http.StatusConflict, models.ErrorResponse{
+		if code, status := models.ErrorStatus(err); code == models.ErrorNoCandidate {
+			message := "no active replacement candidate in team"
+			if err.Error() != string(code) {
+				// Кандидаты были, но всех отсеяло правило команды - говорю какое
+				message = err.Error()
+			}
+			c.JSON(status, models.ErrorResponse{
 				Error: struct {
 					Code    models.ErrorCode `json:"code"`
 					Message string           `json:"message"`
 				}{
-					Code:    models.ErrorNoCandidate,
-					Message: "no active replacement candidate in team",
+					Code:    code,
+					Message: message,
 				},
 			})
 			return
 		}
-		c.JSON(http.StatusNotFound, models.ErrorResponse{
+		respondServiceError(c, err)
+		return
+	}
+
+	if !req.DryRun {
+		setETag(c, pr.Version)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"pr":          pr,
+		"replaced_by": newReviewerID,
+		"dry_run":     req.DryRun,
+	})
+}
+
+// AddReviewer - вручную добавляю выбранного ревьюера на PR
+func (h *Handlers) AddReviewer(c *gin.Context) {
+	h.changeReviewer(c, h.service.AddReviewer)
+}
+
+// RemoveReviewer - снимаю ревьюера с PR без замены
+func (h *Handlers) RemoveReviewer(c *gin.Context) {
+	h.changeReviewer(c, h.service.RemoveReviewer)
+}
+
+// DeclineReview - назначенный ревьюер сам отказывается от PR с причиной, замену подбирает сервис
+func (h *Handlers) DeclineReview(c *gin.Context) {
+	var req struct {
+		PullRequestID string `json:"pull_request_id" binding:"required"`
+		ReviewerID    string `json:"reviewer_id" binding:"required"`
+		Reason        string `json:"reason" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Error: struct {
 				Code    models.ErrorCode `json:"code"`
 				Message string           `json:"message"`
 			}{
 				Code:    models.ErrorNotFound,
-				Message: "pull request or user not found",
+				Message: err.Error(),
 			},
 		})
 		return
 	}
 
+	ifMatch, err := parseIfMatch(c)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	pr, replacedBy, err := h.service.DeclineReview(req.PullRequestID, req.ReviewerID, req.Reason, ifMatch)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	setETag(c, pr.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"pr":          pr,
-		"replaced_by": newReviewerID,
+		"replaced_by": replacedBy,
 	})
 }
 
-func (h *Handlers) HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"status": "ok"})
-}
+// changeReviewer - у добавления и снятия одинаковые запрос и ответ, отличается только действие
+func (h *Handlers) changeReviewer(c *gin.Context, change func(prID, reviewerID string, expectedVersions []int) (*models.PullRequest, error)) {
+	var req struct {
+		PullRequestID string `json:"pull_request_id" binding:"required"`
+		ReviewerID    string `json:"reviewer_id" binding:"required"`
+	}
 
-// Statistics - просто отдаю статистику
-func (h *Handlers) GetStatistics(c *gin.Context) {
-	stats, err := h.service.GetStatistics()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Error: struct {
 				Code    models.ErrorCode `json:"code"`
 				Message string           `json:"message"`
@@ -375,13 +747,31 @@ func (h *Handlers) GetStatistics(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	ifMatch, err := parseIfMatch(c)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	pr, err := change(req.PullRequestID, req.ReviewerID, ifMatch)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	setETag(c, pr.Version)
+	c.JSON(http.StatusOK, gin.H{"pr": pr})
+}
+
+func (h *Handlers) HealthCheck(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
 // BulkDeactivateTeam - массовая деактивация команды с переназначением PR
 func (h *Handlers) BulkDeactivateTeam(c *gin.Context) {
 	var req struct {
 		TeamName string `json:"team_name" binding:"required"`
+		DryRun   bool   `json:"dry_run"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -397,21 +787,56 @@ func (h *Handlers) BulkDeactivateTeam(c *gin.Context) {
 		return
 	}
 
-	deactivatedUserIDs, reassignedPRs, err := h.service.BulkDeactivateTeam(req.TeamName)
+	ifMatch, err := parseIfMatch(c)
 	if err != nil {
-		if err.Error() == "team not found" {
-			c.JSON(http.StatusNotFound, models.ErrorResponse{
+		respondServiceError(c, err)
+		return
+	}
+
+	result, err := h.service.BulkDeactivateTeam(req.TeamName, req.DryRun, ifMatch)
+	if err != nil {
+		if err.Error() == "VERSION_MISMATCH" {
+			c.JSON(http.StatusPreconditionFailed, models.ErrorResponse{
 				Error: struct {
 					Code    models.ErrorCode `json:"code"`
 					Message string           `json:"message"`
 				}{
-					Code:    models.ErrorNotFound,
-					Message: "team not found",
+					Code:    models.ErrorVersionMismatch,
+					Message: "team version does not match If-Match",
 				},
 			})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		respondServiceError(c, err)
+		return
+	}
+
+	message := fmt.Sprintf("Deactivated %d users, reassigned %d PRs", len(result.DeactivatedUserIDs), len(result.ReassignedPRs))
+	if result.DryRun {
+		message = fmt.Sprintf("Dry run: would deactivate %d users, reassign %d PRs, %d reviewers without candidate",
+			len(result.DeactivatedUserIDs), len(result.ReassignedPRs), len(result.WithoutCandidate))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deactivated_user_ids": result.DeactivatedUserIDs,
+		"reassigned_prs":       result.ReassignedPRs,
+		"reassignments":        result.Reassignments,
+		"without_candidate":    result.WithoutCandidate,
+		"dry_run":              result.DryRun,
+		"message":              message,
+	})
+}
+
+// RebalanceTeam - переложить открытые ревью с перегруженных участников на недогруженных.
+// С dry_run только показываю, что бы переназначил.
+func (h *Handlers) RebalanceTeam(c *gin.Context) {
+	var req struct {
+		TeamName string `json:"team_name" binding:"required"`
+		DryRun   bool   `json:"dry_run"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Error: struct {
 				Code    models.ErrorCode `json:"code"`
 				Message string           `json:"message"`
@@ -423,9 +848,58 @@ func (h *Handlers) BulkDeactivateTeam(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"deactivated_user_ids": deactivatedUserIDs,
-		"reassigned_prs":       reassignedPRs,
-		"message":              fmt.Sprintf("Deactivated %d users, reassigned %d PRs", len(deactivatedUserIDs), len(reassignedPRs)),
+	result, err := h.service.RebalanceTeam(req.TeamName, req.DryRun)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// respondServiceError - ошибка сервиса в ответ: код и HTTP-статус по общей с gRPC таблице models.ErrorStatus
+func respondServiceError(c *gin.Context, err error) {
+	code, status := models.ErrorStatus(err)
+	c.JSON(status, models.ErrorResponse{
+		Error: struct {
+			Code    models.ErrorCode `json:"code"`
+			Message string           `json:"message"`
+		}{
+			Code:    code,
+			Message: err.Error(),
+		},
 	})
 }
+
+// setETag - отдаю версию ресурса в заголовке ETag
+func setETag(c *gin.Context, version int) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// parseIfMatch - достаю ожидаемые версии из If-Match. Заголовок может перечислять несколько ETag
+// через запятую, тогда подходит любой. Пустой заголовок или "*" означают, что версию проверять не нужно.
+// Всё, что не похоже на выданный сервисом ETag, - ошибка запроса, а не несовпадение версии.
+func parseIfMatch(c *gin.Context) ([]int, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return nil, nil
+	}
+
+	versions := make([]int, 0)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, nil
+		}
+		value := strings.TrimPrefix(tag, "W/")
+		if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+			value = value[1 : len(value)-1]
+		}
+		version, err := strconv.Atoi(value)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("invalid If-Match: %q is not a version ETag", tag)
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
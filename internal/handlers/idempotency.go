@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"pr-reviewer-service/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// replayedHeaders - какие заголовки ответа сохраняю вместе с телом, чтобы повтор был неотличим от оригинала
+var replayedHeaders = []string{"Content-Type", "ETag"}
+
+// bodyRecorder - пишет ответ клиенту и параллельно копит его, чтобы потом сохранить
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+
+// Idempotency - middleware для заголовка Idempotency-Key на POST-ручках.
+// Первый запрос с ключом выполняется как обычно, а его ответ сохраняется в базе на ttl.
+// Повтор с тем же телом получает сохранённый ответ, повтор с другим телом - 422.
+// Если исходный запрос упал с 5xx, ключ освобождается, и повтор выполнится заново.
+func (h *Handlers) Idempotency(ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: struct {
+					Code    models.ErrorCode `json:"code"`
+					Message string           `json:"message"`
+				}{
+					Code:    models.ErrorNotFound,
+					Message: err.Error(),
+				},
+			})
+			return
+		}
+		// Возвращаю тело на место, чтобы ручка смогла его прочитать
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		route := c.Request.Method + " " + c.FullPath()
+		hash := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(hash[:])
+
+		claimed, err := h.service.ClaimIdempotencyKey(key, route, requestHash, ttl)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: struct {
+					Code    models.ErrorCode `json:"code"`
+					Message string           `json:"message"`
+				}{
+					Code:    models.ErrorNotFound,
+					Message: err.Error(),
+				},
+			})
+			return
+		}
+
+		if !claimed {
+			h.replayIdempotentResponse(c, key, route, requestHash)
+			return
+		}
+
+		// Если ручка запаникует, ключ тоже нужно отпустить, иначе он будет "в процессе" до конца ttl
+		defer func() {
+			if p := recover(); p != nil {
+				if err := h.service.ReleaseIdempotencyKey(key, route); err != nil {
+					log.Printf("Не получилось освободить Idempotency-Key %s: %v", key, err)
+				}
+				panic(p)
+			}
+		}()
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			if err := h.service.ReleaseIdempotencyKey(key, route); err != nil {
+				log.Printf("Не получилось освободить Idempotency-Key %s: %v", key, err)
+			}
+			return
+		}
+
+		headers := make(map[string]string)
+		for _, name := range replayedHeaders {
+			if value := recorder.Header().Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+		if err := h.service.SaveIdempotencyResponse(key, route, status, recorder.body.Bytes(), headers); err != nil {
+			log.Printf("Не получилось сохранить ответ для Idempotency-Key %s: %v", key, err)
+		}
+	}
+}
+
+// replayIdempotentResponse - ключ уже занят: отдаю сохранённый ответ или объясняю, почему не могу
+func (h *Handlers) replayIdempotentResponse(c *gin.Context, key, route, requestHash string) {
+	record, err := h.service.GetIdempotencyRecord(key, route)
+	if err != nil {
+		// Запись могли удалить между попыткой занять ключ и чтением (например, запрос упал с 5xx)
+		c.AbortWithStatusJSON(http.StatusConflict, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorIdempotencyInProgress,
+				Message: "request with this Idempotency-Key is being processed, retry later",
+			},
+		})
+		return
+	}
+
+	if record.RequestHash != requestHash {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorIdempotencyKeyReused,
+				Message: "Idempotency-Key was already used with a different request body",
+			},
+		})
+		return
+	}
+
+	if !record.Completed {
+		c.AbortWithStatusJSON(http.StatusConflict, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorIdempotencyInProgress,
+				Message: "request with this Idempotency-Key is being processed, retry later",
+			},
+		})
+		return
+	}
+
+	for name, value := range record.ResponseHeaders {
+		c.Header(name, value)
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Status(record.StatusCode)
+	c.Writer.Write(record.ResponseBody)
+	c.Abort()
+}
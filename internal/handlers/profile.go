@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"pr-reviewer-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetUserProfile - уровень и навыки пользователя. По уровню считаются старшие для require_senior,
+// по навыкам при match_skills подбираются ревьюеры к меткам PR.
+func (h *Handlers) SetUserProfile(c *gin.Context) {
+	var req struct {
+		UserID    string           `json:"user_id" binding:"required"`
+		Seniority models.Seniority `json:"seniority"`
+		Skills    []string         `json:"skills"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	user, err := h.service.SetUserProfile(req.UserID, req.Seniority, req.Skills)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+// GetUser - пользователь вместе с профилем
+func (h *Handlers) GetUser(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: "user_id is required",
+			},
+		})
+		return
+	}
+
+	user, err := h.service.GetUser(userID)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
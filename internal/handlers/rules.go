@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"pr-reviewer-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetReviewRules - правила подбора ревьюеров на PR авторов команды.
+// Все списки пустые, require_senior и match_skills выключены - правила удаляются.
+func (h *Handlers) SetReviewRules(c *gin.Context) {
+	var req struct {
+		TeamName       string                 `json:"team_name" binding:"required"`
+		NeverReview    []models.UserPair      `json:"never_review"`
+		PreferredPairs []models.PreferredPair `json:"preferred_pairs"`
+		Seniors        []string               `json:"seniors"`
+		RequireSenior  bool                   `json:"require_senior"`
+		MatchSkills    bool                   `json:"match_skills"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	rules, err := h.service.SetReviewRules(&models.ReviewRules{
+		TeamName:       req.TeamName,
+		NeverReview:    req.NeverReview,
+		PreferredPairs: req.PreferredPairs,
+		Seniors:        req.Seniors,
+		RequireSenior:  req.RequireSenior,
+		MatchSkills:    req.MatchSkills,
+	})
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+func (h *Handlers) GetReviewRules(c *gin.Context) {
+	teamName := c.Query("team_name")
+	if teamName == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: "team_name is required",
+			},
+		})
+		return
+	}
+
+	rules, err := h.service.GetReviewRules(teamName)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
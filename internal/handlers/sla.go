@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"pr-reviewer-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetTeamSLA - SLA команды на первое ревью и действие при нарушении.
+// first_review_hours = 0 выключает SLA.
+func (h *Handlers) SetTeamSLA(c *gin.Context) {
+	var req struct {
+		TeamName         string           `json:"team_name" binding:"required"`
+		FirstReviewHours *float64         `json:"first_review_hours" binding:"required"`
+		Action           models.SLAAction `json:"action"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	sla, err := h.service.SetTeamSLA(&models.TeamSLA{
+		TeamName:         req.TeamName,
+		FirstReviewHours: *req.FirstReviewHours,
+		Action:           req.Action,
+	})
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sla)
+}
+
+func (h *Handlers) GetTeamSLA(c *gin.Context) {
+	teamName := c.Query("team_name")
+	if teamName == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: "team_name is required",
+			},
+		})
+		return
+	}
+
+	sla, err := h.service.GetTeamSLA(teamName)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sla)
+}
+
+// RunEscalations - внеочередной проход по SLA, не дожидаясь планировщика
+func (h *Handlers) RunEscalations(c *gin.Context) {
+	escalations, err := h.service.EscalateOverdueReviews()
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"escalations": escalations})
+}
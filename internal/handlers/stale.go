@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"pr-reviewer-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetStalePolicy - через сколько дней без активности предупреждать о PR и закрывать его.
+// warn_after_days = 0 выключает политику, close_after_days = 0 - только предупреждать.
+func (h *Handlers) SetStalePolicy(c *gin.Context) {
+	var req struct {
+		TeamName       string   `json:"team_name" binding:"required"`
+		WarnAfterDays  *int     `json:"warn_after_days" binding:"required"`
+		CloseAfterDays int      `json:"close_after_days"`
+		ExcludedLabels []string `json:"excluded_labels"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	policy, err := h.service.SetStalePolicy(&models.StalePolicy{
+		TeamName:       req.TeamName,
+		WarnAfterDays:  *req.WarnAfterDays,
+		CloseAfterDays: req.CloseAfterDays,
+		ExcludedLabels: req.ExcludedLabels,
+	})
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *Handlers) GetStalePolicy(c *gin.Context) {
+	teamName := c.Query("team_name")
+	if teamName == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: "team_name is required",
+			},
+		})
+		return
+	}
+
+	policy, err := h.service.GetStalePolicy(teamName)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// ListStalePullRequests - брошенные PR; team_name необязателен
+func (h *Handlers) ListStalePullRequests(c *gin.Context) {
+	stale, err := h.service.ListStalePullRequests(c.Query("team_name"))
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pull_requests": stale})
+}
+
+// SweepStalePullRequests - внеочередной проход политики, не дожидаясь фонового воркера
+func (h *Handlers) SweepStalePullRequests(c *gin.Context) {
+	result, err := h.service.SweepStalePullRequests()
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"pr-reviewer-service/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Statistics - просто отдаю статистику.
+// Можно сузить выборку параметрами from, to и team_name, а с format=csv|ndjson
+// (или Accept: text/csv, application/x-ndjson) выгрузить статистику по пользователям построчно.
+func (h *Handlers) GetStatistics(c *gin.Context) {
+	filter, err := parseStatisticsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	format, err := negotiateFormat(c)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	// В CSV/NDJSON отдаю только строки по пользователям, общая статистика по PR в таблицу не ложится
+	if format != formatJSON {
+		stream := newRowStream(c, format, "statistics", userStatsCSVHeader)
+		err := h.service.StreamUserStats(filter, func(stat *models.UserReviewStats) error {
+			return stream.Write(userStatsRecord(stat), stat)
+		})
+		if stream.Finish(err) {
+			respondServiceError(c, err)
+		}
+		return
+	}
+
+	stats, err := h.service.GetStatistics(filter)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetTimeSeries - PR создано/смержено, назначения и переназначения по дням или неделям
+func (h *Handlers) GetTimeSeries(c *gin.Context) {
+	filter, err := parseStatisticsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	series, err := h.service.GetTimeSeries(c.DefaultQuery("interval", "day"), filter)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// GetReviewerAssignmentSeries - назначения по каждому ревьюеру по дням или неделям
+func (h *Handlers) GetReviewerAssignmentSeries(c *gin.Context) {
+	filter, err := parseStatisticsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	series, err := h.service.GetReviewerAssignmentSeries(c.DefaultQuery("interval", "day"), filter)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// GetTeamStatistics - разбивка по командам
+func (h *Handlers) GetTeamStatistics(c *gin.Context) {
+	filter, err := parseStatisticsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	teams, err := h.service.GetTeamStatistics(filter)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"teams": teams})
+}
+
+// GetTurnaround - медиана и p90 времени до мержа и времени ответа ревьюеров
+func (h *Handlers) GetTurnaround(c *gin.Context) {
+	filter, err := parseStatisticsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	turnaround, err := h.service.GetTurnaround(filter)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, turnaround)
+}
+
+// GetLeaderboard - рейтинг ревьюеров, по умолчанию топ-10
+func (h *Handlers) GetLeaderboard(c *gin.Context) {
+	filter, err := parseStatisticsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil {
+		respondServiceError(c, fmt.Errorf("invalid limit: %v", err))
+		return
+	}
+
+	entries, err := h.service.GetLeaderboard(filter, limit)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"leaderboard": entries})
+}
+
+// GetFairness - доля назначений против доли активного времени, Джини и выбросы по командам
+func (h *Handlers) GetFairness(c *gin.Context) {
+	filter, err := parseStatisticsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	fairness, err := h.service.GetFairness(filter)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, fairness)
+}
+
+// parseStatisticsFilter - достаю from, to и team_name из query.
+// Даты принимаю как 2006-01-02 или в RFC3339.
+func parseStatisticsFilter(c *gin.Context) (models.StatisticsFilter, error) {
+	filter := models.StatisticsFilter{TeamName: c.Query("team_name")}
+
+	from, err := parseStatisticsTime(c.Query("from"))
+	if err != nil {
+		return filter, fmt.Errorf("invalid from: %v", err)
+	}
+	to, err := parseStatisticsTime(c.Query("to"))
+	if err != nil {
+		return filter, fmt.Errorf("invalid to: %v", err)
+	}
+
+	filter.From = from
+	filter.To = to
+	return filter, nil
+}
+
+func parseStatisticsTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("expected YYYY-MM-DD or RFC3339, got %q", value)
+	}
+	t = t.UTC()
+	return &t, nil
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"pr-reviewer-service/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamKeepAlive - раз в столько шлю комментарий, чтобы прокси не рвали тихое соединение
+const streamKeepAlive = 15 * time.Second
+
+// StreamUserEvents - SSE-поток событий ревьюера: назначили, сняли, PR смержили.
+// После переподключения браузер сам присылает Last-Event-ID, и я досылаю пропущенное из базы.
+// Для клиентов, которые не умеют в заголовок, то же самое можно передать параметром last_event_id.
+func (h *Handlers) StreamUserEvents(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: struct {
+				Code    models.ErrorCode `json:"code"`
+				Message string           `json:"message"`
+			}{
+				Code:    models.ErrorNotFound,
+				Message: "user_id is required",
+			},
+		})
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var lastID int64
+	if lastEventID != "" {
+		var err error
+		lastID, err = strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || lastID < 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: struct {
+					Code    models.ErrorCode `json:"code"`
+					Message string           `json:"message"`
+				}{
+					Code:    models.ErrorNotFound,
+					Message: "invalid Last-Event-ID",
+				},
+			})
+			return
+		}
+	}
+
+	if _, err := h.service.GetUser(userID); err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	// Подписываюсь до чтения истории, чтобы не потерять события между ними.
+	// Событие, попавшее и в историю, и в подписку, второй раз не отправляю.
+	events, unsubscribe := h.service.SubscribeUserEvents(userID)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// nginx по умолчанию буферизует ответ, для SSE это надо выключить
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	send := func(event models.UserEvent) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+		return err
+	}
+
+	// Досылаю пропущенное с прошлого подключения.
+	// id события - его позиция в порядке коммитов, так что после переподключения ничего не теряется.
+	// Подписка открыта до досылки, поэтому одно и то же событие может прийти дважды - повторы отсекаю по replayed.
+	replayed := make(map[int64]bool)
+	if lastEventID != "" {
+		err := h.service.ReplayUserEvents(userID, lastID, func(event models.UserEvent) error {
+			replayed[event.ID] = true
+			return send(event)
+		})
+		if err != nil {
+			return
+		}
+	}
+	// Первым комментарием отдаю заголовки сразу, не дожидаясь первого события
+	fmt.Fprint(c.Writer, ": connected\n\n")
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				// Не успевал читать - отключаю, клиент переподключится с Last-Event-ID
+				return
+			}
+			if replayed[event.ID] {
+				continue
+			}
+			if err := send(event); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
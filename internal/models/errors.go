@@ -0,0 +1,41 @@
+package models
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ErrorStatus - код ErrorCode и HTTP-статус для ошибки сервиса.
+// Сервис возвращает ошибки строками: коды вроде "PR_MERGED" (иногда с ID после двоеточия),
+// "... not found" и "invalid ...". По этой же таблице gRPC выбирает свой статус,
+// чтобы клиенты REST и gRPC видели одни и те же коды.
+func ErrorStatus(err error) (ErrorCode, int) {
+	msg := err.Error()
+	code := ErrorCode(msg)
+	if i := strings.Index(msg, ":"); i > 0 {
+		code = ErrorCode(msg[:i])
+	}
+
+	switch code {
+	case ErrorTeamExists:
+		// Так было с самого начала в /team/add
+		return code, http.StatusBadRequest
+	case ErrorPRExists, ErrorPRMerged, ErrorPRClosed, ErrorNotAssigned, ErrorNoCandidate,
+		ErrorNotEligible, ErrorReviewersFull:
+		return code, http.StatusConflict
+	case ErrorVersionMismatch:
+		return code, http.StatusPreconditionFailed
+	case ErrorIdempotencyKeyReused:
+		return code, http.StatusUnprocessableEntity
+	case ErrorIdempotencyInProgress:
+		return code, http.StatusConflict
+	}
+
+	switch {
+	case strings.HasSuffix(msg, "not found"):
+		return ErrorNotFound, http.StatusNotFound
+	case strings.HasPrefix(msg, "invalid"):
+		return ErrorNotFound, http.StatusBadRequest
+	}
+	return ErrorNotFound, http.StatusInternalServerError
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type TeamMember struct {
 	UserID   string `json:"user_id" db:"user_id"`
@@ -11,6 +14,7 @@ type TeamMember struct {
 type Team struct {
 	TeamName string       `json:"team_name" db:"team_name"`
 	Members  []TeamMember `json:"members"`
+	Version  int          `json:"version" db:"version"`
 }
 
 type User struct {
@@ -18,13 +22,38 @@ type User struct {
 	Username string `json:"username" db:"username"`
 	TeamName string `json:"team_name" db:"team_name"`
 	IsActive bool   `json:"is_active" db:"is_active"`
+	// Seniority и Skills - профиль для подбора ревьюеров, меняется через /users/setProfile
+	Seniority Seniority `json:"seniority,omitempty" db:"seniority"`
+	Skills    []string  `json:"skills,omitempty" db:"skills"`
 }
 
+// Seniority - уровень пользователя. senior и lead считаются старшими для правила require_senior.
+type Seniority string
+
+const (
+	SeniorityJunior Seniority = "junior"
+	SeniorityMiddle Seniority = "middle"
+	SenioritySenior Seniority = "senior"
+	SeniorityLead   Seniority = "lead"
+)
+
 type PullRequestStatus string
 
 const (
 	StatusOpen   PullRequestStatus = "OPEN"
 	StatusMerged PullRequestStatus = "MERGED"
+	// StatusClosed - PR закрыт без мержа политикой брошенных PR
+	StatusClosed PullRequestStatus = "CLOSED"
+)
+
+// PullRequestPriority - насколько PR срочный. Для urgent выбираю самых свободных ревьюеров.
+type PullRequestPriority string
+
+const (
+	PriorityLow    PullRequestPriority = "low"
+	PriorityNormal PullRequestPriority = "normal"
+	PriorityHigh   PullRequestPriority = "high"
+	PriorityUrgent PullRequestPriority = "urgent"
 )
 
 type PullRequest struct {
@@ -36,6 +65,106 @@ type PullRequest struct {
 	NeedMoreReviewers bool              `json:"needMoreReviewers" db:"need_more_reviewers"`
 	CreatedAt         *time.Time        `json:"createdAt,omitempty" db:"created_at"`
 	MergedAt          *time.Time        `json:"mergedAt,omitempty" db:"merged_at"`
+	ClosedAt          *time.Time        `json:"closedAt,omitempty" db:"closed_at"`
+	Version           int               `json:"version" db:"version"`
+	Labels            []string          `json:"labels" db:"labels"`
+	// Priority - low, normal, high или urgent
+	Priority PullRequestPriority `json:"priority" db:"priority"`
+	// LastActivityAt - последнее изменение PR: создание, ревью, смена ревьюеров, мерж
+	LastActivityAt *time.Time `json:"lastActivityAt,omitempty" db:"last_activity_at"`
+	// Description, URL (ссылка на PR в GitHub/GitLab) и TargetBranch меняются через /pullRequest/update
+	Description  string `json:"description" db:"description"`
+	URL          string `json:"url" db:"url"`
+	TargetBranch string `json:"target_branch" db:"target_branch"`
+	// Escalations - эскалации по SLA, заполняются только в /pullRequest/get
+	Escalations []*Escalation `json:"escalations,omitempty"`
+	// Audit - история правок через /pullRequest/update, тоже только в /pullRequest/get
+	Audit []*PullRequestAuditEntry `json:"audit,omitempty"`
+	// Declines - отказы ревьюеров от этого PR, тоже только в /pullRequest/get
+	Declines []*ReviewDecline `json:"declines,omitempty"`
+}
+
+// PullRequestFilter - фильтр для списка PR. Пустое поле - без фильтра, TeamName - команда автора.
+type PullRequestFilter struct {
+	Status   string
+	AuthorID string
+	TeamName string
+	Priority string
+	// Label - PR должен иметь эту метку
+	Label string
+}
+
+// PullRequestUpdate - что поменять в PR через /pullRequest/update. nil - не трогать.
+type PullRequestUpdate struct {
+	Name         *string
+	Description  *string
+	URL          *string
+	TargetBranch *string
+	Labels       *[]string
+	Priority     *PullRequestPriority
+	// ChangedBy - кто правит, попадает в журнал. Необязателен.
+	ChangedBy string
+}
+
+// FieldChange - старое и новое значение одного поля PR
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// PullRequestAuditEntry - одна правка PR: какие поля поменялись и кто это сделал
+type PullRequestAuditEntry struct {
+	ID            int64                  `json:"id"`
+	PullRequestID string                 `json:"pull_request_id"`
+	ChangedBy     string                 `json:"changed_by,omitempty"`
+	Changes       map[string]FieldChange `json:"changes"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
+
+// ReviewDecline - ревьюер отказался от PR. ReplacedBy пустой, если замены не нашлось.
+type ReviewDecline struct {
+	ID            int64     `json:"id"`
+	PullRequestID string    `json:"pull_request_id"`
+	ReviewerID    string    `json:"reviewer_id"`
+	Reason        string    `json:"reason"`
+	ReplacedBy    string    `json:"replaced_by,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ReviewVerdict - чем закончилось действие ревьюера на PR
+type ReviewVerdict string
+
+const (
+	VerdictApproved         ReviewVerdict = "APPROVED"
+	VerdictChangesRequested ReviewVerdict = "CHANGES_REQUESTED"
+	VerdictCommented        ReviewVerdict = "COMMENTED"
+)
+
+// ReviewAction - действие ревьюера на PR. Первое такое действие считается ответом на назначение.
+type ReviewAction struct {
+	PullRequestID string        `json:"pull_request_id"`
+	ReviewerID    string        `json:"reviewer_id"`
+	Verdict       ReviewVerdict `json:"verdict"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+// UserEventType - что случилось с ревью пользователя
+type UserEventType string
+
+const (
+	UserEventAssigned   UserEventType = "assigned"
+	UserEventUnassigned UserEventType = "unassigned"
+	UserEventMerged     UserEventType = "merged"
+	UserEventClosed     UserEventType = "closed"
+)
+
+// UserEvent - событие для потока /users/stream. ID растёт, по нему клиент продолжает поток.
+type UserEvent struct {
+	ID            int64         `json:"id"`
+	UserID        string        `json:"user_id"`
+	Type          UserEventType `json:"type"`
+	PullRequestID string        `json:"pull_request_id"`
+	CreatedAt     time.Time     `json:"created_at"`
 }
 
 type PullRequestShort struct {
@@ -43,6 +172,349 @@ type PullRequestShort struct {
 	PullRequestName string            `json:"pull_request_name"`
 	AuthorID        string            `json:"author_id"`
 	Status          PullRequestStatus `json:"status"`
+	// Priority и Labels - чтобы фильтровать и сортировать ревью у себя
+	Priority PullRequestPriority `json:"priority"`
+	Labels   []string            `json:"labels"`
+}
+
+// ReviewerReassignment - одна замена ревьюера на PR.
+// Если NewReviewerID пустой, значит замену найти не удалось.
+type ReviewerReassignment struct {
+	PullRequestID string `json:"pull_request_id"`
+	OldReviewerID string `json:"old_reviewer_id"`
+	NewReviewerID string `json:"new_reviewer_id,omitempty"`
+	// BlockedBy - правило команды, из-за которого замены не нашлось (только в without_candidate)
+	BlockedBy string `json:"blocked_by,omitempty"`
+}
+
+// ReviewerChange - ревьюера добавили или сняли вручную, без замены
+type ReviewerChange struct {
+	PullRequestID     string `json:"pull_request_id"`
+	ReviewerID        string `json:"reviewer_id"`
+	NeedMoreReviewers bool   `json:"needMoreReviewers"`
+}
+
+// BulkDeactivationResult - итог массовой деактивации (или её прогона в режиме dry_run)
+type BulkDeactivationResult struct {
+	DeactivatedUserIDs []string               `json:"deactivated_user_ids"`
+	ReassignedPRs      []string               `json:"reassigned_prs"`
+	Reassignments      []ReviewerReassignment `json:"reassignments"`
+	WithoutCandidate   []ReviewerReassignment `json:"without_candidate"`
+	DryRun             bool                   `json:"dry_run"`
+}
+
+// MemberFairness - доля назначений участника против доли времени, когда он был активен.
+// LoadRatio = назначения / ожидаемые назначения, 1 - ровно по справедливости.
+type MemberFairness struct {
+	UserID              string   `json:"user_id"`
+	Username            string   `json:"username"`
+	IsActive            bool     `json:"is_active"`
+	Assignments         int      `json:"assignments"`
+	ActiveHours         float64  `json:"active_hours"`
+	AssignmentShare     float64  `json:"assignment_share"`
+	ActiveTimeShare     float64  `json:"active_time_share"`
+	ExpectedAssignments float64  `json:"expected_assignments"`
+	LoadRatio           *float64 `json:"load_ratio"`
+	Outlier             string   `json:"outlier,omitempty"`
+}
+
+// TeamFairness - справедливость распределения внутри команды
+type TeamFairness struct {
+	TeamName         string            `json:"team_name"`
+	TotalAssignments int               `json:"total_assignments"`
+	Gini             float64           `json:"gini"`
+	Members          []*MemberFairness `json:"members"`
+}
+
+type FairnessResponse struct {
+	From  time.Time       `json:"from"`
+	To    time.Time       `json:"to"`
+	Teams []*TeamFairness `json:"teams"`
+}
+
+// MemberLoad - сырые данные для отчёта о справедливости, одна строка на участника
+type MemberLoad struct {
+	TeamName    string
+	UserID      string
+	Username    string
+	IsActive    bool
+	Assignments int
+	ActiveHours float64
+}
+
+// RebalanceResult - итог перебалансировки открытых ревью в команде (или её прогона в режиме dry_run)
+type RebalanceResult struct {
+	TeamName      string                 `json:"team_name"`
+	LoadBefore    map[string]int         `json:"load_before"`
+	LoadAfter     map[string]int         `json:"load_after"`
+	Reassignments []ReviewerReassignment `json:"reassignments"`
+	DryRun        bool                   `json:"dry_run"`
+}
+
+// SetUserActiveResult - пользователь после изменения и его переназначенные ревью
+type SetUserActiveResult struct {
+	User             *User                  `json:"user"`
+	ReassignedPRs    []string               `json:"reassigned_prs"`
+	Reassignments    []ReviewerReassignment `json:"reassignments"`
+	WithoutCandidate []ReviewerReassignment `json:"without_candidate"`
+}
+
+// SLAAction - что делать с PR, который не получил первое ревью вовремя
+type SLAAction string
+
+const (
+	// SLANotify - только событие в outbox (и сообщение в Slack, если он подключён)
+	SLANotify SLAAction = "notify"
+	// SLAAddReviewer - добавить ещё одного ревьюера из команды автора
+	SLAAddReviewer SLAAction = "add_reviewer"
+	// SLAReassign - заменить ревьюеров, которые так и не взялись за PR
+	SLAReassign SLAAction = "reassign"
+)
+
+// TeamSLA - SLA команды на первое ревью. Считается по команде автора PR.
+type TeamSLA struct {
+	TeamName         string    `json:"team_name"`
+	FirstReviewHours float64   `json:"first_review_hours"`
+	Action           SLAAction `json:"action"`
+}
+
+// SLABreach - открытый PR, нарушивший SLA своей команды
+type SLABreach struct {
+	PullRequestID string
+	TeamSLA
+}
+
+// Escalation - одна эскалация по PR. Error заполнен, если действие выполнить не удалось
+// (например, NO_CANDIDATE - некого добавить или поставить на замену).
+type Escalation struct {
+	ID            int64     `json:"id"`
+	PullRequestID string    `json:"pull_request_id"`
+	Action        SLAAction `json:"action"`
+	ReviewerID    string    `json:"reviewer_id,omitempty"`
+	NewReviewerID string    `json:"new_reviewer_id,omitempty"`
+	Reason        string    `json:"reason"`
+	Error         string    `json:"error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// StalePolicy - политика команды для брошенных PR: через сколько дней без активности
+// предупреждать и через сколько закрывать (0 - не закрывать). PR с любой из ExcludedLabels не трогаю.
+type StalePolicy struct {
+	TeamName       string   `json:"team_name"`
+	WarnAfterDays  int      `json:"warn_after_days"`
+	CloseAfterDays int      `json:"close_after_days"`
+	ExcludedLabels []string `json:"excluded_labels"`
+}
+
+// UserPair - два пользователя, которые не ревьюят PR друг друга
+type UserPair struct {
+	UserA string `json:"user_a"`
+	UserB string `json:"user_b"`
+}
+
+// PreferredPair - кого по возможности ставить ревьюером на PR автора (например, ментора на PR подопечного)
+type PreferredPair struct {
+	ReviewerID string `json:"reviewer_id"`
+	AuthorID   string `json:"author_id"`
+}
+
+// ReviewRules - правила подбора ревьюеров на PR авторов команды
+type ReviewRules struct {
+	TeamName       string          `json:"team_name"`
+	NeverReview    []UserPair      `json:"never_review"`
+	PreferredPairs []PreferredPair `json:"preferred_pairs"`
+	Seniors        []string        `json:"seniors"`
+	RequireSenior  bool            `json:"require_senior"`
+	// MatchSkills - первыми ставить тех, у кого есть навык из меток PR
+	MatchSkills bool `json:"match_skills"`
+}
+
+// Правила, из-за которых может не найтись ревьюер (NO_CANDIDATE)
+const (
+	RuleNeverReview   = "never_review"
+	RuleRequireSenior = "require_senior"
+)
+
+// StalePullRequest - открытый PR без активности дольше warn_after_days своей команды
+type StalePullRequest struct {
+	PullRequestID   string     `json:"pull_request_id"`
+	PullRequestName string     `json:"pull_request_name"`
+	AuthorID        string     `json:"author_id"`
+	TeamName        string     `json:"team_name"`
+	Labels          []string   `json:"labels"`
+	LastActivityAt  time.Time  `json:"last_activity_at"`
+	IdleDays        float64    `json:"idle_days"`
+	WarnedAt        *time.Time `json:"warned_at,omitempty"`
+	// ClosesAt - когда PR закроется, если ничего не произойдёт. Пусто - команда не закрывает PR.
+	ClosesAt *time.Time `json:"closes_at,omitempty"`
+}
+
+// StaleSweepResult - итог одного прохода политики
+type StaleSweepResult struct {
+	Warned []string `json:"warned"`
+	Closed []string `json:"closed"`
+}
+
+// DigestPreferences - настройки ежедневной сводки по ревью на почту
+type DigestPreferences struct {
+	UserID        string     `json:"user_id"`
+	Email         string     `json:"email"`
+	DigestEnabled bool       `json:"digest_enabled"`
+	LastSentAt    *time.Time `json:"last_sent_at,omitempty"`
+}
+
+// DigestItem - открытый PR в сводке
+type DigestItem struct {
+	PullRequestID     string    `json:"pull_request_id"`
+	PullRequestName   string    `json:"pull_request_name"`
+	AuthorID          string    `json:"author_id"`
+	AuthorName        string    `json:"author_name"`
+	CreatedAt         time.Time `json:"created_at"`
+	AgeHours          float64   `json:"age_hours"`
+	NeedMoreReviewers bool      `json:"needMoreReviewers"`
+}
+
+// Digest - сводка для одного пользователя: то, что уйдёт письмом, и данные, из которых оно собрано
+type Digest struct {
+	UserID       string        `json:"user_id"`
+	Username     string        `json:"username"`
+	Email        string        `json:"email"`
+	GeneratedAt  time.Time     `json:"generated_at"`
+	PullRequests []*DigestItem `json:"pull_requests"`
+	Subject      string        `json:"subject"`
+	Body         string        `json:"body"`
+}
+
+// ReviewerAssignment - ревьюер, добавленный на PR без замены кого-то другого
+type ReviewerAssignment struct {
+	PullRequestID string `json:"pull_request_id"`
+	ReviewerID    string `json:"reviewer_id"`
+}
+
+// UserFilter - дополнительный фильтр участников команды для массовых операций
+type UserFilter struct {
+	UsernamePrefix string   `json:"username_prefix"`
+	ExcludeUserIDs []string `json:"exclude_user_ids"`
+}
+
+// BulkSetActiveResult - итог массовой активации или деактивации (или её прогона в режиме dry_run)
+type BulkSetActiveResult struct {
+	IsActive            bool                   `json:"is_active"`
+	UpdatedUserIDs      []string               `json:"updated_user_ids"`
+	UnchangedUserIDs    []string               `json:"unchanged_user_ids"`
+	NotFoundUserIDs     []string               `json:"not_found_user_ids"`
+	ReassignedPRs       []string               `json:"reassigned_prs"`
+	Reassignments       []ReviewerReassignment `json:"reassignments"`
+	WithoutCandidate    []ReviewerReassignment `json:"without_candidate"`
+	BackfilledReviewers []ReviewerAssignment   `json:"backfilled_reviewers"`
+	DryRun              bool                   `json:"dry_run"`
+}
+
+// BulkCreateItem - один PR в пакетном создании
+type BulkCreateItem struct {
+	PullRequestID   string              `json:"pull_request_id"`
+	PullRequestName string              `json:"pull_request_name"`
+	AuthorID        string              `json:"author_id"`
+	Labels          []string            `json:"labels"`
+	Priority        PullRequestPriority `json:"priority"`
+}
+
+// BulkCreateResult - итог по одному PR из пакета. Ошибка одного PR не отменяет остальные.
+type BulkCreateResult struct {
+	PullRequestID string       `json:"pull_request_id"`
+	Created       bool         `json:"created"`
+	PR            *PullRequest `json:"pr,omitempty"`
+	ErrorCode     ErrorCode    `json:"error_code,omitempty"`
+	Message       string       `json:"message,omitempty"`
+}
+
+// OutboxEventType - доменное событие для внешних подписчиков
+type OutboxEventType string
+
+const (
+	OutboxPRCreated          OutboxEventType = "pr.created"
+	OutboxPRMerged           OutboxEventType = "pr.merged"
+	OutboxPRUpdated          OutboxEventType = "pr.updated"
+	OutboxReviewerReassigned OutboxEventType = "pr.reviewer_reassigned"
+	OutboxUserDeactivated    OutboxEventType = "user.deactivated"
+	OutboxReviewEscalated    OutboxEventType = "pr.review_escalated"
+	OutboxPRStaleWarning     OutboxEventType = "pr.stale_warning"
+	OutboxPRClosed           OutboxEventType = "pr.closed"
+	OutboxReviewerAdded      OutboxEventType = "pr.reviewer_added"
+	OutboxReviewerRemoved    OutboxEventType = "pr.reviewer_removed"
+	OutboxReviewDeclined     OutboxEventType = "pr.review_declined"
+)
+
+// Агрегаты событий outbox: порядок доставки гарантируется внутри одного агрегата
+const (
+	AggregatePullRequest = "pull_request"
+	AggregateUser        = "user"
+)
+
+// OutboxEvent - событие из outbox. Payload - JSON, зависит от EventType:
+// PullRequest для pr.created и pr.merged, ReviewerReassignment для pr.reviewer_reassigned,
+// UserDeactivatedPayload для user.deactivated.
+type OutboxEvent struct {
+	ID            int64           `json:"id"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	EventType     OutboxEventType `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Attempts      int             `json:"attempts"`
+}
+
+type UserDeactivatedPayload struct {
+	UserID   string `json:"user_id"`
+	TeamName string `json:"team_name"`
+}
+
+// SnapshotFormatVersion - версия формата выгрузки. Меняю, если формат меняется несовместимо.
+const SnapshotFormatVersion = 1
+
+// Snapshot - полная выгрузка команд, пользователей и PR для переноса между окружениями.
+// Вместе с ними едут профили пользователей, настройки команд и подписки на сводку.
+// Эти разделы необязательные, так что старые выгрузки без них тоже загружаются.
+type Snapshot struct {
+	FormatVersion     int                  `json:"format_version"`
+	ExportedAt        time.Time            `json:"exported_at"`
+	Teams             []*Team              `json:"teams"`
+	PullRequests      []*PullRequest       `json:"pull_requests"`
+	Profiles          []*UserProfile       `json:"profiles,omitempty"`
+	ReviewRules       []*ReviewRules       `json:"review_rules,omitempty"`
+	SLAs              []*TeamSLA           `json:"slas,omitempty"`
+	StalePolicies     []*StalePolicy       `json:"stale_policies,omitempty"`
+	DigestPreferences []*DigestPreferences `json:"digest_preferences,omitempty"`
+}
+
+// UserProfile - уровень и навыки пользователя (как в /users/setProfile), для выгрузки
+type UserProfile struct {
+	UserID    string    `json:"user_id"`
+	Seniority Seniority `json:"seniority,omitempty"`
+	Skills    []string  `json:"skills,omitempty"`
+}
+
+// ImportMode - что делать, если команда или PR из выгрузки уже есть
+type ImportMode string
+
+const (
+	ImportModeFail      ImportMode = "fail"
+	ImportModeSkip      ImportMode = "skip"
+	ImportModeOverwrite ImportMode = "overwrite"
+)
+
+// ImportResult - что сделал импорт. SettingsImported - профили, правила подбора, SLA,
+// политики брошенных PR и подписки на сводку вместе.
+type ImportResult struct {
+	Mode             ImportMode `json:"mode"`
+	TeamsCreated     int        `json:"teams_created"`
+	TeamsUpdated     int        `json:"teams_updated"`
+	TeamsSkipped     int        `json:"teams_skipped"`
+	UsersImported    int        `json:"users_imported"`
+	PRsCreated       int        `json:"prs_created"`
+	PRsUpdated       int        `json:"prs_updated"`
+	PRsSkipped       int        `json:"prs_skipped"`
+	SettingsImported int        `json:"settings_imported"`
 }
 
 type ErrorCode string
@@ -53,7 +525,18 @@ const (
 	ErrorPRMerged    ErrorCode = "PR_MERGED"
 	ErrorNotAssigned ErrorCode = "NOT_ASSIGNED"
 	ErrorNoCandidate ErrorCode = "NO_CANDIDATE"
+	ErrorPRClosed    ErrorCode = "PR_CLOSED"
 	ErrorNotFound    ErrorCode = "NOT_FOUND"
+	// ErrorVersionMismatch - If-Match не совпал с текущей версией (412)
+	ErrorVersionMismatch ErrorCode = "VERSION_MISMATCH"
+	// ErrorIdempotencyKeyReused - тот же Idempotency-Key пришёл с другим телом запроса (422)
+	ErrorIdempotencyKeyReused ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+	// ErrorIdempotencyInProgress - запрос с этим ключом ещё выполняется (409)
+	ErrorIdempotencyInProgress ErrorCode = "IDEMPOTENCY_IN_PROGRESS"
+	// ErrorNotEligible - выбранного вручную пользователя нельзя сделать ревьюером (409)
+	ErrorNotEligible ErrorCode = "NOT_ELIGIBLE"
+	// ErrorReviewersFull - на PR уже два ревьюера (409)
+	ErrorReviewersFull ErrorCode = "REVIEWERS_FULL"
 )
 
 type ErrorResponse struct {
@@ -63,6 +546,18 @@ type ErrorResponse struct {
 	} `json:"error"`
 }
 
+// IdempotencyRecord - сохранённый ответ на запрос с Idempotency-Key.
+// Пока запрос выполняется, Completed = false и ответа ещё нет.
+type IdempotencyRecord struct {
+	Key             string
+	Route           string
+	RequestHash     string
+	Completed       bool
+	StatusCode      int
+	ResponseBody    []byte
+	ResponseHeaders map[string]string
+}
+
 // Statistics models
 type UserReviewStats struct {
 	UserID           string `json:"user_id" db:"user_id"`
@@ -70,6 +565,8 @@ type UserReviewStats struct {
 	TotalAssignments int    `json:"total_assignments" db:"total_assignments"`
 	OpenAssignments  int    `json:"open_assignments" db:"open_assignments"`
 	MergedAssignments int   `json:"merged_assignments" db:"merged_assignments"`
+	// Declines - сколько раз отказался от ревью через /pullRequest/decline
+	Declines int `json:"declines" db:"declines"`
 }
 
 type PRStats struct {
@@ -77,9 +574,116 @@ type PRStats struct {
 	OpenPRs         int `json:"open_prs" db:"open_prs"`
 	MergedPRs       int `json:"merged_prs" db:"merged_prs"`
 	TotalAssignments int `json:"total_assignments" db:"total_assignments"`
+	// TotalDeclines - отказы ревьюеров от этих PR
+	TotalDeclines int `json:"total_declines" db:"total_declines"`
 }
 
 type StatisticsResponse struct {
 	UserStats []UserReviewStats `json:"user_stats"`
 	PRStats   PRStats           `json:"pr_stats"`
-}
\ No newline at end of file
+}
+
+// StatisticsFilter - фильтры статистики. Пустые поля означают "без ограничения".
+// Интервал полуоткрытый: [From, To).
+type StatisticsFilter struct {
+	From     *time.Time
+	To       *time.Time
+	TeamName string
+}
+
+// TimeSeriesBucket - один бакет (день или неделя) временного ряда
+type TimeSeriesBucket struct {
+	BucketStart   time.Time `json:"bucket_start"`
+	PRsCreated    int       `json:"prs_created"`
+	PRsMerged     int       `json:"prs_merged"`
+	Assignments   int       `json:"assignments"`
+	Reassignments int       `json:"reassignments"`
+}
+
+type TimeSeriesResponse struct {
+	Interval string             `json:"interval"`
+	From     time.Time          `json:"from"`
+	To       time.Time          `json:"to"`
+	TeamName string             `json:"team_name,omitempty"`
+	Buckets  []TimeSeriesBucket `json:"buckets"`
+}
+
+type AssignmentBucket struct {
+	BucketStart time.Time `json:"bucket_start"`
+	Assignments int       `json:"assignments"`
+}
+
+// ReviewerAssignmentSeries - назначения одного ревьюера по бакетам
+type ReviewerAssignmentSeries struct {
+	UserID           string             `json:"user_id"`
+	Username         string             `json:"username"`
+	TotalAssignments int                `json:"total_assignments"`
+	Buckets          []AssignmentBucket `json:"buckets"`
+}
+
+type ReviewerAssignmentSeriesResponse struct {
+	Interval  string                      `json:"interval"`
+	From      time.Time                   `json:"from"`
+	To        time.Time                   `json:"to"`
+	TeamName  string                      `json:"team_name,omitempty"`
+	Reviewers []*ReviewerAssignmentSeries `json:"reviewers"`
+}
+
+// TeamStats - разбивка статистики по командам
+type TeamStats struct {
+	TeamName      string `json:"team_name"`
+	Members       int    `json:"members"`
+	ActiveMembers int    `json:"active_members"`
+	PRsCreated    int    `json:"prs_created"`
+	PRsMerged     int    `json:"prs_merged"`
+	Assignments   int    `json:"assignments"`
+	Reassignments int    `json:"reassignments"`
+}
+
+// DurationStats - медиана и 90-й перцентиль длительности в часах.
+// Если измерений нет, медиана и перцентиль будут null.
+type DurationStats struct {
+	Count       int      `json:"count"`
+	MedianHours *float64 `json:"median_hours"`
+	P90Hours    *float64 `json:"p90_hours"`
+}
+
+type TeamMergeTime struct {
+	TeamName string `json:"team_name"`
+	DurationStats
+}
+
+type AuthorMergeTime struct {
+	AuthorID string `json:"author_id"`
+	Username string `json:"username"`
+	DurationStats
+}
+
+// ReviewerResponseTime - время от назначения до первого действия ревьюера.
+// Count - сколько назначений получили ответ, Pending - сколько пока висят без ответа.
+type ReviewerResponseTime struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	Pending  int    `json:"pending"`
+	DurationStats
+}
+
+type TurnaroundResponse struct {
+	From        *time.Time              `json:"from,omitempty"`
+	To          *time.Time              `json:"to,omitempty"`
+	TeamName    string                  `json:"team_name,omitempty"`
+	TimeToMerge DurationStats           `json:"time_to_merge"`
+	ByTeam      []*TeamMergeTime        `json:"by_team"`
+	ByAuthor    []*AuthorMergeTime      `json:"by_author"`
+	ByReviewer  []*ReviewerResponseTime `json:"by_reviewer"`
+}
+
+// LeaderboardEntry - место ревьюера в рейтинге: больше ревью и быстрее ответ - выше
+type LeaderboardEntry struct {
+	Rank                int      `json:"rank"`
+	UserID              string   `json:"user_id"`
+	Username            string   `json:"username"`
+	TeamName            string   `json:"team_name"`
+	Reviews             int      `json:"reviews"`
+	MedianResponseHours *float64 `json:"median_response_hours"`
+}
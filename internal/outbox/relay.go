@@ -0,0 +1,85 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/service"
+	"time"
+)
+
+// Sink - куда релей отправляет события outbox. Доставка "хотя бы один раз",
+// так что получатель должен быть готов к повторам (по event.ID их легко отсечь).
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event *models.OutboxEvent) error
+}
+
+// Relay - фоновая рассылка событий outbox по синкам
+type Relay struct {
+	service   *service.Service
+	sinks     []Sink
+	interval  time.Duration
+	batchSize int
+	// publishTimeout - сколько жду один синк, чтобы зависший вебхук не держал пачку вечно
+	publishTimeout time.Duration
+}
+
+func NewRelay(svc *service.Service, sinks []Sink, interval time.Duration) *Relay {
+	return &Relay{
+		service:        svc,
+		sinks:          sinks,
+		interval:       interval,
+		batchSize:      100,
+		publishTimeout: 10 * time.Second,
+	}
+}
+
+// Run - раз в interval разбираю outbox, пока в нём есть готовые события. Выхожу, когда закрыли ctx.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for ctx.Err() == nil {
+			claimed, err := r.service.ProcessOutbox(r.batchSize, r.claimLease(), func(event *models.OutboxEvent) error {
+				return r.publish(ctx, event)
+			})
+			if err != nil {
+				log.Printf("Не получилось разобрать outbox: %v", err)
+				break
+			}
+			if claimed < r.batchSize {
+				break
+			}
+		}
+	}
+}
+
+// claimLease - на сколько забираю пачку: даже если каждый синк тянет до таймаута,
+// другой релей не возьмёт события, пока эта пачка не разобрана
+func (r *Relay) claimLease() time.Duration {
+	return time.Duration(r.batchSize*len(r.sinks))*r.publishTimeout + time.Minute
+}
+
+// publish - отправляю событие во все синки. Если упал хоть один, событие уйдёт повторно во все:
+// так проще, а повторы получатели и так должны переживать.
+func (r *Relay) publish(ctx context.Context, event *models.OutboxEvent) error {
+	for _, sink := range r.sinks {
+		sinkCtx, cancel := context.WithTimeout(ctx, r.publishTimeout)
+		err := sink.Publish(sinkCtx, event)
+		cancel()
+		if err != nil {
+			log.Printf("Событие outbox %d (%s) не ушло в %s: %v", event.ID, event.EventType, sink.Name(), err)
+			return fmt.Errorf("%s: %w", sink.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"pr-reviewer-service/internal/models"
+	"testing"
+	"time"
+)
+
+// flakySink - падает первые failures раз, потом принимает события
+type flakySink struct {
+	name     string
+	failures int
+	received []int64
+}
+
+func (s *flakySink) Name() string { return s.name }
+
+func (s *flakySink) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("sink unavailable")
+	}
+	s.received = append(s.received, event.ID)
+	return nil
+}
+
+// TestRelayPublishRetriesAfterSinkFailure - упавший синк обрывает публикацию, а повтор доходит до всех
+func TestRelayPublishRetriesAfterSinkFailure(t *testing.T) {
+	first := &flakySink{name: "first"}
+	flaky := &flakySink{name: "flaky", failures: 1}
+	last := &flakySink{name: "last"}
+	relay := &Relay{sinks: []Sink{first, flaky, last}, publishTimeout: time.Second}
+	event := outboxEvent(t, models.OutboxPRCreated, map[string]string{"pull_request_id": "pr-1"})
+
+	// Первая попытка: ошибка с именем синка, а до следующих синков дело не доходит
+	err := relay.publish(context.Background(), event)
+	if err == nil || err.Error() != "flaky: sink unavailable" {
+		t.Fatalf("Ожидалась ошибка синка flaky, получено %v", err)
+	}
+	if len(first.received) != 1 || len(flaky.received) != 0 || len(last.received) != 0 {
+		t.Fatalf("Неожиданная доставка после сбоя: first=%v flaky=%v last=%v", first.received, flaky.received, last.received)
+	}
+
+	// Повтор уходит во все синки, включая тот, что уже получил событие
+	if err := relay.publish(context.Background(), event); err != nil {
+		t.Fatalf("Повторная публикация не удалась: %v", err)
+	}
+	if len(first.received) != 2 || len(flaky.received) != 1 || len(last.received) != 1 {
+		t.Errorf("Неожиданная доставка после повтора: first=%v flaky=%v last=%v", first.received, flaky.received, last.received)
+	}
+}
+
+// TestRelayClaimLeaseCoversSlowSinks - аренда пачки дольше, чем худший случай её разбора
+func TestRelayClaimLeaseCoversSlowSinks(t *testing.T) {
+	relay := &Relay{
+		sinks:          []Sink{&flakySink{name: "a"}, &flakySink{name: "b"}},
+		batchSize:      10,
+		publishTimeout: time.Second,
+	}
+	worst := time.Duration(relay.batchSize*len(relay.sinks)) * relay.publishTimeout
+	if lease := relay.claimLease(); lease <= worst {
+		t.Errorf("Аренда %v не дольше худшего времени разбора пачки %v", lease, worst)
+	}
+}
@@ -0,0 +1,304 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"pr-reviewer-service/internal/models"
+	"strings"
+	"text/template"
+)
+
+// Шаблоны сообщений по умолчанию. В шаблон приходит slackMessage.
+const (
+	defaultAssignedTemplate = `{{.Reviewers}}, вас назначили ревьюером на PR *{{.PullRequestName}}* ({{.PullRequestID}}) от {{.Author}}` +
+		`{{if .OldReviewer}} вместо {{.OldReviewer}}{{end}}`
+	defaultRemovedTemplate           = `{{.OldReviewer}} больше не ревьюер PR *{{.PullRequestName}}* ({{.PullRequestID}}) от {{.Author}}`
+	defaultNeedMoreReviewersTemplate = `PR *{{.PullRequestName}}* ({{.PullRequestID}}) от {{.Author}} ждёт ревьюеров: ` +
+		`в команде {{.TeamName}} не нашлось двух свободных{{if .Reviewers}}, сейчас назначены {{.Reviewers}}{{end}}`
+	defaultEscalatedTemplate = `PR *{{.PullRequestName}}* ({{.PullRequestID}}) от {{.Author}} не получил первое ревью вовремя ({{.Reason}})` +
+		`{{if .Reviewers}}. {{.Reviewers}}, посмотрите, пожалуйста{{end}}`
+)
+
+// SlackConfig - куда и как слать уведомления в Slack. Читается из JSON-файла (SLACK_CONFIG).
+type SlackConfig struct {
+	// TeamWebhooks - incoming webhook на канал каждой команды
+	TeamWebhooks map[string]string `json:"team_webhooks"`
+	// DefaultWebhook - для команд без своего канала. Пусто - такие команды не уведомляю.
+	DefaultWebhook string `json:"default_webhook"`
+	// UserHandles - user_id -> Slack member ID (U024BE7LH), чтобы упоминание было кликабельным
+	UserHandles map[string]string `json:"user_handles"`
+	Templates   struct {
+		Assigned          string `json:"assigned"`
+		Removed           string `json:"removed"`
+		NeedMoreReviewers string `json:"need_more_reviewers"`
+		Escalated         string `json:"escalated"`
+	} `json:"templates"`
+}
+
+func LoadSlackConfig(path string) (*SlackConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg SlackConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid slack config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// Directory - откуда Slack-синк берёт пользователей и PR: события outbox несут только ID.
+// Его реализует service.Service.
+type Directory interface {
+	GetUser(userID string) (*models.User, error)
+	GetPullRequest(prID string) (*models.PullRequest, error)
+}
+
+// slackMessage - данные для шаблона. Пользователи уже превращены в упоминания.
+type slackMessage struct {
+	PullRequestID   string
+	PullRequestName string
+	TeamName        string
+	Author          string
+	Reviewers       string
+	// OldReviewer - кого заменили (assigned) или сняли (removed)
+	OldReviewer string
+	// Reason - почему PR эскалирован, только для шаблона escalated
+	Reason string
+}
+
+// SlackSink - уведомления о назначениях в Slack-совместимые incoming webhooks.
+// Пишет, когда PR создан, когда ревьюера заменили, добавили или сняли, и отдельно -
+// когда после этого на PR не хватает ревьюеров (needMoreReviewers).
+// Канал выбирается по команде автора PR.
+type SlackSink struct {
+	cfg       *SlackConfig
+	directory Directory
+	client    *http.Client
+	assigned  *template.Template
+	removed   *template.Template
+	needMore  *template.Template
+	escalated *template.Template
+}
+
+func NewSlackSink(cfg *SlackConfig, directory Directory) (*SlackSink, error) {
+	assigned, err := parseSlackTemplate("assigned", cfg.Templates.Assigned, defaultAssignedTemplate)
+	if err != nil {
+		return nil, err
+	}
+	removed, err := parseSlackTemplate("removed", cfg.Templates.Removed, defaultRemovedTemplate)
+	if err != nil {
+		return nil, err
+	}
+	needMore, err := parseSlackTemplate("need_more_reviewers", cfg.Templates.NeedMoreReviewers, defaultNeedMoreReviewersTemplate)
+	if err != nil {
+		return nil, err
+	}
+	escalated, err := parseSlackTemplate("escalated", cfg.Templates.Escalated, defaultEscalatedTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &SlackSink{
+		cfg:       cfg,
+		directory: directory,
+		client:    &http.Client{},
+		assigned:  assigned,
+		removed:   removed,
+		needMore:  needMore,
+		escalated: escalated,
+	}, nil
+}
+
+func parseSlackTemplate(name, text, fallback string) (*template.Template, error) {
+	if text == "" {
+		text = fallback
+	}
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid slack template %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func (s *SlackSink) Name() string { return "slack" }
+
+func (s *SlackSink) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	switch event.EventType {
+	case models.OutboxPRCreated:
+		var pr models.PullRequest
+		if err := json.Unmarshal(event.Payload, &pr); err != nil {
+			return err
+		}
+		return s.notifyCreated(ctx, &pr)
+	case models.OutboxReviewerReassigned:
+		var reassignment models.ReviewerReassignment
+		if err := json.Unmarshal(event.Payload, &reassignment); err != nil {
+			return err
+		}
+		return s.notifyReassigned(ctx, &reassignment)
+	case models.OutboxReviewerAdded, models.OutboxReviewerRemoved:
+		var change models.ReviewerChange
+		if err := json.Unmarshal(event.Payload, &change); err != nil {
+			return err
+		}
+		return s.notifyReviewerChange(ctx, event.EventType, &change)
+	case models.OutboxReviewEscalated:
+		var escalation models.Escalation
+		if err := json.Unmarshal(event.Payload, &escalation); err != nil {
+			return err
+		}
+		return s.notifyEscalated(ctx, &escalation)
+	}
+	// Остальные события в Slack не шлю
+	return nil
+}
+
+func (s *SlackSink) notifyCreated(ctx context.Context, pr *models.PullRequest) error {
+	msg, webhook, err := s.prepare(pr)
+	if err != nil || webhook == "" {
+		return err
+	}
+	msg.Reviewers = s.mentions(pr.AssignedReviewers)
+
+	if len(pr.AssignedReviewers) > 0 {
+		if err := s.post(ctx, webhook, s.assigned, msg); err != nil {
+			return err
+		}
+	}
+	if pr.NeedMoreReviewers {
+		return s.post(ctx, webhook, s.needMore, msg)
+	}
+	return nil
+}
+
+func (s *SlackSink) notifyReassigned(ctx context.Context, reassignment *models.ReviewerReassignment) error {
+	pr, err := s.directory.GetPullRequest(reassignment.PullRequestID)
+	if err != nil {
+		return err
+	}
+	msg, webhook, err := s.prepare(pr)
+	if err != nil || webhook == "" {
+		return err
+	}
+	msg.Reviewers = s.mention(reassignment.NewReviewerID)
+	msg.OldReviewer = s.mention(reassignment.OldReviewerID)
+	return s.post(ctx, webhook, s.assigned, msg)
+}
+
+// notifyReviewerChange - ревьюера добавили или сняли. Если после этого ревьюеров не хватает,
+// следом прошу помочь, упоминая тех, кто на PR сейчас.
+func (s *SlackSink) notifyReviewerChange(ctx context.Context, eventType models.OutboxEventType, change *models.ReviewerChange) error {
+	pr, err := s.directory.GetPullRequest(change.PullRequestID)
+	if err != nil {
+		return err
+	}
+	msg, webhook, err := s.prepare(pr)
+	if err != nil || webhook == "" {
+		return err
+	}
+
+	tmpl := s.assigned
+	if eventType == models.OutboxReviewerAdded {
+		msg.Reviewers = s.mention(change.ReviewerID)
+	} else {
+		tmpl = s.removed
+		msg.OldReviewer = s.mention(change.ReviewerID)
+	}
+	if err := s.post(ctx, webhook, tmpl, msg); err != nil {
+		return err
+	}
+
+	if !change.NeedMoreReviewers {
+		return nil
+	}
+	msg.Reviewers = s.mentions(pr.AssignedReviewers)
+	msg.OldReviewer = ""
+	return s.post(ctx, webhook, s.needMore, msg)
+}
+
+// notifyEscalated - PR нарушил SLA на первое ревью. Упоминаю текущих ревьюеров,
+// то есть уже с учётом замены или добавления, которые сделала эскалация.
+func (s *SlackSink) notifyEscalated(ctx context.Context, escalation *models.Escalation) error {
+	pr, err := s.directory.GetPullRequest(escalation.PullRequestID)
+	if err != nil {
+		return err
+	}
+	msg, webhook, err := s.prepare(pr)
+	if err != nil || webhook == "" {
+		return err
+	}
+	msg.Reviewers = s.mentions(pr.AssignedReviewers)
+	msg.Reason = escalation.Reason
+	return s.post(ctx, webhook, s.escalated, msg)
+}
+
+// prepare - общие поля сообщения и вебхук команды автора (пусто, если слать некуда)
+func (s *SlackSink) prepare(pr *models.PullRequest) (*slackMessage, string, error) {
+	author, err := s.directory.GetUser(pr.AuthorID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	webhook := s.cfg.TeamWebhooks[author.TeamName]
+	if webhook == "" {
+		webhook = s.cfg.DefaultWebhook
+	}
+
+	return &slackMessage{
+		PullRequestID:   pr.PullRequestID,
+		PullRequestName: pr.PullRequestName,
+		TeamName:        author.TeamName,
+		Author:          s.mention(author.UserID),
+	}, webhook, nil
+}
+
+// mention - <@U024BE7LH>, если знаю Slack-аккаунт пользователя, иначе просто его имя
+func (s *SlackSink) mention(userID string) string {
+	if handle := s.cfg.UserHandles[userID]; handle != "" {
+		return "<@" + handle + ">"
+	}
+	if user, err := s.directory.GetUser(userID); err == nil && user.Username != "" {
+		return user.Username
+	}
+	return userID
+}
+
+func (s *SlackSink) mentions(userIDs []string) string {
+	names := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		names = append(names, s.mention(userID))
+	}
+	return strings.Join(names, ", ")
+}
+
+func (s *SlackSink) post(ctx context.Context, webhook string, tmpl *template.Template, msg *slackMessage) error {
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, msg); err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]string{"text": text.String()})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"pr-reviewer-service/internal/models"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type fakeDirectory struct {
+	users map[string]*models.User
+	prs   map[string]*models.PullRequest
+}
+
+func (d *fakeDirectory) GetUser(userID string) (*models.User, error) {
+	if user, ok := d.users[userID]; ok {
+		return user, nil
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+func (d *fakeDirectory) GetPullRequest(prID string) (*models.PullRequest, error) {
+	if pr, ok := d.prs[prID]; ok {
+		return pr, nil
+	}
+	return nil, fmt.Errorf("pull request not found")
+}
+
+// slackStub - локальный вебхук: запоминаю, что и на какой путь прислали
+type slackStub struct {
+	mu       sync.Mutex
+	messages map[string][]string
+	status   int
+}
+
+func (s *slackStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Text string `json:"text"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	s.mu.Lock()
+	s.messages[r.URL.Path] = append(s.messages[r.URL.Path], body.Text)
+	s.mu.Unlock()
+
+	w.WriteHeader(s.status)
+}
+
+func outboxEvent(t *testing.T, eventType models.OutboxEventType, payload interface{}) *models.OutboxEvent {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	return &models.OutboxEvent{ID: 1, EventType: eventType, Payload: data}
+}
+
+// TestSlackSink - уведомления уходят в канал команды автора, с упоминаниями из маппинга
+// и по шаблонам; needMoreReviewers - отдельным сообщением
+func TestSlackSink(t *testing.T) {
+	stub := &slackStub{messages: make(map[string][]string), status: http.StatusOK}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	directory := &fakeDirectory{
+		users: map[string]*models.User{
+			"u1": {UserID: "u1", Username: "Alice", TeamName: "backend"},
+			"u2": {UserID: "u2", Username: "Bob", TeamName: "backend"},
+			"u3": {UserID: "u3", Username: "Carol", TeamName: "backend"},
+			"u4": {UserID: "u4", Username: "Dave", TeamName: "frontend"},
+		},
+		prs: map[string]*models.PullRequest{
+			"pr-1": {PullRequestID: "pr-1", PullRequestName: "Add search", AuthorID: "u1"},
+		},
+	}
+
+	cfg := &SlackConfig{
+		TeamWebhooks: map[string]string{"backend": server.URL + "/backend"},
+		UserHandles:  map[string]string{"u2": "U0BOB"},
+	}
+	cfg.Templates.Assigned = "assigned {{.PullRequestID}} to {{.Reviewers}}{{if .OldReviewer}} instead of {{.OldReviewer}}{{end}}"
+
+	sink, err := NewSlackSink(cfg, directory)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	ctx := context.Background()
+
+	// PR создан с одним ревьюером - назначение и просьба добрать ревьюеров
+	err = sink.Publish(ctx, outboxEvent(t, models.OutboxPRCreated, models.PullRequest{
+		PullRequestID:     "pr-1",
+		PullRequestName:   "Add search",
+		AuthorID:          "u1",
+		AssignedReviewers: []string{"u2"},
+		NeedMoreReviewers: true,
+	}))
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+
+	// Замена ревьюера
+	err = sink.Publish(ctx, outboxEvent(t, models.OutboxReviewerReassigned, models.ReviewerReassignment{
+		PullRequestID: "pr-1",
+		OldReviewerID: "u2",
+		NewReviewerID: "u3",
+	}))
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+
+	// PR команды без канала и без default_webhook - никуда не шлю
+	err = sink.Publish(ctx, outboxEvent(t, models.OutboxPRCreated, models.PullRequest{
+		PullRequestID:     "pr-2",
+		AuthorID:          "u4",
+		AssignedReviewers: []string{"u1"},
+	}))
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+
+	// Эскалация по SLA
+	err = sink.Publish(ctx, outboxEvent(t, models.OutboxReviewEscalated, models.Escalation{
+		PullRequestID: "pr-1",
+		Action:        models.SLANotify,
+		Reason:        "no first review within 24h",
+	}))
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+
+	got := stub.messages["/backend"]
+	if len(got) != 4 || len(stub.messages) != 1 {
+		t.Fatalf("Ожидалось 4 сообщения в канал backend, получено %v", stub.messages)
+	}
+	if got[0] != "assigned pr-1 to <@U0BOB>" {
+		t.Errorf("Неожиданное сообщение о назначении: %q", got[0])
+	}
+	if !strings.Contains(got[1], "ждёт ревьюеров") || !strings.Contains(got[1], "Alice") {
+		t.Errorf("Неожиданное сообщение о needMoreReviewers: %q", got[1])
+	}
+	if got[2] != "assigned pr-1 to Carol instead of <@U0BOB>" {
+		t.Errorf("Неожиданное сообщение о замене: %q", got[2])
+	}
+	if !strings.Contains(got[3], "no first review within 24h") || !strings.Contains(got[3], "Add search") {
+		t.Errorf("Неожиданное сообщение об эскалации: %q", got[3])
+	}
+
+	// Если Slack отвечает ошибкой, событие должно остаться в outbox для повтора
+	stub.status = http.StatusInternalServerError
+	err = sink.Publish(ctx, outboxEvent(t, models.OutboxReviewerReassigned, models.ReviewerReassignment{
+		PullRequestID: "pr-1",
+		OldReviewerID: "u3",
+		NewReviewerID: "u2",
+	}))
+	if err == nil {
+		t.Error("Ожидалась ошибка при ответе 500")
+	}
+}
+
+// TestSlackSinkReviewerChanges - ручное добавление и снятие ревьюера, и просьба добрать
+// ревьюеров, если после изменения их не хватает
+func TestSlackSinkReviewerChanges(t *testing.T) {
+	stub := &slackStub{messages: make(map[string][]string), status: http.StatusOK}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	directory := &fakeDirectory{
+		users: map[string]*models.User{
+			"u1": {UserID: "u1", Username: "Alice", TeamName: "backend"},
+			"u2": {UserID: "u2", Username: "Bob", TeamName: "backend"},
+			"u3": {UserID: "u3", Username: "Carol", TeamName: "backend"},
+		},
+		prs: map[string]*models.PullRequest{
+			// Состояние PR на момент отправки: Bob уже снят, остался Carol
+			"pr-1": {PullRequestID: "pr-1", PullRequestName: "Add search", AuthorID: "u1", AssignedReviewers: []string{"u3"}},
+		},
+	}
+
+	cfg := &SlackConfig{DefaultWebhook: server.URL + "/default"}
+	cfg.Templates.Assigned = "assigned {{.PullRequestID}} to {{.Reviewers}}"
+	cfg.Templates.Removed = "removed {{.OldReviewer}} from {{.PullRequestID}}"
+	cfg.Templates.NeedMoreReviewers = "need more on {{.PullRequestID}}, now {{.Reviewers}}"
+
+	sink, err := NewSlackSink(cfg, directory)
+	if err != nil {
+		t.Fatalf("Ошибка: %v", err)
+	}
+	ctx := context.Background()
+
+	publish := func(eventType models.OutboxEventType, change models.ReviewerChange) []string {
+		before := len(stub.messages["/default"])
+		if err := sink.Publish(ctx, outboxEvent(t, eventType, change)); err != nil {
+			t.Fatalf("Ошибка: %v", err)
+		}
+		return stub.messages["/default"][before:]
+	}
+
+	// Добавили, ревьюеров хватает - одно сообщение
+	got := publish(models.OutboxReviewerAdded, models.ReviewerChange{PullRequestID: "pr-1", ReviewerID: "u3"})
+	if len(got) != 1 || got[0] != "assigned pr-1 to Carol" {
+		t.Errorf("Неожиданные сообщения о добавлении: %q", got)
+	}
+
+	// Сняли, ревьюеров не хватает - снятие и просьба помочь
+	got = publish(models.OutboxReviewerRemoved, models.ReviewerChange{PullRequestID: "pr-1", ReviewerID: "u2", NeedMoreReviewers: true})
+	if len(got) != 2 || got[0] != "removed Bob from pr-1" || got[1] != "need more on pr-1, now Carol" {
+		t.Errorf("Неожиданные сообщения о снятии: %q", got)
+	}
+
+	// Сняли, но ревьюеров по-прежнему хватает - только снятие
+	got = publish(models.OutboxReviewerRemoved, models.ReviewerChange{PullRequestID: "pr-1", ReviewerID: "u2"})
+	if len(got) != 1 || got[0] != "removed Bob from pr-1" {
+		t.Errorf("Неожиданные сообщения о снятии без needMoreReviewers: %q", got)
+	}
+
+	// Добавили, но второго всё ещё нет - добавление и просьба помочь
+	got = publish(models.OutboxReviewerAdded, models.ReviewerChange{PullRequestID: "pr-1", ReviewerID: "u3", NeedMoreReviewers: true})
+	if len(got) != 2 || got[1] != "need more on pr-1, now Carol" {
+		t.Errorf("Неожиданные сообщения о добавлении с needMoreReviewers: %q", got)
+	}
+}
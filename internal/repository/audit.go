@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"pr-reviewer-service/internal/models"
+)
+
+func (r *Repository) CreateAuditEntry(entry *models.PullRequestAuditEntry) error {
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return err
+	}
+	return r.db.QueryRow(`
+		INSERT INTO pull_request_audit (pull_request_id, changed_by, changes)
+		VALUES ($1::text, NULLIF($2::text, ''), $3::jsonb)
+		RETURNING id, created_at
+	`, entry.PullRequestID, entry.ChangedBy, string(changes)).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+// GetAuditEntries - правки PR по порядку
+func (r *Repository) GetAuditEntries(prID string) ([]*models.PullRequestAuditEntry, error) {
+	rows, err := r.db.Query(`
+		SELECT id, pull_request_id, changed_by, changes, created_at
+		FROM pull_request_audit
+		WHERE pull_request_id = $1::text
+		ORDER BY id
+	`, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*models.PullRequestAuditEntry, 0)
+	for rows.Next() {
+		entry := &models.PullRequestAuditEntry{}
+		var changedBy sql.NullString
+		var changes []byte
+		if err := rows.Scan(&entry.ID, &entry.PullRequestID, &changedBy, &changes, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entry.ChangedBy = changedBy.String
+		if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"database/sql"
+	"pr-reviewer-service/internal/models"
+)
+
+// CreateReviewDecline - записываю отказ ревьюера от PR
+func (r *Repository) CreateReviewDecline(decline *models.ReviewDecline) error {
+	return r.db.QueryRow(`
+		INSERT INTO review_declines (pull_request_id, reviewer_id, reason, replaced_by)
+		VALUES ($1::text, $2::text, $3::text, NULLIF($4::text, ''))
+		RETURNING id, created_at
+	`, decline.PullRequestID, decline.ReviewerID, decline.Reason, decline.ReplacedBy).Scan(&decline.ID, &decline.CreatedAt)
+}
+
+// GetReviewDeclines - отказы от PR по порядку
+func (r *Repository) GetReviewDeclines(prID string) ([]*models.ReviewDecline, error) {
+	rows, err := r.db.Query(`
+		SELECT id, pull_request_id, reviewer_id, reason, replaced_by, created_at
+		FROM review_declines
+		WHERE pull_request_id = $1::text
+		ORDER BY id
+	`, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	declines := make([]*models.ReviewDecline, 0)
+	for rows.Next() {
+		decline := &models.ReviewDecline{}
+		var replacedBy sql.NullString
+		if err := rows.Scan(&decline.ID, &decline.PullRequestID, &decline.ReviewerID, &decline.Reason, &replacedBy, &decline.CreatedAt); err != nil {
+			return nil, err
+		}
+		decline.ReplacedBy = replacedBy.String
+		declines = append(declines, decline)
+	}
+	return declines, rows.Err()
+}
+
+// GetDeclinedReviewerIDs - кто уже отказывался от PR, их автоматически не назначаю
+func (r *Repository) GetDeclinedReviewerIDs(prID string) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT reviewer_id FROM review_declines WHERE pull_request_id = $1::text
+	`, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviewerIDs := make([]string, 0)
+	for rows.Next() {
+		var reviewerID string
+		if err := rows.Scan(&reviewerID); err != nil {
+			return nil, err
+		}
+		reviewerIDs = append(reviewerIDs, reviewerID)
+	}
+	return reviewerIDs, rows.Err()
+}
@@ -2,17 +2,150 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"pr-reviewer-service/internal/models"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+// maxTxAttempts - сколько раз пробую транзакцию, если Postgres отвалил её из-за конкурентного доступа
+const maxTxAttempts = 3
+
+// userEventsSeqLock - ключ advisory-блокировки, под которой выдаю stream_seq событиям потока
+const userEventsSeqLock = 7301001
+
+// querier - общее между *sql.DB и *sql.Tx, чтобы одни и те же методы работали и внутри транзакции
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 // Repository - тут вся работа с базой данных
 type Repository struct {
-	db *sql.DB
+	db   querier
+	conn *sql.DB // nil, если репозиторий уже работает внутри транзакции
+	// events - события, записанные в этой транзакции. После коммита они уходят в publishEvents.
+	events *[]models.UserEvent
+	// streamMu держу от выдачи stream_seq до раздачи событий, чтобы подписчики этого процесса
+	// получали их в том же порядке, в каком они закоммичены
+	streamMu      sync.Mutex
+	publishEvents func(events []models.UserEvent)
 }
 
 func NewRepository(db *sql.DB) *Repository {
-	return &Repository{db: db}
+	return &Repository{db: db, conn: db}
+}
+
+// SetEventPublisher - куда отдавать события пользователей после коммита транзакции
+func (r *Repository) SetEventPublisher(publish func(events []models.UserEvent)) {
+	r.publishEvents = publish
+}
+
+// WithTx - выполняю fn в одной транзакции.
+// Если fn вернула ошибку, всё откатывается. Если репозиторий уже внутри транзакции,
+// просто переиспользую её, чтобы вложенные вызовы не открывали новые.
+// При дедлоке или ошибке сериализации транзакция повторяется целиком, поэтому fn
+// должна быть готова к тому, что её вызовут ещё раз.
+func (r *Repository) WithTx(fn func(tx *Repository) error) error {
+	if r.conn == nil {
+		return fn(r)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := r.runTx(fn)
+		if err == nil || !isRetryableTxError(err) || attempt == maxTxAttempts {
+			return err
+		}
+	}
+}
+
+func (r *Repository) runTx(fn func(tx *Repository) error) error {
+	tx, err := r.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	txRepo := &Repository{db: tx, events: &[]models.UserEvent{}}
+	if err := fn(txRepo); err != nil {
+		return err
+	}
+	if len(*txRepo.events) == 0 {
+		return tx.Commit()
+	}
+
+	r.streamMu.Lock()
+	defer r.streamMu.Unlock()
+	if err := txRepo.sequenceUserEvents(); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if r.publishEvents != nil {
+		r.publishEvents(*txRepo.events)
+	}
+	return nil
+}
+
+// sequenceUserEvents - прямо перед коммитом выдаю событиям транзакции позиции в потоке.
+// id из BIGSERIAL выдаётся при вставке, и транзакция с меньшим id может закоммититься позже:
+// клиент, уже получивший больший id, после переподключения её событие бы не получил.
+// Advisory-блокировка держится до конца транзакции, так что следующая транзакция получит
+// позиции только после этого коммита, и stream_seq растёт строго в порядке коммитов.
+// Позиции правлю прямо в r.events, поэтому подписчикам уходят уже окончательные.
+func (r *Repository) sequenceUserEvents() error {
+	if _, err := r.db.Exec(`SELECT pg_advisory_xact_lock($1::bigint)`, userEventsSeqLock); err != nil {
+		return err
+	}
+
+	events := *r.events
+	seqs := make([]int64, len(events))
+	for i, event := range events {
+		seqs[i] = event.ID
+	}
+	rows, err := r.db.Query(`
+		UPDATE user_events e
+		SET stream_seq = nextval('user_events_stream_seq')
+		FROM unnest($1::bigint[]) AS t(seq)
+		WHERE e.stream_seq = t.seq
+		RETURNING t.seq, e.stream_seq
+	`, pq.Array(seqs))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	assigned := make(map[int64]int64, len(events))
+	for rows.Next() {
+		var oldSeq, newSeq int64
+		if err := rows.Scan(&oldSeq, &newSeq); err != nil {
+			return err
+		}
+		assigned[oldSeq] = newSeq
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range events {
+		events[i].ID = assigned[events[i].ID]
+	}
+	return nil
+}
+
+// isRetryableTxError - 40001 (serialization_failure) и 40P01 (deadlock_detected) лечатся повтором
+func isRetryableTxError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
 }
 
 // Teams
@@ -30,9 +163,29 @@ func (r *Repository) TeamExists(teamName string) (bool, error) {
 	return exists, err
 }
 
+// LockTeam - блокирую строку команды до конца транзакции,
+// чтобы массовые операции над одной командой не шли параллельно
+func (r *Repository) LockTeam(teamName string) error {
+	var name string
+	err := r.db.QueryRow("SELECT team_name FROM teams WHERE team_name = $1 FOR UPDATE", teamName).Scan(&name)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("team not found")
+	}
+	return err
+}
+
 func (r *Repository) GetTeam(teamName string) (*models.Team, error) {
 	team := &models.Team{TeamName: teamName}
 
+	// Заодно проверяю, что команда существует, даже если в ней нет участников
+	err := r.db.QueryRow("SELECT version FROM teams WHERE team_name = $1", teamName).Scan(&team.Version)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("team not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	rows, err := r.db.Query(`
 		SELECT user_id, username, is_active 
 		FROM users 
@@ -52,20 +205,15 @@ func (r *Repository) GetTeam(teamName string) (*models.Team, error) {
 		team.Members = append(team.Members, member)
 	}
 
-	if len(team.Members) == 0 {
-		// Проверяю, существует ли команда, даже если в ней нет участников
-		exists, err := r.TeamExists(teamName)
-		if err != nil {
-			return nil, err
-		}
-		if !exists {
-			return nil, fmt.Errorf("team not found")
-		}
-	}
-
 	return team, nil
 }
 
+// BumpTeamVersion - увеличиваю версию команды после изменения её состава
+func (r *Repository) BumpTeamVersion(teamName string) error {
+	_, err := r.db.Exec("UPDATE teams SET version = version + 1 WHERE team_name = $1", teamName)
+	return err
+}
+
 // Users
 
 // CreateOrUpdateUser - хитрый запрос.
@@ -88,10 +236,10 @@ func (r *Repository) CreateOrUpdateUser(user *models.User) error {
 func (r *Repository) GetUser(userID string) (*models.User, error) {
 	user := &models.User{}
 	err := r.db.QueryRow(`
-		SELECT user_id, username, team_name, is_active 
+		SELECT user_id, username, team_name, is_active, seniority, skills
 		FROM users 
 		WHERE user_id = $1
-	`, userID).Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive)
+	`, userID).Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive, &user.Seniority, pq.Array(&user.Skills))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("user not found")
 	}
@@ -120,14 +268,164 @@ func (r *Repository) UpdateUserActive(userID string, isActive bool) error {
 	return nil
 }
 
+// SetUserProfile - уровень и навыки пользователя
+func (r *Repository) SetUserProfile(userID string, seniority models.Seniority, skills []string) error {
+	result, err := r.db.Exec(`
+		UPDATE users
+		SET seniority = $1, skills = COALESCE($2::text[], '{}'), updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $3
+	`, seniority, pq.Array(skills), userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// GetSeniorUserIDs - кто из userIDs старший по уровню (senior и lead), для правила require_senior.
+// Проверяю только переданных: читать всех старших компании на каждый подбор незачем.
+func (r *Repository) GetSeniorUserIDs(userIDs []string) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT user_id FROM users
+		WHERE user_id = ANY($1::text[]) AND seniority IN ('senior', 'lead')
+		ORDER BY user_id
+	`, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seniors := make([]string, 0)
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		seniors = append(seniors, userID)
+	}
+	return seniors, rows.Err()
+}
+
+// Digest
+
+// SetDigestPreferences - сохраняю почту и согласие на сводку. При включении считаю, что сводка
+// только что ушла: иначе подписавшийся днём сразу получил бы сводку за уже прошедшее утро.
+func (r *Repository) SetDigestPreferences(userID, email string, enabled bool, now time.Time) error {
+	result, err := r.db.Exec(`
+		UPDATE users
+		SET email = NULLIF($2::text, ''),
+			digest_last_sent_at = CASE WHEN $3::boolean AND NOT digest_enabled THEN $4::timestamp ELSE digest_last_sent_at END,
+			digest_enabled = $3::boolean,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1::text
+	`, userID, email, enabled, now)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+func (r *Repository) GetDigestPreferences(userID string) (*models.DigestPreferences, error) {
+	prefs := &models.DigestPreferences{UserID: userID}
+	var lastSentAt sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT COALESCE(email, ''), digest_enabled, digest_last_sent_at
+		FROM users
+		WHERE user_id = $1::text
+	`, userID).Scan(&prefs.Email, &prefs.DigestEnabled, &lastSentAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lastSentAt.Valid {
+		prefs.LastSentAt = &lastSentAt.Time
+	}
+	return prefs, nil
+}
+
+// GetDueDigestRecipients - активные пользователи со сводкой, которым её ещё не отправляли после dueAt
+func (r *Repository) GetDueDigestRecipients(dueAt time.Time) ([]*models.DigestPreferences, error) {
+	rows, err := r.db.Query(`
+		SELECT user_id, email, digest_enabled, digest_last_sent_at
+		FROM users
+		WHERE is_active
+			AND digest_enabled
+			AND COALESCE(email, '') <> ''
+			AND (digest_last_sent_at IS NULL OR digest_last_sent_at < $1::timestamp)
+		ORDER BY user_id
+	`, dueAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipients := make([]*models.DigestPreferences, 0)
+	for rows.Next() {
+		prefs := &models.DigestPreferences{}
+		var lastSentAt sql.NullTime
+		if err := rows.Scan(&prefs.UserID, &prefs.Email, &prefs.DigestEnabled, &lastSentAt); err != nil {
+			return nil, err
+		}
+		if lastSentAt.Valid {
+			prefs.LastSentAt = &lastSentAt.Time
+		}
+		recipients = append(recipients, prefs)
+	}
+	return recipients, rows.Err()
+}
+
+// ClaimDigest - помечаю сводку отправленной в sentAt, если её ещё никто не отправил после dueAt.
+// false - её уже забрал другой экземпляр сервиса.
+func (r *Repository) ClaimDigest(userID string, dueAt, sentAt time.Time) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE users
+		SET digest_last_sent_at = $3::timestamp
+		WHERE user_id = $1::text
+			AND (digest_last_sent_at IS NULL OR digest_last_sent_at < $2::timestamp)
+	`, userID, dueAt, sentAt)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// RestoreDigestSentAt - откатываю ClaimDigest, если письмо так и не ушло
+func (r *Repository) RestoreDigestSentAt(userID string, lastSentAt *time.Time) error {
+	_, err := r.db.Exec(`
+		UPDATE users SET digest_last_sent_at = $2::timestamp WHERE user_id = $1::text
+	`, userID, lastSentAt)
+	return err
+}
+
 // GetActiveUsersByTeam - получает список активных пользователей из команды,
 // не включая одного конкретного пользователя (обычно это автор PR).
+// FOR SHARE нужен внутри транзакции: пока я назначаю кандидата, его нельзя деактивировать.
 func (r *Repository) GetActiveUsersByTeam(teamName string, excludeUserID string) ([]*models.User, error) {
 	rows, err := r.db.Query(`
-		SELECT user_id, username, team_name, is_active 
+		SELECT user_id, username, team_name, is_active, seniority, skills
 		FROM users 
 		WHERE team_name = $1 AND is_active = true AND user_id != $2
 		ORDER BY user_id
+		FOR SHARE
 	`, teamName, excludeUserID)
 	if err != nil {
 		return nil, err
@@ -137,7 +435,7 @@ func (r *Repository) GetActiveUsersByTeam(teamName string, excludeUserID string)
 	var users []*models.User
 	for rows.Next() {
 		user := &models.User{}
-		if err := rows.Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive); err != nil {
+		if err := rows.Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive, &user.Seniority, pq.Array(&user.Skills)); err != nil {
 			return nil, err
 		}
 		users = append(users, user)
@@ -145,6 +443,55 @@ func (r *Repository) GetActiveUsersByTeam(teamName string, excludeUserID string)
 	return users, nil
 }
 
+// GetUsersByIDs - пользователи по списку ID одним запросом. Кого нет - просто не будет в ответе.
+func (r *Repository) GetUsersByIDs(userIDs []string) (map[string]*models.User, error) {
+	rows, err := r.db.Query(`
+		SELECT user_id, username, team_name, is_active, seniority, skills
+		FROM users
+		WHERE user_id = ANY($1::text[])
+	`, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make(map[string]*models.User)
+	for rows.Next() {
+		user := &models.User{}
+		if err := rows.Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive, &user.Seniority, pq.Array(&user.Skills)); err != nil {
+			return nil, err
+		}
+		users[user.UserID] = user
+	}
+	return users, rows.Err()
+}
+
+// GetActiveUsersByTeams - активные участники сразу нескольких команд, сгруппированные по команде.
+// FOR SHARE по той же причине, что и в GetActiveUsersByTeam.
+func (r *Repository) GetActiveUsersByTeams(teamNames []string) (map[string][]*models.User, error) {
+	rows, err := r.db.Query(`
+		SELECT user_id, username, team_name, is_active, seniority, skills
+		FROM users
+		WHERE team_name = ANY($1::text[]) AND is_active = true
+		ORDER BY team_name, user_id
+		FOR SHARE
+	`, pq.Array(teamNames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make(map[string][]*models.User)
+	for rows.Next() {
+		user := &models.User{}
+		if err := rows.Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive, &user.Seniority, pq.Array(&user.Skills)); err != nil {
+			return nil, err
+		}
+		users[user.TeamName] = append(users[user.TeamName], user)
+	}
+	return users, rows.Err()
+}
+
 func (r *Repository) GetUserTeam(userID string) (string, error) {
 	var teamName string
 	err := r.db.QueryRow("SELECT team_name FROM users WHERE user_id = $1", userID).Scan(&teamName)
@@ -156,31 +503,146 @@ func (r *Repository) GetUserTeam(userID string) (string, error) {
 
 // Pull Requests
 func (r *Repository) CreatePullRequest(pr *models.PullRequest) error {
-	tx, err := r.db.Begin()
+	return r.WithTx(func(tx *Repository) error {
+		// ON CONFLICT вместо отдельной проверки: если два запроса создают один PR одновременно,
+		// второй просто ничего не вставит и получит ошибку
+		result, err := tx.db.Exec(`
+			INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status, need_more_reviewers, labels, priority, created_at)
+			VALUES ($1, $2, $3, $4, $5, COALESCE($6::text[], '{}'), COALESCE(NULLIF($7::text, ''), 'normal'), CURRENT_TIMESTAMP)
+			ON CONFLICT (pull_request_id) DO NOTHING
+		`, pr.PullRequestID, pr.PullRequestName, pr.AuthorID, pr.Status, pr.NeedMoreReviewers, pq.Array(pr.Labels), pr.Priority)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("pull request already exists")
+		}
+
+		for _, reviewerID := range pr.AssignedReviewers {
+			_, err = tx.db.Exec(`
+				INSERT INTO pull_request_reviewers (pull_request_id, reviewer_id)
+				VALUES ($1, $2)
+			`, pr.PullRequestID, reviewerID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetExistingPullRequestIDs - какие из переданных ID уже заняты, одним запросом
+func (r *Repository) GetExistingPullRequestIDs(pullRequestIDs []string) (map[string]bool, error) {
+	rows, err := r.db.Query(`
+		SELECT pull_request_id FROM pull_requests WHERE pull_request_id = ANY($1::text[])
+	`, pq.Array(pullRequestIDs))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer tx.Rollback()
+	defer rows.Close()
 
-	_, err = tx.Exec(`
-		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status, need_more_reviewers, created_at)
-		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
-	`, pr.PullRequestID, pr.PullRequestName, pr.AuthorID, pr.Status, pr.NeedMoreReviewers)
-	if err != nil {
-		return err
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var prID string
+		if err := rows.Scan(&prID); err != nil {
+			return nil, err
+		}
+		existing[prID] = true
 	}
+	return existing, rows.Err()
+}
 
-	for _, reviewerID := range pr.AssignedReviewers {
-		_, err = tx.Exec(`
-			INSERT INTO pull_request_reviewers (pull_request_id, reviewer_id)
-			VALUES ($1, $2)
-		`, pr.PullRequestID, reviewerID)
+// BulkCreatePullRequests - вставляю пачку PR и их ревьюеров двумя запросами через unnest.
+// Как и в CreatePullRequest, дубликаты не падают, а просто не вставляются:
+// возвращаю время создания только для реально вставленных PR.
+func (r *Repository) BulkCreatePullRequests(prs []*models.PullRequest) (map[string]time.Time, error) {
+	created := make(map[string]time.Time)
+	if len(prs) == 0 {
+		return created, nil
+	}
+
+	err := r.WithTx(func(tx *Repository) error {
+		ids := make([]string, len(prs))
+		names := make([]string, len(prs))
+		authors := make([]string, len(prs))
+		statuses := make([]string, len(prs))
+		needMore := make([]bool, len(prs))
+		// Массив массивов unnest развернёт в плоский список, поэтому метки каждого PR передаю JSON-строкой
+		labels := make([]string, len(prs))
+		priorities := make([]string, len(prs))
+		for i, pr := range prs {
+			ids[i] = pr.PullRequestID
+			names[i] = pr.PullRequestName
+			authors[i] = pr.AuthorID
+			statuses[i] = string(pr.Status)
+			needMore[i] = pr.NeedMoreReviewers
+			prLabels := pr.Labels
+			if prLabels == nil {
+				prLabels = []string{}
+			}
+			encoded, err := json.Marshal(prLabels)
+			if err != nil {
+				return err
+			}
+			labels[i] = string(encoded)
+			priorities[i] = string(pr.Priority)
+		}
+
+		rows, err := tx.db.Query(`
+			INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status, need_more_reviewers, labels, priority, created_at)
+			SELECT id, name, author_id, status, need_more,
+				ARRAY(SELECT jsonb_array_elements_text(labels::jsonb)), COALESCE(NULLIF(priority, ''), 'normal'), CURRENT_TIMESTAMP
+			FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::boolean[], $6::text[], $7::text[])
+				AS t(id, name, author_id, status, need_more, labels, priority)
+			ON CONFLICT (pull_request_id) DO NOTHING
+			RETURNING pull_request_id, created_at
+		`, pq.Array(ids), pq.Array(names), pq.Array(authors), pq.Array(statuses), pq.Array(needMore), pq.Array(labels), pq.Array(priorities))
 		if err != nil {
 			return err
 		}
-	}
+		for rows.Next() {
+			var prID string
+			var createdAt time.Time
+			if err := rows.Scan(&prID, &createdAt); err != nil {
+				rows.Close()
+				return err
+			}
+			created[prID] = createdAt
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		var reviewPRs, reviewers []string
+		for _, pr := range prs {
+			if _, ok := created[pr.PullRequestID]; !ok {
+				continue
+			}
+			for _, reviewerID := range pr.AssignedReviewers {
+				reviewPRs = append(reviewPRs, pr.PullRequestID)
+				reviewers = append(reviewers, reviewerID)
+			}
+		}
+		if len(reviewers) == 0 {
+			return nil
+		}
 
-	return tx.Commit()
+		_, err = tx.db.Exec(`
+			INSERT INTO pull_request_reviewers (pull_request_id, reviewer_id)
+			SELECT * FROM unnest($1::text[], $2::text[])
+		`, pq.Array(reviewPRs), pq.Array(reviewers))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
 }
 
 func (r *Repository) PullRequestExists(pullRequestID string) (bool, error) {
@@ -189,13 +651,30 @@ func (r *Repository) PullRequestExists(pullRequestID string) (bool, error) {
 	return exists, err
 }
 
+// LockPullRequest - блокирую строку PR до конца транзакции (SELECT ... FOR UPDATE).
+// Все решения про ревьюеров принимаю только после этой блокировки, поэтому
+// два параллельных переназначения на одном PR выполняются строго по очереди.
+func (r *Repository) LockPullRequest(pullRequestID string) error {
+	var id string
+	err := r.db.QueryRow(`
+		SELECT pull_request_id FROM pull_requests WHERE pull_request_id = $1 FOR UPDATE
+	`, pullRequestID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("pull request not found")
+	}
+	return err
+}
+
 func (r *Repository) GetPullRequest(pullRequestID string) (*models.PullRequest, error) {
 	pr := &models.PullRequest{}
-	var createdAt, mergedAt sql.NullTime
+	var createdAt, mergedAt, closedAt sql.NullTime
 	var needMoreReviewers bool
+	var labels pq.StringArray
+	var lastActivityAt time.Time
 
 	err := r.db.QueryRow(`
-		SELECT pull_request_id, pull_request_name, author_id, status, need_more_reviewers, created_at, merged_at
+		SELECT pull_request_id, pull_request_name, author_id, status, need_more_reviewers, created_at, merged_at, version,
+			closed_at, labels, last_activity_at, priority, description, url, target_branch
 		FROM pull_requests
 		WHERE pull_request_id = $1
 	`, pullRequestID).Scan(
@@ -206,6 +685,14 @@ func (r *Repository) GetPullRequest(pullRequestID string) (*models.PullRequest,
 		&needMoreReviewers,
 		&createdAt,
 		&mergedAt,
+		&pr.Version,
+		&closedAt,
+		&labels,
+		&lastActivityAt,
+		&pr.Priority,
+		&pr.Description,
+		&pr.URL,
+		&pr.TargetBranch,
 	)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("pull request not found")
@@ -220,7 +707,12 @@ func (r *Repository) GetPullRequest(pullRequestID string) (*models.PullRequest,
 	if mergedAt.Valid {
 		pr.MergedAt = &mergedAt.Time
 	}
+	if closedAt.Valid {
+		pr.ClosedAt = &closedAt.Time
+	}
 	pr.NeedMoreReviewers = needMoreReviewers
+	pr.Labels = []string(labels)
+	pr.LastActivityAt = &lastActivityAt
 
 	rows, err := r.db.Query(`
 		SELECT reviewer_id 
@@ -244,10 +736,22 @@ func (r *Repository) GetPullRequest(pullRequestID string) (*models.PullRequest,
 	return pr, nil
 }
 
+// UpdatePullRequest - сохраняю редактируемые поля PR, поднимаю версию и отмечаю активность
+func (r *Repository) UpdatePullRequest(pr *models.PullRequest) error {
+	_, err := r.db.Exec(`
+		UPDATE pull_requests
+		SET pull_request_name = $2::text, description = $3::text, url = $4::text, target_branch = $5::text,
+			labels = COALESCE($6::text[], '{}'), priority = $7::text,
+			version = version + 1, last_activity_at = CURRENT_TIMESTAMP
+		WHERE pull_request_id = $1::text
+	`, pr.PullRequestID, pr.PullRequestName, pr.Description, pr.URL, pr.TargetBranch, pq.Array(pr.Labels), pr.Priority)
+	return err
+}
+
 func (r *Repository) MergePullRequest(pullRequestID string) error {
 	result, err := r.db.Exec(`
 		UPDATE pull_requests 
-		SET status = 'MERGED', merged_at = CURRENT_TIMESTAMP 
+		SET status = 'MERGED', merged_at = CURRENT_TIMESTAMP, version = version + 1, last_activity_at = CURRENT_TIMESTAMP
 		WHERE pull_request_id = $1 AND status = 'OPEN'
 	`, pullRequestID)
 	if err != nil {
@@ -272,117 +776,198 @@ func (r *Repository) MergePullRequest(pullRequestID string) error {
 }
 
 func (r *Repository) ReassignReviewer(pullRequestID string, oldReviewerID string, newReviewerID string) error {
-	tx, err := r.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	// Проверяю, что старый ревьювер действительно назначен на этот PR
-	var exists bool
-	err = tx.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM pull_request_reviewers 
+	return r.WithTx(func(tx *Repository) error {
+		// Удаляю старого ревьювера. Заодно так проверяю, что он действительно был назначен,
+		// и запоминаю, когда его назначили - это нужно для истории переназначений
+		var oldAssignedAt time.Time
+		err := tx.db.QueryRow(`
+			DELETE FROM pull_request_reviewers 
 			WHERE pull_request_id = $1 AND reviewer_id = $2
-		)
-	`, pullRequestID, oldReviewerID).Scan(&exists)
-	if err != nil {
-		return err
-	}
-	if !exists {
-		return fmt.Errorf("reviewer is not assigned to this PR")
-	}
-
-	// Удаляю старого ревьювера
-	_, err = tx.Exec(`
-		DELETE FROM pull_request_reviewers 
-		WHERE pull_request_id = $1 AND reviewer_id = $2
-	`, pullRequestID, oldReviewerID)
-	if err != nil {
-		return err
-	}
+			RETURNING assigned_at
+		`, pullRequestID, oldReviewerID).Scan(&oldAssignedAt)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("reviewer is not assigned to this PR")
+		}
+		if err != nil {
+			return err
+		}
 
-	// Добавляю нового ревьювера
-	_, err = tx.Exec(`
-		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_id)
-		VALUES ($1, $2)
-	`, pullRequestID, newReviewerID)
-	if err != nil {
+		// Добавляю нового ревьювера
+		_, err = tx.db.Exec(`
+			INSERT INTO pull_request_reviewers (pull_request_id, reviewer_id)
+			VALUES ($1, $2)
+		`, pullRequestID, newReviewerID)
+		if err != nil {
+			return err
+		}
+
+		// Пишу переназначение в историю для статистики
+		_, err = tx.db.Exec(`
+			INSERT INTO reviewer_reassignments (pull_request_id, old_reviewer_id, new_reviewer_id, old_assigned_at)
+			VALUES ($1, $2, $3, $4)
+		`, pullRequestID, oldReviewerID, newReviewerID, oldAssignedAt)
+		if err != nil {
+			return err
+		}
+
+		// Состав ревьюеров поменялся - это новая версия PR
+		_, err = tx.db.Exec(`
+			UPDATE pull_requests SET version = version + 1, last_activity_at = CURRENT_TIMESTAMP WHERE pull_request_id = $1
+		`, pullRequestID)
 		return err
+	})
+}
+
+// CreateReviewAction - записываю действие ревьюера на PR
+func (r *Repository) CreateReviewAction(pullRequestID, reviewerID string, verdict models.ReviewVerdict) (*models.ReviewAction, error) {
+	action := &models.ReviewAction{PullRequestID: pullRequestID, ReviewerID: reviewerID, Verdict: verdict}
+	err := r.db.QueryRow(`
+		INSERT INTO review_actions (pull_request_id, reviewer_id, verdict)
+		VALUES ($1, $2, $3)
+		RETURNING created_at
+	`, pullRequestID, reviewerID, verdict).Scan(&action.CreatedAt)
+	if err != nil {
+		return nil, err
 	}
 
-	return tx.Commit()
+	// Ревью - тоже активность на PR, версию при этом не поднимаю: сам PR не менялся
+	_, err = r.db.Exec(`
+		UPDATE pull_requests SET last_activity_at = CURRENT_TIMESTAMP WHERE pull_request_id = $1
+	`, pullRequestID)
+	if err != nil {
+		return nil, err
+	}
+	return action, nil
 }
 
-func (r *Repository) GetPullRequestsByReviewer(reviewerID string) ([]*models.PullRequestShort, error) {
+func (r *Repository) GetPullRequestsByReviewer(reviewerID string, filter models.PullRequestFilter) ([]*models.PullRequestShort, error) {
+	var prs []*models.PullRequestShort
+	err := r.StreamPullRequestsByReviewer(reviewerID, filter, func(pr *models.PullRequestShort) error {
+		prs = append(prs, pr)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prs, nil
+}
+
+// StreamPullRequestsByReviewer - то же, что GetPullRequestsByReviewer, но отдаю строки по одной
+// прямо из курсора, не собирая слайс. Нужно для выгрузки в CSV/NDJSON.
+// Из фильтра учитываю статус, приоритет и метку.
+func (r *Repository) StreamPullRequestsByReviewer(reviewerID string, filter models.PullRequestFilter, fn func(pr *models.PullRequestShort) error) error {
 	rows, err := r.db.Query(`
-		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status
+		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status, pr.priority, pr.labels
 		FROM pull_requests pr
 		INNER JOIN pull_request_reviewers prr ON pr.pull_request_id = prr.pull_request_id
 		WHERE prr.reviewer_id = $1
+			AND ($2::text = '' OR pr.status = $2::text)
+			AND ($3::text = '' OR pr.priority = $3::text)
+			AND ($4::text = '' OR $4::text = ANY(pr.labels))
 		ORDER BY pr.created_at DESC
-	`, reviewerID)
+	`, reviewerID, filter.Status, filter.Priority, filter.Label)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var prs []*models.PullRequestShort
 	for rows.Next() {
 		pr := &models.PullRequestShort{}
-		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status); err != nil {
-			return nil, err
+		var labels pq.StringArray
+		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status, &pr.Priority, &labels); err != nil {
+			return err
+		}
+		pr.Labels = []string(labels)
+		if err := fn(pr); err != nil {
+			return err
 		}
-		prs = append(prs, pr)
 	}
-	return prs, nil
+	return rows.Err()
 }
 
-// Statistics - статистика по пользователям
-func (r *Repository) GetUserReviewStats() ([]*models.UserReviewStats, error) {
+// StreamPullRequests - список PR с ревьюерами по фильтру, по одной строке из курсора.
+// Команда в фильтре - команда автора.
+func (r *Repository) StreamPullRequests(filter models.PullRequestFilter, fn func(pr *models.PullRequest) error) error {
 	rows, err := r.db.Query(`
-		SELECT 
-			u.user_id,
-			u.username,
-			COUNT(prr.reviewer_id) as total_assignments,
-			COUNT(CASE WHEN pr.status = 'OPEN' THEN 1 END) as open_assignments,
-			COUNT(CASE WHEN pr.status = 'MERGED' THEN 1 END) as merged_assignments
-		FROM users u
-		LEFT JOIN pull_request_reviewers prr ON u.user_id = prr.reviewer_id
-		LEFT JOIN pull_requests pr ON prr.pull_request_id = pr.pull_request_id
-		GROUP BY u.user_id, u.username
-		ORDER BY total_assignments DESC, u.user_id
-	`)
+		SELECT
+			pr.pull_request_id,
+			pr.pull_request_name,
+			pr.author_id,
+			pr.status,
+			pr.need_more_reviewers,
+			pr.created_at,
+			pr.merged_at,
+			pr.version,
+			pr.closed_at,
+			pr.labels,
+			pr.last_activity_at,
+			pr.priority,
+			pr.description,
+			pr.url,
+			pr.target_branch,
+			COALESCE(
+				(SELECT array_agg(prr.reviewer_id ORDER BY prr.reviewer_id)
+				FROM pull_request_reviewers prr WHERE prr.pull_request_id = pr.pull_request_id),
+				'{}'
+			)
+		FROM pull_requests pr
+		INNER JOIN users a ON a.user_id = pr.author_id
+		WHERE ($1::text = '' OR pr.status = $1::text)
+			AND ($2::text = '' OR pr.author_id = $2::text)
+			AND ($3::text = '' OR a.team_name = $3::text)
+			AND ($4::text = '' OR pr.priority = $4::text)
+			AND ($5::text = '' OR $5::text = ANY(pr.labels))
+		ORDER BY pr.created_at DESC, pr.pull_request_id
+	`, filter.Status, filter.AuthorID, filter.TeamName, filter.Priority, filter.Label)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var stats []*models.UserReviewStats
 	for rows.Next() {
-		stat := &models.UserReviewStats{}
-		if err := rows.Scan(&stat.UserID, &stat.Username, &stat.TotalAssignments, &stat.OpenAssignments, &stat.MergedAssignments); err != nil {
-			return nil, err
+		pr := &models.PullRequest{}
+		var createdAt, mergedAt, closedAt sql.NullTime
+		var reviewers, labels pq.StringArray
+		var lastActivityAt time.Time
+		err := rows.Scan(
+			&pr.PullRequestID,
+			&pr.PullRequestName,
+			&pr.AuthorID,
+			&pr.Status,
+			&pr.NeedMoreReviewers,
+			&createdAt,
+			&mergedAt,
+			&pr.Version,
+			&closedAt,
+			&labels,
+			&lastActivityAt,
+			&pr.Priority,
+			&pr.Description,
+			&pr.URL,
+			&pr.TargetBranch,
+			&reviewers,
+		)
+		if err != nil {
+			return err
 		}
-		stats = append(stats, stat)
-	}
-	return stats, nil
-}
+		if createdAt.Valid {
+			pr.CreatedAt = &createdAt.Time
+		}
+		if mergedAt.Valid {
+			pr.MergedAt = &mergedAt.Time
+		}
+		if closedAt.Valid {
+			pr.ClosedAt = &closedAt.Time
+		}
+		pr.AssignedReviewers = []string(reviewers)
+		pr.Labels = []string(labels)
+		pr.LastActivityAt = &lastActivityAt
 
-func (r *Repository) GetPRStats() (*models.PRStats, error) {
-	stats := &models.PRStats{}
-	err := r.db.QueryRow(`
-		SELECT 
-			COUNT(*) as total_prs,
-			COUNT(CASE WHEN status = 'OPEN' THEN 1 END) as open_prs,
-			COUNT(CASE WHEN status = 'MERGED' THEN 1 END) as merged_prs,
-			(SELECT COUNT(*) FROM pull_request_reviewers) as total_assignments
-		FROM pull_requests
-	`).Scan(&stats.TotalPRs, &stats.OpenPRs, &stats.MergedPRs, &stats.TotalAssignments)
-	if err != nil {
-		return nil, err
+		if err := fn(pr); err != nil {
+			return err
+		}
 	}
-	return stats, nil
+	return rows.Err()
 }
 
 // Bulk deactivation - получаю список пользователей без деактивации
@@ -431,6 +1016,144 @@ func (r *Repository) BulkDeactivateUsersByTeam(teamName string) ([]string, error
 	return userIDs, nil
 }
 
+// LockUsers - блокирую пользователей до конца транзакции, чтобы их не назначили ревьюерами,
+// пока я меняю им is_active. Порядок по user_id, чтобы параллельные операции не ловили дедлок.
+func (r *Repository) LockUsers(userIDs []string) (map[string]*models.User, error) {
+	rows, err := r.db.Query(`
+		SELECT user_id, username, team_name, is_active, seniority, skills
+		FROM users
+		WHERE user_id = ANY($1::text[])
+		ORDER BY user_id
+		FOR UPDATE
+	`, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make(map[string]*models.User)
+	for rows.Next() {
+		user := &models.User{}
+		if err := rows.Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive, &user.Seniority, pq.Array(&user.Skills)); err != nil {
+			return nil, err
+		}
+		users[user.UserID] = user
+	}
+	return users, rows.Err()
+}
+
+// GetTeamUserIDsByFilter - участники команды, подходящие под фильтр
+func (r *Repository) GetTeamUserIDsByFilter(teamName string, filter models.UserFilter) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT user_id FROM users
+		WHERE team_name = $1::text
+			AND ($2::text = '' OR username LIKE $2::text || '%')
+			AND NOT (user_id = ANY(COALESCE($3::text[], '{}')))
+		ORDER BY user_id
+	`, teamName, filter.UsernamePrefix, pq.Array(filter.ExcludeUserIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := make([]string, 0)
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// SetUsersActive - меняю is_active сразу у списка пользователей
+func (r *Repository) SetUsersActive(userIDs []string, isActive bool) error {
+	_, err := r.db.Exec(`
+		UPDATE users
+		SET is_active = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = ANY($2::text[])
+	`, isActive, pq.Array(userIDs))
+	return err
+}
+
+// GetOpenPRsNeedingReviewers - открытые PR с needMoreReviewers, авторы которых из этих команд
+func (r *Repository) GetOpenPRsNeedingReviewers(teamNames []string) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT pr.pull_request_id
+		FROM pull_requests pr
+		INNER JOIN users a ON a.user_id = pr.author_id
+		WHERE pr.status = 'OPEN' AND pr.need_more_reviewers = true
+			AND a.team_name = ANY($1::text[])
+		ORDER BY pr.created_at, pr.pull_request_id
+	`, pq.Array(teamNames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prIDs := make([]string, 0)
+	for rows.Next() {
+		var prID string
+		if err := rows.Scan(&prID); err != nil {
+			return nil, err
+		}
+		prIDs = append(prIDs, prID)
+	}
+	return prIDs, rows.Err()
+}
+
+// AddReviewer - добавляю ревьюера на PR и пересчитываю needMoreReviewers
+func (r *Repository) AddReviewer(pullRequestID, reviewerID string) error {
+	return r.WithTx(func(tx *Repository) error {
+		_, err := tx.db.Exec(`
+			INSERT INTO pull_request_reviewers (pull_request_id, reviewer_id)
+			VALUES ($1, $2)
+		`, pullRequestID, reviewerID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.db.Exec(`
+			UPDATE pull_requests
+			SET version = version + 1,
+				last_activity_at = CURRENT_TIMESTAMP,
+				need_more_reviewers = (SELECT COUNT(*) FROM pull_request_reviewers WHERE pull_request_id = $1) < 2
+			WHERE pull_request_id = $1
+		`, pullRequestID)
+		return err
+	})
+}
+
+// RemoveReviewer - снимаю ревьюера с PR без замены и пересчитываю needMoreReviewers
+func (r *Repository) RemoveReviewer(pullRequestID, reviewerID string) error {
+	return r.WithTx(func(tx *Repository) error {
+		result, err := tx.db.Exec(`
+			DELETE FROM pull_request_reviewers
+			WHERE pull_request_id = $1 AND reviewer_id = $2
+		`, pullRequestID, reviewerID)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return fmt.Errorf("reviewer is not assigned to this PR")
+		}
+
+		_, err = tx.db.Exec(`
+			UPDATE pull_requests
+			SET version = version + 1,
+				last_activity_at = CURRENT_TIMESTAMP,
+				need_more_reviewers = (SELECT COUNT(*) FROM pull_request_reviewers WHERE pull_request_id = $1) < 2
+			WHERE pull_request_id = $1
+		`, pullRequestID)
+		return err
+	})
+}
+
 func (r *Repository) GetOpenPRsWithReviewers(reviewerIDs []string) ([]string, error) {
 	if len(reviewerIDs) == 0 {
 		return []string{}, nil
@@ -469,4 +1192,453 @@ func (r *Repository) GetOpenPRsWithReviewers(reviewerIDs []string) ([]string, er
 		prIDs = append(prIDs, prID)
 	}
 	return prIDs, nil
-}
\ No newline at end of file
+}
+
+// GetOpenReviewsByTeam - открытые ревью каждого активного участника команды.
+// Участники без ревью тоже попадают в ответ с пустым списком. Последние назначения идут первыми.
+func (r *Repository) GetOpenReviewsByTeam(teamName string) (map[string][]string, error) {
+	rows, err := r.db.Query(`
+		SELECT u.user_id, pr.pull_request_id
+		FROM users u
+		LEFT JOIN pull_request_reviewers prr ON prr.reviewer_id = u.user_id
+		LEFT JOIN pull_requests pr ON pr.pull_request_id = prr.pull_request_id AND pr.status = 'OPEN'
+		WHERE u.team_name = $1 AND u.is_active = true
+		ORDER BY u.user_id, prr.assigned_at DESC
+	`, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := make(map[string][]string)
+	for rows.Next() {
+		var userID string
+		var prID sql.NullString
+		if err := rows.Scan(&userID, &prID); err != nil {
+			return nil, err
+		}
+		if _, ok := reviews[userID]; !ok {
+			reviews[userID] = []string{}
+		}
+		if prID.Valid {
+			reviews[userID] = append(reviews[userID], prID.String)
+		}
+	}
+	return reviews, rows.Err()
+}
+
+// GetOpenReviewCounts - сколько открытых PR сейчас на ревью у каждого из пользователей.
+// Тех, у кого ревью нет, в ответе нет.
+func (r *Repository) GetOpenReviewCounts(userIDs []string) (map[string]int, error) {
+	rows, err := r.db.Query(`
+		SELECT prr.reviewer_id, COUNT(*)
+		FROM pull_request_reviewers prr
+		INNER JOIN pull_requests pr ON pr.pull_request_id = prr.pull_request_id
+		WHERE pr.status = 'OPEN' AND prr.reviewer_id = ANY($1::text[])
+		GROUP BY prr.reviewer_id
+	`, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var userID string
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, err
+		}
+		counts[userID] = count
+	}
+	return counts, rows.Err()
+}
+
+// User events
+
+// RecordUserEvent - записываю событие пользователя. Внутри транзакции оно ещё и
+// запоминается, чтобы после коммита его можно было отдать подписчикам (SetEventPublisher).
+func (r *Repository) RecordUserEvent(userID string, eventType models.UserEventType, prID string) error {
+	event := models.UserEvent{UserID: userID, Type: eventType, PullRequestID: prID}
+	err := r.db.QueryRow(`
+		INSERT INTO user_events (user_id, event_type, pull_request_id)
+		VALUES ($1::text, $2::text, $3::text)
+		RETURNING stream_seq, created_at
+	`, userID, string(eventType), prID).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if r.events != nil {
+		*r.events = append(*r.events, event)
+	}
+	return nil
+}
+
+// GetUserEventsAfter - события пользователя после позиции afterID в потоке, в порядке коммитов.
+// ID события для потока - его stream_seq, а не id строки.
+func (r *Repository) GetUserEventsAfter(userID string, afterID int64, limit int) ([]models.UserEvent, error) {
+	rows, err := r.db.Query(`
+		SELECT stream_seq, user_id, event_type, pull_request_id, created_at
+		FROM user_events
+		WHERE user_id = $1::text AND stream_seq > $2::bigint
+		ORDER BY stream_seq
+		LIMIT $3::int
+	`, userID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]models.UserEvent, 0)
+	for rows.Next() {
+		var event models.UserEvent
+		var eventType string
+		if err := rows.Scan(&event.ID, &event.UserID, &eventType, &event.PullRequestID, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		event.Type = models.UserEventType(eventType)
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// Outbox
+
+// InsertOutboxEvent - кладу событие в outbox. Вызывается внутри той же транзакции, что и изменение.
+func (r *Repository) InsertOutboxEvent(aggregateType, aggregateID string, eventType models.OutboxEventType, payload []byte) error {
+	_, err := r.db.Exec(`
+		INSERT INTO outbox_events (aggregate_type, aggregate_id, event_type, payload)
+		VALUES ($1::text, $2::text, $3::text, $4::jsonb)
+	`, aggregateType, aggregateID, string(eventType), string(payload))
+	return err
+}
+
+// ClaimOutboxEvents - беру события, готовые к отправке, и сдвигаю им next_attempt_at на lease.
+// Это одна короткая команда: строки не остаются заблокированными, пока синки отвечают, а другой
+// релей до конца аренды эти события не возьмёт. Если процесс упадёт, событие вернётся, когда аренда истечёт.
+// Для каждого агрегата беру только самое раннее неопубликованное событие: следующее
+// уйдёт, только когда опубликуют это (или признают мёртвым). Так порядок внутри PR сохраняется,
+// даже если релеев несколько, а SKIP LOCKED не даёт им взять одно и то же событие.
+func (r *Repository) ClaimOutboxEvents(limit int, lease time.Duration) ([]*models.OutboxEvent, error) {
+	rows, err := r.db.Query(`
+		WITH ready AS (
+			SELECT e.id
+			FROM outbox_events e
+			WHERE e.published_at IS NULL
+				AND e.dead_at IS NULL
+				AND e.next_attempt_at <= CURRENT_TIMESTAMP
+				AND NOT EXISTS (
+					SELECT 1 FROM outbox_events p
+					WHERE p.published_at IS NULL
+						AND p.dead_at IS NULL
+						AND p.aggregate_type = e.aggregate_type
+						AND p.aggregate_id = e.aggregate_id
+						AND p.id < e.id
+				)
+			ORDER BY e.id
+			LIMIT $1::int
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE outbox_events e
+		SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2::float8)
+		FROM ready
+		WHERE e.id = ready.id
+		RETURNING e.id, e.aggregate_type, e.aggregate_id, e.event_type, e.payload, e.created_at, e.attempts
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]*models.OutboxEvent, 0)
+	for rows.Next() {
+		event := &models.OutboxEvent{}
+		var eventType string
+		var payload []byte
+		if err := rows.Scan(&event.ID, &event.AggregateType, &event.AggregateID, &eventType, &payload, &event.CreatedAt, &event.Attempts); err != nil {
+			return nil, err
+		}
+		event.EventType = models.OutboxEventType(eventType)
+		event.Payload = payload
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// RETURNING порядок не обещает, а отправлять нужно по id
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+func (r *Repository) MarkOutboxEventPublished(id int64) error {
+	_, err := r.db.Exec(`
+		UPDATE outbox_events
+		SET published_at = CURRENT_TIMESTAMP, attempts = attempts + 1, last_error = NULL
+		WHERE id = $1::bigint
+	`, id)
+	return err
+}
+
+// MarkOutboxEventFailed - запоминаю ошибку и откладываю следующую попытку на retryAfter
+func (r *Repository) MarkOutboxEventFailed(id int64, lastError string, retryAfter time.Duration) error {
+	_, err := r.db.Exec(`
+		UPDATE outbox_events
+		SET attempts = attempts + 1,
+			last_error = $2::text,
+			next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $3::float8)
+		WHERE id = $1::bigint
+	`, id, lastError, retryAfter.Seconds())
+	return err
+}
+
+// MarkOutboxEventDead - попытки кончились: больше не отправляю и не держу за ним агрегат
+func (r *Repository) MarkOutboxEventDead(id int64, lastError string) error {
+	_, err := r.db.Exec(`
+		UPDATE outbox_events
+		SET attempts = attempts + 1, last_error = $2::text, dead_at = CURRENT_TIMESTAMP
+		WHERE id = $1::bigint
+	`, id, lastError)
+	return err
+}
+
+// DeletePublishedOutboxEvents - удаляю события, опубликованные больше olderThan назад. Мёртвые не трогаю.
+func (r *Repository) DeletePublishedOutboxEvents(olderThan time.Duration) (int64, error) {
+	result, err := r.db.Exec(`
+		DELETE FROM outbox_events
+		WHERE published_at IS NOT NULL
+			AND published_at < CURRENT_TIMESTAMP - make_interval(secs => $1::float8)
+	`, olderThan.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Snapshot
+
+// GetAllTeams - все команды с участниками одним запросом, для выгрузки
+func (r *Repository) GetAllTeams() ([]*models.Team, error) {
+	rows, err := r.db.Query(`
+		SELECT t.team_name, t.version, u.user_id, u.username, u.is_active
+		FROM teams t
+		LEFT JOIN users u ON u.team_name = t.team_name
+		ORDER BY t.team_name, u.user_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := make([]*models.Team, 0)
+	var current *models.Team
+	for rows.Next() {
+		var teamName string
+		var version int
+		var userID, username sql.NullString
+		var isActive sql.NullBool
+		if err := rows.Scan(&teamName, &version, &userID, &username, &isActive); err != nil {
+			return nil, err
+		}
+		if current == nil || current.TeamName != teamName {
+			current = &models.Team{TeamName: teamName, Version: version, Members: []models.TeamMember{}}
+			teams = append(teams, current)
+		}
+		if userID.Valid {
+			current.Members = append(current.Members, models.TeamMember{
+				UserID:   userID.String,
+				Username: username.String,
+				IsActive: isActive.Bool,
+			})
+		}
+	}
+	return teams, rows.Err()
+}
+
+// GetUserProfiles - пользователи с заполненным уровнем или навыками, для выгрузки
+func (r *Repository) GetUserProfiles() ([]*models.UserProfile, error) {
+	rows, err := r.db.Query(`
+		SELECT user_id, seniority, skills
+		FROM users
+		WHERE seniority <> '' OR cardinality(skills) > 0
+		ORDER BY user_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	profiles := make([]*models.UserProfile, 0)
+	for rows.Next() {
+		profile := &models.UserProfile{}
+		var skills pq.StringArray
+		if err := rows.Scan(&profile.UserID, &profile.Seniority, &skills); err != nil {
+			return nil, err
+		}
+		profile.Skills = []string(skills)
+		profiles = append(profiles, profile)
+	}
+	return profiles, rows.Err()
+}
+
+// GetAllDigestPreferences - подписки на сводку у всех, кто указал почту или включил сводку, для выгрузки
+func (r *Repository) GetAllDigestPreferences() ([]*models.DigestPreferences, error) {
+	rows, err := r.db.Query(`
+		SELECT user_id, COALESCE(email, ''), digest_enabled, digest_last_sent_at
+		FROM users
+		WHERE email IS NOT NULL OR digest_enabled
+		ORDER BY user_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	all := make([]*models.DigestPreferences, 0)
+	for rows.Next() {
+		prefs := &models.DigestPreferences{}
+		var lastSentAt sql.NullTime
+		if err := rows.Scan(&prefs.UserID, &prefs.Email, &prefs.DigestEnabled, &lastSentAt); err != nil {
+			return nil, err
+		}
+		if lastSentAt.Valid {
+			prefs.LastSentAt = &lastSentAt.Time
+		}
+		all = append(all, prefs)
+	}
+	return all, rows.Err()
+}
+
+// ImportPullRequest - записываю PR из выгрузки как есть: со статусом, датами и ревьюерами.
+// Если PR уже есть, перезаписываю его и поднимаю версию. Время назначения ревьюеров
+// беру из времени создания PR, точнее из выгрузки не узнать.
+func (r *Repository) ImportPullRequest(pr *models.PullRequest) error {
+	return r.WithTx(func(tx *Repository) error {
+		_, err := tx.db.Exec(`
+			INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status, need_more_reviewers, created_at, merged_at,
+				closed_at, labels, last_activity_at, priority, description, url, target_branch)
+			VALUES ($1, $2, $3, $4, $5, COALESCE($6::timestamp, CURRENT_TIMESTAMP), $7::timestamp,
+				$8::timestamp, COALESCE($9::text[], '{}'), COALESCE($10::timestamp, CURRENT_TIMESTAMP), COALESCE(NULLIF($11::text, ''), 'normal'),
+				$12::text, $13::text, $14::text)
+			ON CONFLICT (pull_request_id) DO UPDATE SET
+				pull_request_name = EXCLUDED.pull_request_name,
+				author_id = EXCLUDED.author_id,
+				status = EXCLUDED.status,
+				need_more_reviewers = EXCLUDED.need_more_reviewers,
+				created_at = EXCLUDED.created_at,
+				merged_at = EXCLUDED.merged_at,
+				closed_at = EXCLUDED.closed_at,
+				labels = EXCLUDED.labels,
+				last_activity_at = EXCLUDED.last_activity_at,
+				priority = EXCLUDED.priority,
+				description = EXCLUDED.description,
+				url = EXCLUDED.url,
+				target_branch = EXCLUDED.target_branch,
+				stale_warned_at = NULL,
+				version = pull_requests.version + 1
+		`, pr.PullRequestID, pr.PullRequestName, pr.AuthorID, pr.Status, pr.NeedMoreReviewers, pr.CreatedAt, pr.MergedAt,
+			pr.ClosedAt, pq.Array(pr.Labels), pr.LastActivityAt, pr.Priority, pr.Description, pr.URL, pr.TargetBranch)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.db.Exec("DELETE FROM pull_request_reviewers WHERE pull_request_id = $1", pr.PullRequestID)
+		if err != nil {
+			return err
+		}
+
+		for _, reviewerID := range pr.AssignedReviewers {
+			_, err = tx.db.Exec(`
+				INSERT INTO pull_request_reviewers (pull_request_id, reviewer_id, assigned_at)
+				SELECT $1, $2, created_at FROM pull_requests WHERE pull_request_id = $1
+			`, pr.PullRequestID, reviewerID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Idempotency keys
+
+// ClaimIdempotencyKey - пытаюсь занять ключ под новый запрос.
+// Получится, если ключа ещё нет или старая запись уже протухла. false - ключ кем-то занят.
+func (r *Repository) ClaimIdempotencyKey(key, route, requestHash string, ttl time.Duration) (bool, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO idempotency_keys (idempotency_key, route, request_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP + make_interval(secs => $4))
+		ON CONFLICT (idempotency_key, route) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash,
+			status_code = NULL,
+			response_body = NULL,
+			response_headers = NULL,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < CURRENT_TIMESTAMP
+	`, key, route, requestHash, int64(ttl.Seconds()))
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+func (r *Repository) GetIdempotencyRecord(key, route string) (*models.IdempotencyRecord, error) {
+	record := &models.IdempotencyRecord{Key: key, Route: route}
+	var statusCode sql.NullInt64
+	var headers []byte
+
+	err := r.db.QueryRow(`
+		SELECT request_hash, status_code, response_body, response_headers
+		FROM idempotency_keys
+		WHERE idempotency_key = $1 AND route = $2
+	`, key, route).Scan(&record.RequestHash, &statusCode, &record.ResponseBody, &headers)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("idempotency key not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode.Valid {
+		record.Completed = true
+		record.StatusCode = int(statusCode.Int64)
+	}
+	if len(headers) > 0 {
+		if err := json.Unmarshal(headers, &record.ResponseHeaders); err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
+
+func (r *Repository) SaveIdempotencyResponse(key, route string, statusCode int, body []byte, headers map[string]string) error {
+	headersJSON, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		UPDATE idempotency_keys
+		SET status_code = $3, response_body = $4, response_headers = $5
+		WHERE idempotency_key = $1 AND route = $2
+	`, key, route, statusCode, body, headersJSON)
+	return err
+}
+
+func (r *Repository) DeleteIdempotencyKey(key, route string) error {
+	_, err := r.db.Exec("DELETE FROM idempotency_keys WHERE idempotency_key = $1 AND route = $2", key, route)
+	return err
+}
+
+func (r *Repository) DeleteExpiredIdempotencyKeys() (int64, error) {
+	result, err := r.db.Exec("DELETE FROM idempotency_keys WHERE expires_at < CURRENT_TIMESTAMP")
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
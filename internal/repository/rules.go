@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"pr-reviewer-service/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// SetReviewRules - сохраняю правила подбора ревьюеров команды, старые перезаписываю
+func (r *Repository) SetReviewRules(rules *models.ReviewRules) error {
+	neverReview, err := json.Marshal(rules.NeverReview)
+	if err != nil {
+		return err
+	}
+	preferredPairs, err := json.Marshal(rules.PreferredPairs)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO team_review_rules (team_name, never_review, preferred_pairs, seniors, require_senior, match_skills)
+		VALUES ($1::text, $2::jsonb, $3::jsonb, COALESCE($4::text[], '{}'), $5::boolean, $6::boolean)
+		ON CONFLICT (team_name) DO UPDATE
+		SET never_review = EXCLUDED.never_review,
+			preferred_pairs = EXCLUDED.preferred_pairs,
+			seniors = EXCLUDED.seniors,
+			require_senior = EXCLUDED.require_senior,
+			match_skills = EXCLUDED.match_skills,
+			updated_at = CURRENT_TIMESTAMP
+	`, rules.TeamName, string(neverReview), string(preferredPairs), pq.Array(rules.Seniors), rules.RequireSenior, rules.MatchSkills)
+	return err
+}
+
+func (r *Repository) DeleteReviewRules(teamName string) error {
+	_, err := r.db.Exec("DELETE FROM team_review_rules WHERE team_name = $1::text", teamName)
+	return err
+}
+
+func (r *Repository) GetReviewRules(teamName string) (*models.ReviewRules, error) {
+	rules := &models.ReviewRules{TeamName: teamName}
+	var neverReview, preferredPairs []byte
+	var seniors pq.StringArray
+	err := r.db.QueryRow(`
+		SELECT never_review, preferred_pairs, seniors, require_senior, match_skills FROM team_review_rules WHERE team_name = $1::text
+	`, teamName).Scan(&neverReview, &preferredPairs, &seniors, &rules.RequireSenior, &rules.MatchSkills)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("review rules not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(neverReview, &rules.NeverReview); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(preferredPairs, &rules.PreferredPairs); err != nil {
+		return nil, err
+	}
+	rules.Seniors = []string(seniors)
+	return rules, nil
+}
+
+// GetAllReviewRules - правила всех команд, для выгрузки
+func (r *Repository) GetAllReviewRules() ([]*models.ReviewRules, error) {
+	rows, err := r.db.Query(`
+		SELECT team_name, never_review, preferred_pairs, seniors, require_senior, match_skills
+		FROM team_review_rules
+		ORDER BY team_name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	all := make([]*models.ReviewRules, 0)
+	for rows.Next() {
+		rules := &models.ReviewRules{}
+		var neverReview, preferredPairs []byte
+		var seniors pq.StringArray
+		if err := rows.Scan(&rules.TeamName, &neverReview, &preferredPairs, &seniors, &rules.RequireSenior, &rules.MatchSkills); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(neverReview, &rules.NeverReview); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(preferredPairs, &rules.PreferredPairs); err != nil {
+			return nil, err
+		}
+		rules.Seniors = []string(seniors)
+		all = append(all, rules)
+	}
+	return all, rows.Err()
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"pr-reviewer-service/internal/models"
+)
+
+// SetTeamSLA - сохраняю SLA команды, старое перезаписываю
+func (r *Repository) SetTeamSLA(sla *models.TeamSLA) error {
+	_, err := r.db.Exec(`
+		INSERT INTO team_review_sla (team_name, first_review_hours, action)
+		VALUES ($1::text, $2::float8, $3::text)
+		ON CONFLICT (team_name) DO UPDATE
+		SET first_review_hours = EXCLUDED.first_review_hours,
+			action = EXCLUDED.action,
+			updated_at = CURRENT_TIMESTAMP
+	`, sla.TeamName, sla.FirstReviewHours, sla.Action)
+	return err
+}
+
+func (r *Repository) DeleteTeamSLA(teamName string) error {
+	_, err := r.db.Exec("DELETE FROM team_review_sla WHERE team_name = $1::text", teamName)
+	return err
+}
+
+func (r *Repository) GetTeamSLA(teamName string) (*models.TeamSLA, error) {
+	sla := &models.TeamSLA{TeamName: teamName}
+	err := r.db.QueryRow(`
+		SELECT first_review_hours, action FROM team_review_sla WHERE team_name = $1::text
+	`, teamName).Scan(&sla.FirstReviewHours, &sla.Action)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("sla not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sla, nil
+}
+
+// GetAllTeamSLAs - SLA всех команд, для выгрузки
+func (r *Repository) GetAllTeamSLAs() ([]*models.TeamSLA, error) {
+	rows, err := r.db.Query(`
+		SELECT team_name, first_review_hours, action FROM team_review_sla ORDER BY team_name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slas := make([]*models.TeamSLA, 0)
+	for rows.Next() {
+		sla := &models.TeamSLA{}
+		if err := rows.Scan(&sla.TeamName, &sla.FirstReviewHours, &sla.Action); err != nil {
+			return nil, err
+		}
+		slas = append(slas, sla)
+	}
+	return slas, rows.Err()
+}
+
+// GetSLABreaches - открытые PR, которые так и не получили ни одного действия ревьюера
+// за first_review_hours команды автора. Отсчёт идёт от создания PR или от последней эскалации,
+// чтобы один и тот же PR не эскалировался на каждом проходе.
+// Пустой prID - все PR, иначе проверяю только его.
+func (r *Repository) GetSLABreaches(prID string) ([]*models.SLABreach, error) {
+	rows, err := r.db.Query(`
+		SELECT pr.pull_request_id, sla.team_name, sla.first_review_hours, sla.action
+		FROM pull_requests pr
+		INNER JOIN users a ON a.user_id = pr.author_id
+		INNER JOIN team_review_sla sla ON sla.team_name = a.team_name
+		WHERE pr.status = 'OPEN'
+			AND ($1::text = '' OR pr.pull_request_id = $1::text)
+			AND NOT EXISTS (
+				SELECT 1 FROM review_actions ra WHERE ra.pull_request_id = pr.pull_request_id
+			)
+			AND GREATEST(
+				pr.created_at,
+				(SELECT MAX(e.created_at) FROM review_escalations e WHERE e.pull_request_id = pr.pull_request_id)
+			) < CURRENT_TIMESTAMP - make_interval(secs => sla.first_review_hours * 3600)
+		ORDER BY pr.created_at, pr.pull_request_id
+	`, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	breaches := make([]*models.SLABreach, 0)
+	for rows.Next() {
+		breach := &models.SLABreach{}
+		if err := rows.Scan(&breach.PullRequestID, &breach.TeamName, &breach.FirstReviewHours, &breach.Action); err != nil {
+			return nil, err
+		}
+		breaches = append(breaches, breach)
+	}
+	return breaches, rows.Err()
+}
+
+// GetStaleReviewers - ревьюеры PR, назначенные дольше hours часов назад и ничего на нём не сделавшие
+func (r *Repository) GetStaleReviewers(prID string, hours float64) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT prr.reviewer_id
+		FROM pull_request_reviewers prr
+		WHERE prr.pull_request_id = $1::text
+			AND prr.assigned_at < CURRENT_TIMESTAMP - make_interval(secs => $2::float8 * 3600)
+			AND NOT EXISTS (
+				SELECT 1 FROM review_actions ra
+				WHERE ra.pull_request_id = prr.pull_request_id AND ra.reviewer_id = prr.reviewer_id
+			)
+		ORDER BY prr.reviewer_id
+	`, prID, hours)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviewers := make([]string, 0)
+	for rows.Next() {
+		var reviewerID string
+		if err := rows.Scan(&reviewerID); err != nil {
+			return nil, err
+		}
+		reviewers = append(reviewers, reviewerID)
+	}
+	return reviewers, rows.Err()
+}
+
+func (r *Repository) CreateEscalation(escalation *models.Escalation) error {
+	return r.db.QueryRow(`
+		INSERT INTO review_escalations (pull_request_id, action, reviewer_id, new_reviewer_id, reason, error)
+		VALUES ($1::text, $2::text, NULLIF($3::text, ''), NULLIF($4::text, ''), $5::text, NULLIF($6::text, ''))
+		RETURNING id, created_at
+	`, escalation.PullRequestID, escalation.Action, escalation.ReviewerID, escalation.NewReviewerID,
+		escalation.Reason, escalation.Error).Scan(&escalation.ID, &escalation.CreatedAt)
+}
+
+// GetEscalations - эскалации по PR, по порядку
+func (r *Repository) GetEscalations(prID string) ([]*models.Escalation, error) {
+	rows, err := r.db.Query(`
+		SELECT id, pull_request_id, action, COALESCE(reviewer_id, ''), COALESCE(new_reviewer_id, ''),
+			reason, COALESCE(error, ''), created_at
+		FROM review_escalations
+		WHERE pull_request_id = $1::text
+		ORDER BY id
+	`, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	escalations := make([]*models.Escalation, 0)
+	for rows.Next() {
+		e := &models.Escalation{}
+		if err := rows.Scan(&e.ID, &e.PullRequestID, &e.Action, &e.ReviewerID, &e.NewReviewerID,
+			&e.Reason, &e.Error, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		escalations = append(escalations, e)
+	}
+	return escalations, rows.Err()
+}
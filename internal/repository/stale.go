@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"pr-reviewer-service/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// SetStalePolicy - сохраняю политику команды для брошенных PR, старую перезаписываю
+func (r *Repository) SetStalePolicy(policy *models.StalePolicy) error {
+	_, err := r.db.Exec(`
+		INSERT INTO team_stale_policy (team_name, warn_after_days, close_after_days, excluded_labels)
+		VALUES ($1::text, $2::integer, $3::integer, COALESCE($4::text[], '{}'))
+		ON CONFLICT (team_name) DO UPDATE
+		SET warn_after_days = EXCLUDED.warn_after_days,
+			close_after_days = EXCLUDED.close_after_days,
+			excluded_labels = EXCLUDED.excluded_labels,
+			updated_at = CURRENT_TIMESTAMP
+	`, policy.TeamName, policy.WarnAfterDays, policy.CloseAfterDays, pq.Array(policy.ExcludedLabels))
+	return err
+}
+
+func (r *Repository) DeleteStalePolicy(teamName string) error {
+	_, err := r.db.Exec("DELETE FROM team_stale_policy WHERE team_name = $1::text", teamName)
+	return err
+}
+
+func (r *Repository) GetStalePolicy(teamName string) (*models.StalePolicy, error) {
+	policy := &models.StalePolicy{TeamName: teamName}
+	var labels pq.StringArray
+	err := r.db.QueryRow(`
+		SELECT warn_after_days, close_after_days, excluded_labels FROM team_stale_policy WHERE team_name = $1::text
+	`, teamName).Scan(&policy.WarnAfterDays, &policy.CloseAfterDays, &labels)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("stale policy not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	policy.ExcludedLabels = []string(labels)
+	return policy, nil
+}
+
+// GetAllStalePolicies - политики брошенных PR всех команд, для выгрузки
+func (r *Repository) GetAllStalePolicies() ([]*models.StalePolicy, error) {
+	rows, err := r.db.Query(`
+		SELECT team_name, warn_after_days, close_after_days, excluded_labels FROM team_stale_policy ORDER BY team_name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := make([]*models.StalePolicy, 0)
+	for rows.Next() {
+		policy := &models.StalePolicy{}
+		var labels pq.StringArray
+		if err := rows.Scan(&policy.TeamName, &policy.WarnAfterDays, &policy.CloseAfterDays, &labels); err != nil {
+			return nil, err
+		}
+		policy.ExcludedLabels = []string(labels)
+		policies = append(policies, policy)
+	}
+	return policies, rows.Err()
+}
+
+// GetStalePullRequests - открытые PR без активности дольше warn_after_days команды автора,
+// кроме PR с исключёнными метками. Самые заброшенные - первыми.
+// Пустые teamName и prID - без фильтра.
+func (r *Repository) GetStalePullRequests(teamName, prID string) ([]*models.StalePullRequest, error) {
+	rows, err := r.db.Query(`
+		SELECT
+			pr.pull_request_id,
+			pr.pull_request_name,
+			pr.author_id,
+			p.team_name,
+			pr.labels,
+			pr.last_activity_at,
+			EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - pr.last_activity_at) / 86400,
+			-- Предупреждение считается, только если после него ничего не происходило
+			CASE WHEN pr.stale_warned_at >= pr.last_activity_at THEN pr.stale_warned_at END,
+			CASE WHEN p.close_after_days > 0 THEN pr.last_activity_at + make_interval(days => p.close_after_days) END
+		FROM pull_requests pr
+		INNER JOIN users a ON a.user_id = pr.author_id
+		INNER JOIN team_stale_policy p ON p.team_name = a.team_name
+		WHERE pr.status = 'OPEN'
+			AND ($1::text = '' OR p.team_name = $1::text)
+			AND ($2::text = '' OR pr.pull_request_id = $2::text)
+			AND pr.last_activity_at < CURRENT_TIMESTAMP - make_interval(days => p.warn_after_days)
+			AND NOT (pr.labels && p.excluded_labels)
+		ORDER BY pr.last_activity_at, pr.pull_request_id
+	`, teamName, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stale := make([]*models.StalePullRequest, 0)
+	for rows.Next() {
+		pr := &models.StalePullRequest{}
+		var labels pq.StringArray
+		var warnedAt, closesAt sql.NullTime
+		err := rows.Scan(
+			&pr.PullRequestID,
+			&pr.PullRequestName,
+			&pr.AuthorID,
+			&pr.TeamName,
+			&labels,
+			&pr.LastActivityAt,
+			&pr.IdleDays,
+			&warnedAt,
+			&closesAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		pr.Labels = []string(labels)
+		if warnedAt.Valid {
+			pr.WarnedAt = &warnedAt.Time
+		}
+		if closesAt.Valid {
+			pr.ClosesAt = &closesAt.Time
+		}
+		stale = append(stale, pr)
+	}
+	return stale, rows.Err()
+}
+
+// MarkStaleWarned - запоминаю, что автора и ревьюеров уже предупредили. Активностью это не считается.
+func (r *Repository) MarkStaleWarned(prID string) error {
+	_, err := r.db.Exec(`
+		UPDATE pull_requests SET stale_warned_at = CURRENT_TIMESTAMP WHERE pull_request_id = $1::text
+	`, prID)
+	return err
+}
+
+// ClosePullRequest - закрываю открытый PR без мержа. Ревьюеров оставляю для истории.
+func (r *Repository) ClosePullRequest(prID string) error {
+	result, err := r.db.Exec(`
+		UPDATE pull_requests
+		SET status = 'CLOSED', closed_at = CURRENT_TIMESTAMP, version = version + 1, last_activity_at = CURRENT_TIMESTAMP
+		WHERE pull_request_id = $1::text AND status = 'OPEN'
+	`, prID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("pull request not found")
+	}
+	return nil
+}
@@ -0,0 +1,523 @@
+package repository
+
+import (
+	"pr-reviewer-service/internal/models"
+	"time"
+)
+
+// allAssignmentsSQL - все назначения за всю историю: текущие плюс те, что потом переназначили.
+// У переназначенных время назначения лежит в reviewer_reassignments.old_assigned_at.
+const allAssignmentsSQL = `
+	SELECT pull_request_id, reviewer_id, assigned_at FROM pull_request_reviewers
+	UNION ALL
+	SELECT pull_request_id, old_reviewer_id, old_assigned_at FROM reviewer_reassignments
+`
+
+// Statistics - статистика по пользователям.
+// Фильтр по датам смотрит на время создания PR, фильтр по команде - на команду ревьюера.
+func (r *Repository) GetUserReviewStats(filter models.StatisticsFilter) ([]*models.UserReviewStats, error) {
+	var stats []*models.UserReviewStats
+	err := r.StreamUserReviewStats(filter, func(stat *models.UserReviewStats) error {
+		stats = append(stats, stat)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// StreamUserReviewStats - статистика по пользователям построчно из курсора, для выгрузки
+func (r *Repository) StreamUserReviewStats(filter models.StatisticsFilter, fn func(stat *models.UserReviewStats) error) error {
+	rows, err := r.db.Query(`
+		SELECT
+			u.user_id,
+			u.username,
+			COUNT(pr.pull_request_id) as total_assignments,
+			COUNT(CASE WHEN pr.status = 'OPEN' THEN 1 END) as open_assignments,
+			COUNT(CASE WHEN pr.status = 'MERGED' THEN 1 END) as merged_assignments,
+			(
+				SELECT COUNT(*) FROM review_declines d
+				INNER JOIN pull_requests dpr ON dpr.pull_request_id = d.pull_request_id
+				WHERE d.reviewer_id = u.user_id
+					AND ($1::timestamp IS NULL OR dpr.created_at >= $1::timestamp)
+					AND ($2::timestamp IS NULL OR dpr.created_at < $2::timestamp)
+			) as declines
+		FROM users u
+		LEFT JOIN pull_request_reviewers prr ON u.user_id = prr.reviewer_id
+		LEFT JOIN pull_requests pr ON prr.pull_request_id = pr.pull_request_id
+			AND ($1::timestamp IS NULL OR pr.created_at >= $1::timestamp)
+			AND ($2::timestamp IS NULL OR pr.created_at < $2::timestamp)
+		WHERE $3::text = '' OR u.team_name = $3::text
+		GROUP BY u.user_id, u.username
+		ORDER BY total_assignments DESC, u.user_id
+	`, filter.From, filter.To, filter.TeamName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		stat := &models.UserReviewStats{}
+		if err := rows.Scan(&stat.UserID, &stat.Username, &stat.TotalAssignments, &stat.OpenAssignments, &stat.MergedAssignments, &stat.Declines); err != nil {
+			return err
+		}
+		if err := fn(stat); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetPRStats - общая статистика по PR. Фильтр по команде - это команда автора PR.
+func (r *Repository) GetPRStats(filter models.StatisticsFilter) (*models.PRStats, error) {
+	stats := &models.PRStats{}
+	err := r.db.QueryRow(`
+		WITH filtered AS (
+			SELECT pr.pull_request_id, pr.status
+			FROM pull_requests pr
+			INNER JOIN users a ON a.user_id = pr.author_id
+			WHERE ($1::timestamp IS NULL OR pr.created_at >= $1::timestamp)
+				AND ($2::timestamp IS NULL OR pr.created_at < $2::timestamp)
+				AND ($3::text = '' OR a.team_name = $3::text)
+		)
+		SELECT
+			COUNT(*) as total_prs,
+			COUNT(CASE WHEN status = 'OPEN' THEN 1 END) as open_prs,
+			COUNT(CASE WHEN status = 'MERGED' THEN 1 END) as merged_prs,
+			(
+				SELECT COUNT(*) FROM pull_request_reviewers prr
+				WHERE prr.pull_request_id IN (SELECT pull_request_id FROM filtered)
+			) as total_assignments,
+			(
+				SELECT COUNT(*) FROM review_declines d
+				WHERE d.pull_request_id IN (SELECT pull_request_id FROM filtered)
+			) as total_declines
+		FROM filtered
+	`, filter.From, filter.To, filter.TeamName).Scan(&stats.TotalPRs, &stats.OpenPRs, &stats.MergedPRs, &stats.TotalAssignments, &stats.TotalDeclines)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetTimeSeries - бакеты по дням или неделям: сколько PR создано и смержено,
+// сколько было назначений и переназначений. Пустые бакеты тоже возвращаю (generate_series),
+// чтобы на графике не было дыр. Фильтр по команде - это команда автора PR.
+func (r *Repository) GetTimeSeries(interval string, from, to time.Time, teamName string) ([]models.TimeSeriesBucket, error) {
+	rows, err := r.db.Query(`
+		WITH buckets AS (
+			SELECT generate_series(
+				date_trunc($1::text, $2::timestamp),
+				$3::timestamp - INTERVAL '1 microsecond',
+				('1 ' || $1::text)::interval
+			) AS bucket_start
+		),
+		team_prs AS (
+			SELECT pr.pull_request_id, pr.created_at, pr.merged_at
+			FROM pull_requests pr
+			INNER JOIN users a ON a.user_id = pr.author_id
+			WHERE $4::text = '' OR a.team_name = $4::text
+		),
+		created AS (
+			SELECT date_trunc($1::text, created_at) AS bucket_start, COUNT(*) AS cnt
+			FROM team_prs
+			WHERE created_at >= $2::timestamp AND created_at < $3::timestamp
+			GROUP BY 1
+		),
+		merged AS (
+			SELECT date_trunc($1::text, merged_at) AS bucket_start, COUNT(*) AS cnt
+			FROM team_prs
+			WHERE merged_at >= $2::timestamp AND merged_at < $3::timestamp
+			GROUP BY 1
+		),
+		assignments AS (
+			SELECT date_trunc($1::text, a.assigned_at) AS bucket_start, COUNT(*) AS cnt
+			FROM (`+allAssignmentsSQL+`) a
+			INNER JOIN team_prs p ON p.pull_request_id = a.pull_request_id
+			WHERE a.assigned_at >= $2::timestamp AND a.assigned_at < $3::timestamp
+			GROUP BY 1
+		),
+		reassignments AS (
+			SELECT date_trunc($1::text, rr.reassigned_at) AS bucket_start, COUNT(*) AS cnt
+			FROM reviewer_reassignments rr
+			INNER JOIN team_prs p ON p.pull_request_id = rr.pull_request_id
+			WHERE rr.reassigned_at >= $2::timestamp AND rr.reassigned_at < $3::timestamp
+			GROUP BY 1
+		)
+		SELECT
+			b.bucket_start,
+			COALESCE(c.cnt, 0),
+			COALESCE(m.cnt, 0),
+			COALESCE(a.cnt, 0),
+			COALESCE(ra.cnt, 0)
+		FROM buckets b
+		LEFT JOIN created c ON c.bucket_start = b.bucket_start
+		LEFT JOIN merged m ON m.bucket_start = b.bucket_start
+		LEFT JOIN assignments a ON a.bucket_start = b.bucket_start
+		LEFT JOIN reassignments ra ON ra.bucket_start = b.bucket_start
+		ORDER BY b.bucket_start
+	`, interval, from, to, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := make([]models.TimeSeriesBucket, 0)
+	for rows.Next() {
+		var bucket models.TimeSeriesBucket
+		if err := rows.Scan(&bucket.BucketStart, &bucket.PRsCreated, &bucket.PRsMerged, &bucket.Assignments, &bucket.Reassignments); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, rows.Err()
+}
+
+// GetReviewerAssignmentSeries - назначения по каждому ревьюеру в разрезе бакетов.
+// Фильтр по команде здесь - команда ревьюера. Если команда задана, в ответе будут
+// все её участники, даже без назначений.
+func (r *Repository) GetReviewerAssignmentSeries(interval string, from, to time.Time, teamName string) ([]*models.ReviewerAssignmentSeries, error) {
+	rows, err := r.db.Query(`
+		WITH buckets AS (
+			SELECT generate_series(
+				date_trunc($1::text, $2::timestamp),
+				$3::timestamp - INTERVAL '1 microsecond',
+				('1 ' || $1::text)::interval
+			) AS bucket_start
+		),
+		assignments AS (
+			SELECT a.reviewer_id, date_trunc($1::text, a.assigned_at) AS bucket_start, COUNT(*) AS cnt
+			FROM (`+allAssignmentsSQL+`) a
+			INNER JOIN users u ON u.user_id = a.reviewer_id
+			WHERE a.assigned_at >= $2::timestamp AND a.assigned_at < $3::timestamp
+				AND ($4::text = '' OR u.team_name = $4::text)
+			GROUP BY 1, 2
+		),
+		reviewers AS (
+			SELECT user_id AS reviewer_id FROM users WHERE $4::text <> '' AND team_name = $4::text
+			UNION
+			SELECT reviewer_id FROM assignments
+		)
+		SELECT r.reviewer_id, u.username, b.bucket_start, COALESCE(a.cnt, 0)
+		FROM reviewers r
+		INNER JOIN users u ON u.user_id = r.reviewer_id
+		CROSS JOIN buckets b
+		LEFT JOIN assignments a ON a.reviewer_id = r.reviewer_id AND a.bucket_start = b.bucket_start
+		ORDER BY r.reviewer_id, b.bucket_start
+	`, interval, from, to, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Строки отсортированы по ревьюеру, так что собираю серии за один проход
+	series := make([]*models.ReviewerAssignmentSeries, 0)
+	var current *models.ReviewerAssignmentSeries
+	for rows.Next() {
+		var userID, username string
+		var bucket models.AssignmentBucket
+		if err := rows.Scan(&userID, &username, &bucket.BucketStart, &bucket.Assignments); err != nil {
+			return nil, err
+		}
+		if current == nil || current.UserID != userID {
+			current = &models.ReviewerAssignmentSeries{UserID: userID, Username: username, Buckets: []models.AssignmentBucket{}}
+			series = append(series, current)
+		}
+		current.TotalAssignments += bucket.Assignments
+		current.Buckets = append(current.Buckets, bucket)
+	}
+	return series, rows.Err()
+}
+
+// GetTeamStats - разбивка по командам за период. PR и переназначения считаются по команде автора,
+// назначения - по команде ревьюера.
+func (r *Repository) GetTeamStats(filter models.StatisticsFilter) ([]*models.TeamStats, error) {
+	rows, err := r.db.Query(`
+		SELECT
+			t.team_name,
+			(SELECT COUNT(*) FROM users u WHERE u.team_name = t.team_name) AS members,
+			(SELECT COUNT(*) FROM users u WHERE u.team_name = t.team_name AND u.is_active) AS active_members,
+			(
+				SELECT COUNT(*) FROM pull_requests pr
+				INNER JOIN users a ON a.user_id = pr.author_id
+				WHERE a.team_name = t.team_name
+					AND ($1::timestamp IS NULL OR pr.created_at >= $1::timestamp)
+					AND ($2::timestamp IS NULL OR pr.created_at < $2::timestamp)
+			) AS prs_created,
+			(
+				SELECT COUNT(*) FROM pull_requests pr
+				INNER JOIN users a ON a.user_id = pr.author_id
+				WHERE a.team_name = t.team_name AND pr.merged_at IS NOT NULL
+					AND ($1::timestamp IS NULL OR pr.merged_at >= $1::timestamp)
+					AND ($2::timestamp IS NULL OR pr.merged_at < $2::timestamp)
+			) AS prs_merged,
+			(
+				SELECT COUNT(*) FROM (`+allAssignmentsSQL+`) asg
+				INNER JOIN users rv ON rv.user_id = asg.reviewer_id
+				WHERE rv.team_name = t.team_name
+					AND ($1::timestamp IS NULL OR asg.assigned_at >= $1::timestamp)
+					AND ($2::timestamp IS NULL OR asg.assigned_at < $2::timestamp)
+			) AS assignments,
+			(
+				SELECT COUNT(*) FROM reviewer_reassignments rr
+				INNER JOIN pull_requests pr ON pr.pull_request_id = rr.pull_request_id
+				INNER JOIN users a ON a.user_id = pr.author_id
+				WHERE a.team_name = t.team_name
+					AND ($1::timestamp IS NULL OR rr.reassigned_at >= $1::timestamp)
+					AND ($2::timestamp IS NULL OR rr.reassigned_at < $2::timestamp)
+			) AS reassignments
+		FROM teams t
+		WHERE $3::text = '' OR t.team_name = $3::text
+		ORDER BY t.team_name
+	`, filter.From, filter.To, filter.TeamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make([]*models.TeamStats, 0)
+	for rows.Next() {
+		stat := &models.TeamStats{}
+		if err := rows.Scan(&stat.TeamName, &stat.Members, &stat.ActiveMembers, &stat.PRsCreated, &stat.PRsMerged, &stat.Assignments, &stat.Reassignments); err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
+
+// Turnaround
+
+// mergedPRsSQL - смерженные PR за период вместе с командой и именем автора.
+// Период здесь - по времени мержа, команда - команда автора.
+const mergedPRsSQL = `
+	SELECT
+		pr.pull_request_id,
+		pr.author_id,
+		a.username AS author_name,
+		a.team_name,
+		EXTRACT(EPOCH FROM (pr.merged_at - pr.created_at))::double precision / 3600 AS hours
+	FROM pull_requests pr
+	INNER JOIN users a ON a.user_id = pr.author_id
+	WHERE pr.merged_at IS NOT NULL AND pr.created_at IS NOT NULL
+		AND ($1::timestamp IS NULL OR pr.merged_at >= $1::timestamp)
+		AND ($2::timestamp IS NULL OR pr.merged_at < $2::timestamp)
+		AND ($3::text = '' OR a.team_name = $3::text)
+`
+
+// GetTimeToMerge - медиана и p90 времени от создания до мержа по всем PR за период
+func (r *Repository) GetTimeToMerge(filter models.StatisticsFilter) (*models.DurationStats, error) {
+	stats := &models.DurationStats{}
+	err := r.db.QueryRow(`
+		SELECT
+			COUNT(*),
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY hours),
+			percentile_cont(0.9) WITHIN GROUP (ORDER BY hours)
+		FROM (`+mergedPRsSQL+`) merged
+	`, filter.From, filter.To, filter.TeamName).Scan(&stats.Count, &stats.MedianHours, &stats.P90Hours)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (r *Repository) GetTimeToMergeByTeam(filter models.StatisticsFilter) ([]*models.TeamMergeTime, error) {
+	rows, err := r.db.Query(`
+		SELECT
+			team_name,
+			COUNT(*),
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY hours),
+			percentile_cont(0.9) WITHIN GROUP (ORDER BY hours)
+		FROM (`+mergedPRsSQL+`) merged
+		GROUP BY team_name
+		ORDER BY team_name
+	`, filter.From, filter.To, filter.TeamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make([]*models.TeamMergeTime, 0)
+	for rows.Next() {
+		stat := &models.TeamMergeTime{}
+		if err := rows.Scan(&stat.TeamName, &stat.Count, &stat.MedianHours, &stat.P90Hours); err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
+
+func (r *Repository) GetTimeToMergeByAuthor(filter models.StatisticsFilter) ([]*models.AuthorMergeTime, error) {
+	rows, err := r.db.Query(`
+		SELECT
+			author_id,
+			author_name,
+			COUNT(*),
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY hours),
+			percentile_cont(0.9) WITHIN GROUP (ORDER BY hours)
+		FROM (`+mergedPRsSQL+`) merged
+		GROUP BY author_id, author_name
+		ORDER BY author_id
+	`, filter.From, filter.To, filter.TeamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make([]*models.AuthorMergeTime, 0)
+	for rows.Next() {
+		stat := &models.AuthorMergeTime{}
+		if err := rows.Scan(&stat.AuthorID, &stat.Username, &stat.Count, &stat.MedianHours, &stat.P90Hours); err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
+
+// GetReviewerResponseTimes - время от назначения до первого действия ревьюера.
+// Период - по времени назначения, команда - команда ревьюера.
+func (r *Repository) GetReviewerResponseTimes(filter models.StatisticsFilter) ([]*models.ReviewerResponseTime, error) {
+	rows, err := r.db.Query(`
+		WITH assignments AS (
+			SELECT
+				prr.reviewer_id,
+				prr.assigned_at,
+				pr.status,
+				(
+					SELECT MIN(ra.created_at) FROM review_actions ra
+					WHERE ra.pull_request_id = prr.pull_request_id
+						AND ra.reviewer_id = prr.reviewer_id
+						AND ra.created_at >= prr.assigned_at
+				) AS first_action_at
+			FROM pull_request_reviewers prr
+			INNER JOIN pull_requests pr ON pr.pull_request_id = prr.pull_request_id
+			INNER JOIN users u ON u.user_id = prr.reviewer_id
+			WHERE ($1::timestamp IS NULL OR prr.assigned_at >= $1::timestamp)
+				AND ($2::timestamp IS NULL OR prr.assigned_at < $2::timestamp)
+				AND ($3::text = '' OR u.team_name = $3::text)
+		)
+		SELECT
+			a.reviewer_id,
+			u.username,
+			COUNT(a.first_action_at),
+			COUNT(CASE WHEN a.first_action_at IS NULL AND a.status = 'OPEN' THEN 1 END),
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM (a.first_action_at - a.assigned_at))::double precision / 3600),
+			percentile_cont(0.9) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM (a.first_action_at - a.assigned_at))::double precision / 3600)
+		FROM assignments a
+		INNER JOIN users u ON u.user_id = a.reviewer_id
+		GROUP BY a.reviewer_id, u.username
+		ORDER BY a.reviewer_id
+	`, filter.From, filter.To, filter.TeamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make([]*models.ReviewerResponseTime, 0)
+	for rows.Next() {
+		stat := &models.ReviewerResponseTime{}
+		if err := rows.Scan(&stat.UserID, &stat.Username, &stat.Count, &stat.Pending, &stat.MedianHours, &stat.P90Hours); err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
+
+// GetReviewLeaderboard - ревьюеры по числу отревьюенных PR за период,
+// при равенстве выше тот, кто быстрее отвечает на назначение
+func (r *Repository) GetReviewLeaderboard(filter models.StatisticsFilter, limit int) ([]*models.LeaderboardEntry, error) {
+	rows, err := r.db.Query(`
+		WITH reviews AS (
+			SELECT ra.reviewer_id, COUNT(DISTINCT ra.pull_request_id) AS reviews
+			FROM review_actions ra
+			WHERE ($1::timestamp IS NULL OR ra.created_at >= $1::timestamp)
+				AND ($2::timestamp IS NULL OR ra.created_at < $2::timestamp)
+			GROUP BY ra.reviewer_id
+		),
+		first_actions AS (
+			SELECT prr.reviewer_id, prr.assigned_at, MIN(ra.created_at) AS first_action_at
+			FROM pull_request_reviewers prr
+			INNER JOIN review_actions ra ON ra.pull_request_id = prr.pull_request_id
+				AND ra.reviewer_id = prr.reviewer_id
+				AND ra.created_at >= prr.assigned_at
+			GROUP BY prr.pull_request_id, prr.reviewer_id, prr.assigned_at
+		),
+		response_times AS (
+			SELECT
+				reviewer_id,
+				percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM (first_action_at - assigned_at))::double precision / 3600) AS median_hours
+			FROM first_actions
+			WHERE ($1::timestamp IS NULL OR first_action_at >= $1::timestamp)
+				AND ($2::timestamp IS NULL OR first_action_at < $2::timestamp)
+			GROUP BY reviewer_id
+		)
+		SELECT u.user_id, u.username, u.team_name, r.reviews, rt.median_hours
+		FROM reviews r
+		INNER JOIN users u ON u.user_id = r.reviewer_id
+		LEFT JOIN response_times rt ON rt.reviewer_id = r.reviewer_id
+		WHERE $3::text = '' OR u.team_name = $3::text
+		ORDER BY r.reviews DESC, rt.median_hours ASC NULLS LAST, u.user_id
+		LIMIT $4
+	`, filter.From, filter.To, filter.TeamName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*models.LeaderboardEntry, 0)
+	for rows.Next() {
+		entry := &models.LeaderboardEntry{Rank: len(entries) + 1}
+		if err := rows.Scan(&entry.UserID, &entry.Username, &entry.TeamName, &entry.Reviews, &entry.MedianResponseHours); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetMemberLoad - сколько назначений получил каждый участник за период и сколько часов
+// из этого периода он был активен (по user_activity_periods). Команда - текущая команда участника.
+func (r *Repository) GetMemberLoad(from, to time.Time, teamName string) ([]*models.MemberLoad, error) {
+	rows, err := r.db.Query(`
+		WITH assignments AS (
+			SELECT a.reviewer_id, COUNT(*) AS cnt
+			FROM (`+allAssignmentsSQL+`) a
+			WHERE a.assigned_at >= $1::timestamp AND a.assigned_at < $2::timestamp
+			GROUP BY a.reviewer_id
+		),
+		activity AS (
+			SELECT
+				user_id,
+				SUM(EXTRACT(EPOCH FROM (
+					LEAST(COALESCE(ended_at, $2::timestamp), $2::timestamp) - GREATEST(started_at, $1::timestamp)
+				)))::double precision / 3600 AS hours
+			FROM user_activity_periods
+			WHERE started_at < $2::timestamp AND (ended_at IS NULL OR ended_at > $1::timestamp)
+			GROUP BY user_id
+		)
+		SELECT u.team_name, u.user_id, u.username, u.is_active, COALESCE(a.cnt, 0), COALESCE(act.hours, 0)
+		FROM users u
+		LEFT JOIN assignments a ON a.reviewer_id = u.user_id
+		LEFT JOIN activity act ON act.user_id = u.user_id
+		WHERE $3::text = '' OR u.team_name = $3::text
+		ORDER BY u.team_name, u.user_id
+	`, from, to, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	loads := make([]*models.MemberLoad, 0)
+	for rows.Next() {
+		load := &models.MemberLoad{}
+		if err := rows.Scan(&load.TeamName, &load.UserID, &load.Username, &load.IsActive, &load.Assignments, &load.ActiveHours); err != nil {
+			return nil, err
+		}
+		loads = append(loads, load)
+	}
+	return loads, rows.Err()
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"fmt"
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+	"sort"
+)
+
+// BulkSetUsersActive - активирую или деактивирую сразу много пользователей: либо по списку user_ids,
+// либо всех участников команды, подходящих под фильтр.
+// При деактивации их открытые ревью переназначаются так же, как в BulkDeactivateTeam.
+// При активации PR их команд с needMoreReviewers добираются до двух ревьюеров.
+// expectedVersions - версии команды из If-Match (подходит любая), nil - не проверять. Их можно
+// передать только вместе с teamName: у списка user_ids может быть несколько команд.
+func (s *Service) BulkSetUsersActive(userIDs []string, teamName string, filter models.UserFilter, isActive, dryRun bool, expectedVersions []int) (*models.BulkSetActiveResult, error) {
+	if (len(userIDs) == 0) == (teamName == "") {
+		return nil, fmt.Errorf("invalid selector: pass either user_ids or team_name")
+	}
+	if expectedVersions != nil && teamName == "" {
+		return nil, fmt.Errorf("invalid If-Match: team version can only be checked with team_name")
+	}
+
+	var result *models.BulkSetActiveResult
+	err := s.runInTx(dryRun, func(repo *repository.Repository) error {
+		// Транзакция может повториться, поэтому результат собираю заново на каждой попытке
+		result = &models.BulkSetActiveResult{
+			IsActive:            isActive,
+			UpdatedUserIDs:      []string{},
+			UnchangedUserIDs:    []string{},
+			NotFoundUserIDs:     []string{},
+			ReassignedPRs:       []string{},
+			Reassignments:       []models.ReviewerReassignment{},
+			WithoutCandidate:    []models.ReviewerReassignment{},
+			BackfilledReviewers: []models.ReviewerAssignment{},
+			DryRun:              dryRun,
+		}
+
+		targetIDs := userIDs
+		if teamName != "" {
+			if expectedVersions != nil {
+				// Версию сверяю под блокировкой; LockTeam ниже для той же команды уже ничего не ждёт
+				if err := repo.LockTeam(teamName); err != nil {
+					return err
+				}
+			}
+			team, err := repo.GetTeam(teamName)
+			if err != nil {
+				return err
+			}
+			if err := checkVersion(expectedVersions, team.Version); err != nil {
+				return err
+			}
+			targetIDs, err = repo.GetTeamUserIDsByFilter(teamName, filter)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Порядок блокировок как в BulkDeactivateTeam: сначала команды (по имени, чтобы параллельные
+		// массовые операции не ловили дедлок), потом сами пользователи
+		known, err := repo.GetUsersByIDs(targetIDs)
+		if err != nil {
+			return err
+		}
+		teams := make([]string, 0)
+		seenTeams := make(map[string]bool)
+		for _, user := range known {
+			if !seenTeams[user.TeamName] {
+				seenTeams[user.TeamName] = true
+				teams = append(teams, user.TeamName)
+			}
+		}
+		sort.Strings(teams)
+		for _, team := range teams {
+			if err := repo.LockTeam(team); err != nil {
+				return err
+			}
+		}
+
+		users, err := repo.LockUsers(targetIDs)
+		if err != nil {
+			return err
+		}
+
+		seenUsers := make(map[string]bool)
+		for _, userID := range targetIDs {
+			if seenUsers[userID] {
+				continue
+			}
+			seenUsers[userID] = true
+
+			user, ok := users[userID]
+			if !ok {
+				result.NotFoundUserIDs = append(result.NotFoundUserIDs, userID)
+				continue
+			}
+			if user.IsActive == isActive {
+				result.UnchangedUserIDs = append(result.UnchangedUserIDs, userID)
+				continue
+			}
+			result.UpdatedUserIDs = append(result.UpdatedUserIDs, userID)
+		}
+
+		if len(result.UpdatedUserIDs) == 0 {
+			return nil
+		}
+
+		if isActive {
+			if err := repo.SetUsersActive(result.UpdatedUserIDs, true); err != nil {
+				return err
+			}
+			result.BackfilledReviewers, err = s.backfillReviewers(repo, teams)
+			if err != nil {
+				return err
+			}
+		} else {
+			// Как и в BulkDeactivateTeam: сначала переназначаю, потом деактивирую
+			result.ReassignedPRs, result.Reassignments, result.WithoutCandidate, err = s.reassignOpenReviews(repo, result.UpdatedUserIDs)
+			if err != nil {
+				return err
+			}
+			if err := repo.SetUsersActive(result.UpdatedUserIDs, false); err != nil {
+				return err
+			}
+			for _, userID := range result.UpdatedUserIDs {
+				if err := enqueueUsersDeactivated(repo, users[userID].TeamName, []string{userID}); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Версию поднимаю только тем командам, где кто-то реально поменял статус
+		bumped := make(map[string]bool)
+		for _, userID := range result.UpdatedUserIDs {
+			team := users[userID].TeamName
+			if bumped[team] {
+				continue
+			}
+			bumped[team] = true
+			if err := repo.BumpTeamVersion(team); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// backfillReviewers - добираю ревьюеров на открытые PR с needMoreReviewers, авторы которых из этих команд.
+// Выбор тот же, что при создании PR (chooseReviewersByRules): команда автора без автора, уже назначенных
+// и отказавшихся, правила команды и ревьюер безопасности для PR с меткой security. Каждое назначение
+// уходит в outbox как pr.reviewer_added, как при ручном добавлении.
+func (s *Service) backfillReviewers(repo *repository.Repository, teamNames []string) ([]models.ReviewerAssignment, error) {
+	assignments := []models.ReviewerAssignment{}
+
+	prIDs, err := repo.GetOpenPRsNeedingReviewers(teamNames)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, prID := range prIDs {
+		if err := repo.LockPullRequest(prID); err != nil {
+			return nil, err
+		}
+		pr, err := repo.GetPullRequest(prID)
+		if err != nil {
+			return nil, err
+		}
+		// Пока я ждал блокировку, PR могли смержить или уже доукомплектовать
+		if pr.Status != models.StatusOpen || len(pr.AssignedReviewers) >= maxReviewers {
+			continue
+		}
+
+		author, err := repo.GetUser(pr.AuthorID)
+		if err != nil {
+			continue
+		}
+		unavailable, err := unavailableReviewers(repo, prID, pr.AssignedReviewers)
+		if err != nil {
+			return nil, err
+		}
+		candidates, err := repo.GetActiveUsersByTeam(author.TeamName, pr.AuthorID)
+		if err != nil {
+			return nil, err
+		}
+		candidates = s.filterAssignedReviewers(candidates, unavailable, pr.AuthorID)
+		var securityCandidates []*models.User
+		if s.needsSecurityReviewer(pr, author.TeamName) {
+			if securityCandidates, err = repo.GetActiveUsersByTeam(s.securityReviewTeam, pr.AuthorID); err != nil {
+				return nil, err
+			}
+			securityCandidates = s.filterAssignedReviewers(securityCandidates, unavailable, pr.AuthorID)
+		}
+		rules, err := effectiveReviewRules(repo, author.TeamName, seniorityScope(pr.AssignedReviewers, candidates, securityCandidates))
+		if err != nil {
+			return nil, err
+		}
+
+		selected, err := s.chooseReviewersByRules(repo, pr, author.TeamName, rules, securityCandidates, candidates, nil, maxReviewers-len(pr.AssignedReviewers))
+		if err != nil {
+			// Нет ревьюера безопасности или старшего - PR так и остаётся с needMoreReviewers
+			if isNoCandidate(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, reviewerID := range selected {
+			if err := repo.AddReviewer(prID, reviewerID); err != nil {
+				return nil, err
+			}
+			if err := recordEvents(repo, models.UserEventAssigned, prID, reviewerID); err != nil {
+				return nil, err
+			}
+			updated, err := repo.GetPullRequest(prID)
+			if err != nil {
+				return nil, err
+			}
+			if err := enqueueReviewerChange(repo, models.OutboxReviewerAdded, updated, reviewerID); err != nil {
+				return nil, err
+			}
+			assignments = append(assignments, models.ReviewerAssignment{PullRequestID: prID, ReviewerID: reviewerID})
+		}
+	}
+
+	return assignments, nil
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"fmt"
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+)
+
+// maxBulkCreateItems - сколько PR можно создать за один запрос
+const maxBulkCreateItems = 5000
+
+// BulkCreatePullRequests - создаю пачку PR в одной транзакции. Правила те же, что в CreatePullRequest:
+// метки и приоритет, ревьюер безопасности, самые свободные для urgent и правила команды автора.
+// Но ошибка одного PR (занятый ID, нет автора, нет кандидата) не отменяет остальные - она попадает в его результат.
+func (s *Service) BulkCreatePullRequests(items []models.BulkCreateItem) ([]*models.BulkCreateResult, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("invalid batch: pull_requests must not be empty")
+	}
+	if len(items) > maxBulkCreateItems {
+		return nil, fmt.Errorf("invalid batch: at most %d pull requests per request", maxBulkCreateItems)
+	}
+
+	var results []*models.BulkCreateResult
+	err := s.runInTx(false, func(repo *repository.Repository) error {
+		// Транзакция может повториться, поэтому результаты собираю заново на каждой попытке
+		results = make([]*models.BulkCreateResult, len(items))
+
+		prIDs := make([]string, 0, len(items))
+		authorIDs := make([]string, 0, len(items))
+		for _, item := range items {
+			prIDs = append(prIDs, item.PullRequestID)
+			authorIDs = append(authorIDs, item.AuthorID)
+		}
+
+		existing, err := repo.GetExistingPullRequestIDs(prIDs)
+		if err != nil {
+			return err
+		}
+		authors, err := repo.GetUsersByIDs(authorIDs)
+		if err != nil {
+			return err
+		}
+
+		teamNames := make([]string, 0)
+		seenTeams := make(map[string]bool)
+		for _, author := range authors {
+			if !seenTeams[author.TeamName] {
+				seenTeams[author.TeamName] = true
+				teamNames = append(teamNames, author.TeamName)
+			}
+		}
+		memberTeams := teamNames
+		if s.securityReviewTeam != "" && !seenTeams[s.securityReviewTeam] {
+			// Команду безопасности читаю тем же запросом, она нужна PR с меткой security
+			memberTeams = append(append([]string{}, teamNames...), s.securityReviewTeam)
+		}
+		teamMembers, err := repo.GetActiveUsersByTeams(memberTeams)
+		if err != nil {
+			return err
+		}
+		teamRules := make(map[string]*models.ReviewRules, len(teamNames))
+		for _, teamName := range teamNames {
+			// Ревьюеры пакета - из команды автора и команды безопасности, старших ищу только среди них
+			scope := seniorityScope(nil, teamMembers[teamName], teamMembers[s.securityReviewTeam])
+			if teamRules[teamName], err = effectiveReviewRules(repo, teamName, scope); err != nil {
+				return err
+			}
+		}
+
+		prs := make([]*models.PullRequest, 0, len(items))
+		inBatch := make(map[string]bool)
+		// pending - сколько ревью уже раздал в этом пакете, чтобы urgent не свалились на одного
+		pending := make(map[string]int)
+		for i, item := range items {
+			result := &models.BulkCreateResult{PullRequestID: item.PullRequestID}
+			results[i] = result
+
+			if item.PullRequestID == "" || item.PullRequestName == "" || item.AuthorID == "" {
+				result.ErrorCode = models.ErrorNotFound
+				result.Message = "pull_request_id, pull_request_name and author_id are required"
+				continue
+			}
+			labels, err := normalizeLabels(item.Labels)
+			if err != nil {
+				result.ErrorCode, _ = models.ErrorStatus(err)
+				result.Message = err.Error()
+				continue
+			}
+			priority, err := normalizePriority(item.Priority)
+			if err != nil {
+				result.ErrorCode, _ = models.ErrorStatus(err)
+				result.Message = err.Error()
+				continue
+			}
+			// Повтор ID внутри одного пакета считаю таким же конфликтом, как с уже существующим PR
+			if existing[item.PullRequestID] || inBatch[item.PullRequestID] {
+				result.ErrorCode = models.ErrorPRExists
+				result.Message = "PR id already exists"
+				continue
+			}
+			author, ok := authors[item.AuthorID]
+			if !ok {
+				result.ErrorCode = models.ErrorNotFound
+				result.Message = "author or team not found"
+				continue
+			}
+
+			pr := &models.PullRequest{
+				PullRequestID:   item.PullRequestID,
+				PullRequestName: item.PullRequestName,
+				AuthorID:        item.AuthorID,
+				Status:          models.StatusOpen,
+				Version:         1,
+				Labels:          labels,
+				Priority:        priority,
+			}
+			candidates := s.filterAssignedReviewers(teamMembers[author.TeamName], nil, author.UserID)
+			reviewers, err := s.chooseReviewersByRules(repo, pr, author.TeamName, teamRules[author.TeamName], teamMembers[s.securityReviewTeam], candidates, pending, 2)
+			if err != nil {
+				if isNoCandidate(err) {
+					result.ErrorCode = models.ErrorNoCandidate
+					result.Message = err.Error()
+					continue
+				}
+				return err
+			}
+			inBatch[item.PullRequestID] = true
+			for _, reviewerID := range reviewers {
+				pending[reviewerID]++
+			}
+
+			pr.AssignedReviewers = reviewers
+			pr.NeedMoreReviewers = len(reviewers) < 2
+			prs = append(prs, pr)
+			result.PR = pr
+		}
+
+		created, err := repo.BulkCreatePullRequests(prs)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range results {
+			if result.PR == nil {
+				continue
+			}
+			createdAt, ok := created[result.PullRequestID]
+			if !ok {
+				// Кто-то успел создать этот PR параллельно, между проверкой и вставкой
+				result.PR = nil
+				result.ErrorCode = models.ErrorPRExists
+				result.Message = "PR id already exists"
+				continue
+			}
+			result.PR.CreatedAt = &createdAt
+			result.Created = true
+			if err := recordEvents(repo, models.UserEventAssigned, result.PullRequestID, result.PR.AssignedReviewers...); err != nil {
+				return err
+			}
+			if err := enqueuePullRequestEvent(repo, models.OutboxPRCreated, result.PR); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"fmt"
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+	"strings"
+)
+
+// maxDeclineReasonLength - причина отказа - это пара фраз, а не описание PR
+const maxDeclineReasonLength = 1000
+
+// DeclineReview - назначенный ревьюер сам отказывается от PR. Замену ищу так же, как при
+// /pullRequest/reassign, но отказавшихся от этого PR (и его самого) автоматически больше не назначаю.
+// Если замены нет, ревьюера всё равно снимаю: PR остаётся с needMoreReviewers, и его
+// подхватят эскалация по SLA или добор при активации. Тогда, как и при ручном снятии,
+// в outbox уходит ещё pr.reviewer_removed с needMoreReviewers. Возвращаю PR и замену ("" - не нашлось).
+func (s *Service) DeclineReview(prID, reviewerID, reason string, expectedVersions []int) (*models.PullRequest, string, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" || len(reason) > maxDeclineReasonLength {
+		return nil, "", fmt.Errorf("invalid reason: must be 1 to %d characters", maxDeclineReasonLength)
+	}
+
+	var updated *models.PullRequest
+	var replacedBy string
+	err := s.runInTx(false, func(repo *repository.Repository) error {
+		updated, replacedBy = nil, ""
+
+		pr, err := lockOpenPullRequest(repo, prID, expectedVersions)
+		if err != nil {
+			return err
+		}
+		if !containsString(pr.AssignedReviewers, reviewerID) {
+			return fmt.Errorf("NOT_ASSIGNED")
+		}
+
+		// Сам отказавшийся в кандидаты не попадёт: он ещё числится назначенным
+		replacedBy, err = s.replaceReviewer(repo, pr, reviewerID)
+		if err != nil && isNoCandidate(err) {
+			if err := repo.RemoveReviewer(prID, reviewerID); err != nil {
+				return err
+			}
+			err = recordEvents(repo, models.UserEventUnassigned, prID, reviewerID)
+		}
+		if err != nil {
+			return err
+		}
+
+		decline := &models.ReviewDecline{PullRequestID: prID, ReviewerID: reviewerID, Reason: reason, ReplacedBy: replacedBy}
+		if err := repo.CreateReviewDecline(decline); err != nil {
+			return err
+		}
+
+		updated, err = repo.GetPullRequest(prID)
+		if err != nil {
+			return err
+		}
+		if replacedBy == "" {
+			if err := enqueueReviewerChange(repo, models.OutboxReviewerRemoved, updated, reviewerID); err != nil {
+				return err
+			}
+		}
+		return enqueueOutbox(repo, models.AggregatePullRequest, prID, models.OutboxReviewDeclined, decline)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return updated, replacedBy, nil
+}
+
+// unavailableReviewers - кого нельзя автоматически назначить на PR:
+// уже назначенных и тех, кто от него отказался
+func unavailableReviewers(repo *repository.Repository, prID string, assigned []string) ([]string, error) {
+	declined, err := repo.GetDeclinedReviewerIDs(prID)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]string{}, assigned...), declined...), nil
+}
+
+// withoutString - копия values без value
+func withoutString(values []string, value string) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"fmt"
+	"net/mail"
+	"pr-reviewer-service/internal/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SetDigestPreferences - почта и согласие на ежедневную сводку. Без почты включить сводку нельзя.
+func (s *Service) SetDigestPreferences(userID, email string, enabled bool) (*models.DigestPreferences, error) {
+	prefs := &models.DigestPreferences{UserID: userID, Email: email, DigestEnabled: enabled}
+	if err := normalizeDigestPreferences(prefs); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetDigestPreferences(userID, prefs.Email, enabled, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDigestPreferences(userID)
+}
+
+// normalizeDigestPreferences - проверяю почту. Общее для /users/setDigestPreferences и импорта.
+func normalizeDigestPreferences(prefs *models.DigestPreferences) error {
+	prefs.Email = strings.TrimSpace(prefs.Email)
+	if prefs.Email != "" {
+		addr, err := mail.ParseAddress(prefs.Email)
+		if err != nil || addr.Address != prefs.Email {
+			return fmt.Errorf("invalid email")
+		}
+	}
+	if prefs.DigestEnabled && prefs.Email == "" {
+		return fmt.Errorf("invalid preferences: email is required to enable digest")
+	}
+	return nil
+}
+
+// BuildDigest - сводка открытых PR, которые ждут ревью пользователя: самые старые сверху.
+// Это ровно то, что уйдёт письмом, поэтому /users/digest показывает её как превью.
+func (s *Service) BuildDigest(userID string) (*models.Digest, error) {
+	user, err := s.repo.GetUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	prefs, err := s.repo.GetDigestPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	prs, err := s.repo.GetPullRequestsByReviewer(userID, models.PullRequestFilter{Status: string(models.StatusOpen)})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	items := make([]*models.DigestItem, 0, len(prs))
+	authors := make(map[string]string)
+	for _, short := range prs {
+		pr, err := s.repo.GetPullRequest(short.PullRequestID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Авторы часто повторяются, не хожу за ними в базу каждый раз
+		authorName, ok := authors[pr.AuthorID]
+		if !ok {
+			authorName = pr.AuthorID
+			if author, err := s.repo.GetUser(pr.AuthorID); err == nil {
+				authorName = author.Username
+			}
+			authors[pr.AuthorID] = authorName
+		}
+
+		item := &models.DigestItem{
+			PullRequestID:     pr.PullRequestID,
+			PullRequestName:   pr.PullRequestName,
+			AuthorID:          pr.AuthorID,
+			AuthorName:        authorName,
+			NeedMoreReviewers: pr.NeedMoreReviewers,
+		}
+		if pr.CreatedAt != nil {
+			item.CreatedAt = pr.CreatedAt.UTC()
+			item.AgeHours = now.Sub(item.CreatedAt).Round(time.Minute).Hours()
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+
+	digest := &models.Digest{
+		UserID:       user.UserID,
+		Username:     user.Username,
+		Email:        prefs.Email,
+		GeneratedAt:  now,
+		PullRequests: items,
+	}
+	digest.Subject, digest.Body = renderDigest(digest)
+	return digest, nil
+}
+
+// renderDigest - тема и текст письма
+func renderDigest(digest *models.Digest) (string, string) {
+	subject := fmt.Sprintf("Ревью: %d открытых PR ждут вас", len(digest.PullRequests))
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Привет, %s!\n\n", digest.Username)
+	if len(digest.PullRequests) == 0 {
+		body.WriteString("Открытых PR на ревью у вас нет.\n")
+		return subject, body.String()
+	}
+
+	body.WriteString("Открытые PR, где вы ревьюер (сначала самые старые):\n\n")
+	for _, item := range digest.PullRequests {
+		fmt.Fprintf(&body, "- %s (%s) от %s, ждёт %s", item.PullRequestName, item.PullRequestID, item.AuthorName, formatAge(item.AgeHours))
+		if item.NeedMoreReviewers {
+			body.WriteString(", не хватает ревьюеров")
+		}
+		body.WriteString("\n")
+	}
+	return subject, body.String()
+}
+
+// formatAge - возраст PR по-человечески: "5 ч" или "3 дн 4 ч"
+func formatAge(hours float64) string {
+	total := int(hours)
+	if total < 24 {
+		return fmt.Sprintf("%d ч", total)
+	}
+	return fmt.Sprintf("%d дн %d ч", total/24, total%24)
+}
+
+// SendDueDigests - рассылаю сводки всем, кто на неё подписан и ещё не получил её после dueAt.
+// Перед отправкой помечаю сводку отправленной, чтобы два экземпляра сервиса не прислали её дважды;
+// если письмо не ушло, отметку откатываю, и следующий проход попробует снова.
+// Пустые сводки не шлю. Возвращаю, сколько писем отправлено.
+func (s *Service) SendDueDigests(dueAt time.Time, send func(digest *models.Digest) error) (int, error) {
+	recipients, err := s.repo.GetDueDigestRecipients(dueAt)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	var firstErr error
+	for _, recipient := range recipients {
+		claimed, err := s.repo.ClaimDigest(recipient.UserID, dueAt, time.Now().UTC())
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		digest, err := s.BuildDigest(recipient.UserID)
+		if err == nil {
+			if len(digest.PullRequests) == 0 {
+				continue
+			}
+			err = send(digest)
+		}
+		if err != nil {
+			if restoreErr := s.repo.RestoreDigestSentAt(recipient.UserID, recipient.LastSentAt); restoreErr != nil {
+				return sent, restoreErr
+			}
+			if firstErr == nil {
+				firstErr = fmt.Errorf("digest for %s: %w", recipient.UserID, err)
+			}
+			continue
+		}
+		sent++
+	}
+	return sent, firstErr
+}
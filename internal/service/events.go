@@ -0,0 +1,127 @@
+package service
+
+import (
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+	"sync"
+)
+
+const (
+	// subscriberBuffer - сколько событий может накопиться у медленного подписчика
+	subscriberBuffer = 64
+	// maxReplayEvents - сколько пропущенных событий читаю из базы за раз при переподключении
+	maxReplayEvents = 1000
+)
+
+// eventBus - шина событий внутри процесса. Сервис публикует в неё события после коммита,
+// а /users/stream подписывается на события конкретного пользователя.
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan models.UserEvent]struct{}
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subscribers: make(map[string]map[chan models.UserEvent]struct{})}
+}
+
+func (b *eventBus) subscribe(userID string) (<-chan models.UserEvent, func()) {
+	ch := make(chan models.UserEvent, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[chan models.UserEvent]struct{})
+	}
+	b.subscribers[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(userID, ch)
+	}
+}
+
+// remove - вызывается под b.mu. Канал закрываю только здесь, чтобы не закрыть его дважды.
+func (b *eventBus) remove(userID string, ch chan models.UserEvent) {
+	if _, ok := b.subscribers[userID][ch]; !ok {
+		return
+	}
+	delete(b.subscribers[userID], ch)
+	if len(b.subscribers[userID]) == 0 {
+		delete(b.subscribers, userID)
+	}
+	close(ch)
+}
+
+// publish - раздаю события подписчикам. Если подписчик не успевает и его буфер полон,
+// отключаю его: клиент переподключится с Last-Event-ID и доберёт пропущенное из базы.
+func (b *eventBus) publish(events []models.UserEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, event := range events {
+		for ch := range b.subscribers[event.UserID] {
+			select {
+			case ch <- event:
+			default:
+				b.remove(event.UserID, ch)
+			}
+		}
+	}
+}
+
+// SubscribeUserEvents - подписка на события пользователя. Канал закрывается, если подписчик
+// отстал; вторым значением возвращаю отписку, её нужно вызвать, когда клиент ушёл.
+func (s *Service) SubscribeUserEvents(userID string) (<-chan models.UserEvent, func()) {
+	return s.events.subscribe(userID)
+}
+
+// ReplayUserEvents - события пользователя после lastEventID, для продолжения потока.
+// Читаю из базы пачками, чтобы долгий разрыв не тянул всю историю в память.
+func (s *Service) ReplayUserEvents(userID string, lastEventID int64, fn func(event models.UserEvent) error) error {
+	for {
+		events, err := s.repo.GetUserEventsAfter(userID, lastEventID, maxReplayEvents)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err := fn(event); err != nil {
+				return err
+			}
+			lastEventID = event.ID
+		}
+		if len(events) < maxReplayEvents {
+			return nil
+		}
+	}
+}
+
+// recordEvents - одно и то же событие для нескольких пользователей
+func recordEvents(repo *repository.Repository, eventType models.UserEventType, prID string, userIDs ...string) error {
+	for _, userID := range userIDs {
+		if err := repo.RecordUserEvent(userID, eventType, prID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordReassignment - старого ревьюера сняли, нового назначили: события в поток обоим
+// и доменное событие в outbox
+func recordReassignment(repo *repository.Repository, prID, oldReviewerID, newReviewerID string) error {
+	if err := repo.RecordUserEvent(oldReviewerID, models.UserEventUnassigned, prID); err != nil {
+		return err
+	}
+	if err := repo.RecordUserEvent(newReviewerID, models.UserEventAssigned, prID); err != nil {
+		return err
+	}
+	return enqueueOutbox(repo, models.AggregatePullRequest, prID, models.OutboxReviewerReassigned, models.ReviewerReassignment{
+		PullRequestID: prID,
+		OldReviewerID: oldReviewerID,
+		NewReviewerID: newReviewerID,
+	})
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"fmt"
+	"pr-reviewer-service/internal/models"
+	"strings"
+)
+
+// maxLabelLength - метка длиннее этого почти наверняка ошибка клиента
+const maxLabelLength = 64
+
+// normalizeLabels - убираю пробелы по краям, пустые метки и повторы. Порядок сохраняю.
+func normalizeLabels(labels []string) ([]string, error) {
+	result := make([]string, 0, len(labels))
+	seen := make(map[string]bool)
+	for _, label := range labels {
+		label = strings.TrimSpace(label)
+		if label == "" || seen[label] {
+			continue
+		}
+		if len(label) > maxLabelLength {
+			return nil, fmt.Errorf("invalid label %q: longer than %d characters", label, maxLabelLength)
+		}
+		seen[label] = true
+		result = append(result, label)
+	}
+	return result, nil
+}
+
+// normalizePriority - пустой приоритет считаю normal
+func normalizePriority(priority models.PullRequestPriority) (models.PullRequestPriority, error) {
+	switch priority {
+	case "":
+		return models.PriorityNormal, nil
+	case models.PriorityLow, models.PriorityNormal, models.PriorityHigh, models.PriorityUrgent:
+		return priority, nil
+	}
+	return "", fmt.Errorf("invalid priority: must be low, normal, high or urgent")
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+	"time"
+)
+
+const (
+	// outboxRetryBase и outboxRetryMax - экспоненциальная пауза между попытками отправить событие
+	outboxRetryBase = time.Second
+	outboxRetryMax  = 5 * time.Minute
+	// outboxMaxAttempts - после стольких неудачных попыток событие считаю мёртвым (dead_at).
+	// С паузой до 5 минут это примерно полтора часа недоступности синка.
+	outboxMaxAttempts = 25
+)
+
+// enqueueOutbox - кладу доменное событие в outbox в текущей транзакции.
+// Если транзакция откатится (в том числе в dry_run), пропадёт и событие.
+func enqueueOutbox(repo *repository.Repository, aggregateType, aggregateID string, eventType models.OutboxEventType, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return repo.InsertOutboxEvent(aggregateType, aggregateID, eventType, data)
+}
+
+func enqueuePullRequestEvent(repo *repository.Repository, eventType models.OutboxEventType, pr *models.PullRequest) error {
+	return enqueueOutbox(repo, models.AggregatePullRequest, pr.PullRequestID, eventType, pr)
+}
+
+// enqueueUsersDeactivated - по событию на каждого деактивированного пользователя
+func enqueueUsersDeactivated(repo *repository.Repository, teamName string, userIDs []string) error {
+	for _, userID := range userIDs {
+		payload := models.UserDeactivatedPayload{UserID: userID, TeamName: teamName}
+		if err := enqueueOutbox(repo, models.AggregateUser, userID, models.OutboxUserDeactivated, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProcessOutbox - одна пачка событий outbox. Забираю готовые к отправке в аренду на lease
+// (короткая команда, транзакция не висит, пока отвечают синки), отдаю каждое в publish
+// и отдельно отмечаю результат. Упавшее событие откладываю с растущей паузой, остальные
+// события его PR ждут, пока оно не уйдёт; после outboxMaxAttempts попыток событие мёртвое
+// и очередь PR идёт дальше. Доставка "хотя бы один раз": если процесс упадёт между publish
+// и отметкой, событие отправится ещё раз, когда истечёт аренда.
+// Возвращаю, сколько событий взял, чтобы релей понимал, есть ли ещё работа.
+func (s *Service) ProcessOutbox(limit int, lease time.Duration, publish func(event *models.OutboxEvent) error) (int, error) {
+	events, err := s.repo.ClaimOutboxEvents(limit, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		if err := publish(event); err != nil {
+			if err := s.markOutboxFailed(event, err); err != nil {
+				return len(events), err
+			}
+			continue
+		}
+		if err := s.repo.MarkOutboxEventPublished(event.ID); err != nil {
+			return len(events), err
+		}
+	}
+	return len(events), nil
+}
+
+func (s *Service) markOutboxFailed(event *models.OutboxEvent, publishErr error) error {
+	if outboxExhausted(event.Attempts) {
+		log.Printf("Событие outbox %d (%s) не ушло за %d попыток, больше не отправляю: %v", event.ID, event.EventType, outboxMaxAttempts, publishErr)
+		return s.repo.MarkOutboxEventDead(event.ID, publishErr.Error())
+	}
+	return s.repo.MarkOutboxEventFailed(event.ID, publishErr.Error(), outboxRetryDelay(event.Attempts))
+}
+
+// outboxExhausted - attempts - сколько попыток было до текущей, упавшей
+func outboxExhausted(attempts int) bool {
+	return attempts+1 >= outboxMaxAttempts
+}
+
+// CleanupOutbox - удаляю события, опубликованные больше retention назад
+func (s *Service) CleanupOutbox(retention time.Duration) (int64, error) {
+	return s.repo.DeletePublishedOutboxEvents(retention)
+}
+
+// outboxRetryDelay - 1с, 2с, 4с... но не больше outboxRetryMax
+func outboxRetryDelay(attempts int) time.Duration {
+	delay := outboxRetryBase
+	for i := 0; i < attempts && delay < outboxRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > outboxRetryMax {
+		return outboxRetryMax
+	}
+	return delay
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+// TestOutboxRetryDelay - пауза удваивается с каждой попыткой, но не дольше outboxRetryMax
+func TestOutboxRetryDelay(t *testing.T) {
+	cases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{8, 256 * time.Second},
+		// 512с уже больше пяти минут - упираюсь в потолок
+		{9, outboxRetryMax},
+		{1000, outboxRetryMax},
+	}
+	for _, c := range cases {
+		if got := outboxRetryDelay(c.attempts); got != c.want {
+			t.Errorf("outboxRetryDelay(%d) = %v, ожидалось %v", c.attempts, got, c.want)
+		}
+	}
+}
+
+// TestOutboxExhausted - событие умирает на outboxMaxAttempts-й неудачной попытке, не раньше
+func TestOutboxExhausted(t *testing.T) {
+	if outboxExhausted(0) {
+		t.Error("Событие не должно умирать после первой неудачи")
+	}
+	if outboxExhausted(outboxMaxAttempts - 2) {
+		t.Errorf("После %d неудач у события ещё есть попытка", outboxMaxAttempts-1)
+	}
+	if !outboxExhausted(outboxMaxAttempts - 1) {
+		t.Errorf("После %d неудач событие должно стать мёртвым", outboxMaxAttempts)
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"fmt"
+	"pr-reviewer-service/internal/models"
+	"sort"
+	"strings"
+)
+
+// maxSkills - навыков у человека немного, сотня - уже явно ошибка клиента
+const maxSkills = 100
+
+// SetUserProfile - уровень и навыки пользователя. Пустой seniority - уровень не указан,
+// skills заменяет навыки целиком.
+func (s *Service) SetUserProfile(userID string, seniority models.Seniority, skills []string) (*models.User, error) {
+	profile := &models.UserProfile{UserID: userID, Seniority: seniority, Skills: skills}
+	if err := normalizeUserProfile(profile); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetUserProfile(userID, profile.Seniority, profile.Skills); err != nil {
+		return nil, err
+	}
+	return s.repo.GetUser(userID)
+}
+
+// normalizeUserProfile - проверяю уровень и чищу навыки. Общее для /users/setProfile и импорта.
+func normalizeUserProfile(profile *models.UserProfile) error {
+	switch profile.Seniority {
+	case "", models.SeniorityJunior, models.SeniorityMiddle, models.SenioritySenior, models.SeniorityLead:
+	default:
+		return fmt.Errorf("invalid seniority: must be junior, middle, senior or lead")
+	}
+	skills, err := normalizeSkills(profile.Skills)
+	if err != nil {
+		return err
+	}
+	profile.Skills = skills
+	return nil
+}
+
+// normalizeSkills - навыки сравниваю с метками PR, поэтому чищу их так же, как метки
+func normalizeSkills(skills []string) ([]string, error) {
+	if len(skills) > maxSkills {
+		return nil, fmt.Errorf("invalid skills: at most %d", maxSkills)
+	}
+	result := make([]string, 0, len(skills))
+	for _, skill := range skills {
+		skill = strings.TrimSpace(skill)
+		if skill == "" || containsString(result, skill) {
+			continue
+		}
+		if len(skill) > maxLabelLength {
+			return nil, fmt.Errorf("invalid skill %q: longer than %d characters", skill, maxLabelLength)
+		}
+		result = append(result, skill)
+	}
+	return result, nil
+}
+
+// anySenior - есть ли среди users старший по уровню: senior или lead
+func anySenior(users []*models.User) bool {
+	for _, user := range users {
+		if user.Seniority == models.SenioritySenior || user.Seniority == models.SeniorityLead {
+			return true
+		}
+	}
+	return false
+}
+
+// preferSkilled - ставлю вперёд кандидатов с навыком из меток PR, порядок внутри обеих групп сохраняю.
+// Это только порядок: если совпадений нет, выбор такой же, как без match_skills.
+func preferSkilled(ranked []string, candidates []*models.User, labels []string) []string {
+	skilled := make(map[string]bool)
+	for _, candidate := range candidates {
+		if hasSkillFor(candidate, labels) {
+			skilled[candidate.UserID] = true
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return skilled[ranked[i]] && !skilled[ranked[j]]
+	})
+	return ranked
+}
+
+// hasSkillFor - есть ли у пользователя навык, совпадающий с одной из меток PR
+func hasSkillFor(user *models.User, labels []string) bool {
+	for _, skill := range user.Skills {
+		if containsString(labels, skill) {
+			return true
+		}
+	}
+	return false
+}
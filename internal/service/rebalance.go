@@ -0,0 +1,197 @@
+package service
+
+import (
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+	"sort"
+)
+
+// RebalanceTeam - перекладываю открытые ревью с самых загруженных участников команды на самых свободных.
+// Каждый перенос - это то же переназначение, что и в ReassignReviewer: PR открыт, новый ревьюер
+// активен, не автор, ещё не назначен, не отказывался от этого PR и подходит по правилам команды автора.
+// Останавливаюсь, когда разница между максимумом и минимумом открытых ревью не больше одного
+// или переносить больше нечего.
+func (s *Service) RebalanceTeam(teamName string, dryRun bool) (*models.RebalanceResult, error) {
+	var result *models.RebalanceResult
+
+	err := s.runInTx(dryRun, func(repo *repository.Repository) error {
+		// Транзакция может повториться, поэтому результат собираю заново на каждой попытке
+		result = &models.RebalanceResult{
+			TeamName:      teamName,
+			LoadBefore:    map[string]int{},
+			LoadAfter:     map[string]int{},
+			Reassignments: []models.ReviewerReassignment{},
+			DryRun:        dryRun,
+		}
+
+		// Блокирую команду, чтобы параллельно никто не деактивировал участников
+		if err := repo.LockTeam(teamName); err != nil {
+			return err
+		}
+		if _, err := repo.GetTeam(teamName); err != nil {
+			return err
+		}
+
+		reviews, err := repo.GetOpenReviewsByTeam(teamName)
+		if err != nil {
+			return err
+		}
+		for userID, prIDs := range reviews {
+			result.LoadBefore[userID] = len(prIDs)
+		}
+
+		// PR читаю под блокировкой один раз и дальше обновляю состав ревьюеров в памяти
+		prs := make(map[string]*models.PullRequest)
+		declined := make(map[string][]string)
+		// rules - по pull_request_id
+		rules := make(map[string]*models.ReviewRules)
+		memberIDs := make([]string, 0, len(reviews))
+		for userID := range reviews {
+			memberIDs = append(memberIDs, userID)
+		}
+		getPR := func(prID string) (*models.PullRequest, error) {
+			if pr, ok := prs[prID]; ok {
+				return pr, nil
+			}
+			if err := repo.LockPullRequest(prID); err != nil {
+				return nil, err
+			}
+			pr, err := repo.GetPullRequest(prID)
+			if err != nil {
+				return nil, err
+			}
+			if declined[prID], err = repo.GetDeclinedReviewerIDs(prID); err != nil {
+				return nil, err
+			}
+			// Старших ищу среди участников команды и ревьюеров этого PR, поэтому правила - на каждый PR
+			if rules[prID], err = authorReviewRules(repo, pr.AuthorID, append(memberIDs, pr.AssignedReviewers...)); err != nil {
+				return nil, err
+			}
+			prs[prID] = pr
+			return pr, nil
+		}
+
+		// Каждый перенос уменьшает разброс, так что больше переносов, чем открытых ревью, не бывает
+		totalReviews := 0
+		for _, prIDs := range reviews {
+			totalReviews += len(prIDs)
+		}
+
+		for moves := 0; moves < totalReviews; moves++ {
+			members := sortedByLoad(reviews)
+			if len(members) < 2 {
+				break
+			}
+
+			moved := false
+			// Начинаю с самого загруженного, но если у него нечего переложить - пробую следующего
+			for i := len(members) - 1; i > 0 && !moved; i-- {
+				from := members[i]
+				for _, prID := range reviews[from] {
+					pr, err := getPR(prID)
+					if err != nil {
+						return err
+					}
+					if pr.Status != models.StatusOpen {
+						continue
+					}
+
+					to := pickRebalanceTarget(members[:i], reviews, len(reviews[from]), pr, from, declined[prID], rules[prID])
+					if to == "" {
+						continue
+					}
+
+					if err := repo.ReassignReviewer(prID, from, to); err != nil {
+						return err
+					}
+					if err := recordReassignment(repo, prID, from, to); err != nil {
+						return err
+					}
+
+					for j, reviewerID := range pr.AssignedReviewers {
+						if reviewerID == from {
+							pr.AssignedReviewers[j] = to
+						}
+					}
+					reviews[from] = removeString(reviews[from], prID)
+					reviews[to] = append(reviews[to], prID)
+					result.Reassignments = append(result.Reassignments, models.ReviewerReassignment{
+						PullRequestID: prID,
+						OldReviewerID: from,
+						NewReviewerID: to,
+					})
+					moved = true
+					break
+				}
+			}
+			if !moved {
+				break
+			}
+		}
+
+		for userID, prIDs := range reviews {
+			result.LoadAfter[userID] = len(prIDs)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// sortedByLoad - участники по возрастанию числа открытых ревью, при равенстве по user_id,
+// чтобы результат был воспроизводимым
+func sortedByLoad(reviews map[string][]string) []string {
+	members := make([]string, 0, len(reviews))
+	for userID := range reviews {
+		members = append(members, userID)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if len(reviews[members[i]]) != len(reviews[members[j]]) {
+			return len(reviews[members[i]]) < len(reviews[members[j]])
+		}
+		return members[i] < members[j]
+	})
+	return members
+}
+
+// pickRebalanceTarget - самый свободный участник, которому можно отдать PR.
+// Переносить есть смысл, только если у него хотя бы на два ревью меньше, иначе просто поменяю их местами.
+// declined - кто уже отказался от этого PR, им его не отдаю. Правила команды автора тоже соблюдаю:
+// never_review, и если from - единственный старший на PR, отдаю только другому старшему.
+func pickRebalanceTarget(candidates []string, reviews map[string][]string, fromLoad int, pr *models.PullRequest, from string, declined []string, rules *models.ReviewRules) string {
+	needSenior := rules.RequireSenior && containsString(rules.Seniors, from) && !hasSenior(rules, withoutString(pr.AssignedReviewers, from))
+	for _, userID := range candidates {
+		if len(reviews[userID]) > fromLoad-2 {
+			return ""
+		}
+		if userID == pr.AuthorID || containsString(declined, userID) || neverReviews(rules, userID, pr.AuthorID) {
+			continue
+		}
+		if needSenior && !containsString(rules.Seniors, userID) {
+			continue
+		}
+		assigned := false
+		for _, reviewerID := range pr.AssignedReviewers {
+			if reviewerID == userID {
+				assigned = true
+				break
+			}
+		}
+		if !assigned {
+			return userID
+		}
+	}
+	return ""
+}
+
+func removeString(values []string, value string) []string {
+	for i, v := range values {
+		if v == value {
+			return append(values[:i], values[i+1:]...)
+		}
+	}
+	return values
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"fmt"
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+)
+
+// maxReviewers - больше двух ревьюеров на PR не бывает, ни при автоназначении, ни вручную
+const maxReviewers = 2
+
+// AddReviewer - вручную добавляю выбранного ревьюера на открытый PR.
+// Команда тут не важна: лид может позвать человека со стороны.
+// expectedVersions приходят из If-Match (подходит любая), nil - не проверять.
+func (s *Service) AddReviewer(prID, reviewerID string, expectedVersions []int) (*models.PullRequest, error) {
+	var updated *models.PullRequest
+	err := s.runInTx(false, func(repo *repository.Repository) error {
+		pr, err := lockOpenPullRequest(repo, prID, expectedVersions)
+		if err != nil {
+			return err
+		}
+		if len(pr.AssignedReviewers) >= maxReviewers {
+			return fmt.Errorf("REVIEWERS_FULL: pull request already has %d reviewers", maxReviewers)
+		}
+		if err := checkEligibleReviewer(repo, pr, reviewerID); err != nil {
+			return err
+		}
+
+		if err := repo.AddReviewer(prID, reviewerID); err != nil {
+			return err
+		}
+		if err := recordEvents(repo, models.UserEventAssigned, prID, reviewerID); err != nil {
+			return err
+		}
+
+		updated, err = repo.GetPullRequest(prID)
+		if err != nil {
+			return err
+		}
+		return enqueueReviewerChange(repo, models.OutboxReviewerAdded, updated, reviewerID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// RemoveReviewer - снимаю ревьюера с открытого PR без замены. needMoreReviewers пересчитывается,
+// так что такой PR подхватят эскалация по SLA и добор при активации пользователей.
+func (s *Service) RemoveReviewer(prID, reviewerID string, expectedVersions []int) (*models.PullRequest, error) {
+	var updated *models.PullRequest
+	err := s.runInTx(false, func(repo *repository.Repository) error {
+		if _, err := lockOpenPullRequest(repo, prID, expectedVersions); err != nil {
+			return err
+		}
+
+		if err := repo.RemoveReviewer(prID, reviewerID); err != nil {
+			if err.Error() == "reviewer is not assigned to this PR" {
+				return fmt.Errorf("NOT_ASSIGNED")
+			}
+			return err
+		}
+		if err := recordEvents(repo, models.UserEventUnassigned, prID, reviewerID); err != nil {
+			return err
+		}
+
+		var err error
+		updated, err = repo.GetPullRequest(prID)
+		if err != nil {
+			return err
+		}
+		return enqueueReviewerChange(repo, models.OutboxReviewerRemoved, updated, reviewerID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// lockOpenPullRequest - блокирую PR, сверяю версию и проверяю, что его ещё можно менять
+func lockOpenPullRequest(repo *repository.Repository, prID string, expectedVersions []int) (*models.PullRequest, error) {
+	if err := repo.LockPullRequest(prID); err != nil {
+		return nil, err
+	}
+
+	pr, err := repo.GetPullRequest(prID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(expectedVersions, pr.Version); err != nil {
+		return nil, err
+	}
+	if pr.Status == models.StatusMerged {
+		return nil, fmt.Errorf("PR_MERGED")
+	}
+	if pr.Status == models.StatusClosed {
+		return nil, fmt.Errorf("PR_CLOSED")
+	}
+	return pr, nil
+}
+
+// checkEligibleReviewer - можно ли назначить reviewerID на PR руками: пользователь есть,
+// активен, не автор, ещё не назначен и never_review команды автора не запрещает.
+// Остальные правила команды - пожелания к автоподбору, лид их может обойти.
+func checkEligibleReviewer(repo *repository.Repository, pr *models.PullRequest, reviewerID string) error {
+	user, err := repo.GetUser(reviewerID)
+	if err != nil {
+		return fmt.Errorf("reviewer not found")
+	}
+	if !user.IsActive {
+		return fmt.Errorf("NOT_ELIGIBLE: user %s is inactive", reviewerID)
+	}
+	if reviewerID == pr.AuthorID {
+		return fmt.Errorf("NOT_ELIGIBLE: author cannot review own pull request")
+	}
+	for _, assigned := range pr.AssignedReviewers {
+		if assigned == reviewerID {
+			return fmt.Errorf("NOT_ELIGIBLE: user %s is already assigned", reviewerID)
+		}
+	}
+
+	// Здесь нужен только never_review, старших не читаю
+	rules, err := authorReviewRules(repo, pr.AuthorID, nil)
+	if err != nil {
+		return err
+	}
+	if neverReviews(rules, reviewerID, pr.AuthorID) {
+		return fmt.Errorf("NOT_ELIGIBLE: blocked by rule %s", models.RuleNeverReview)
+	}
+	return nil
+}
+
+// reassignTo - меняю oldReviewerID на выбранного вручную newReviewerID. PR уже должен быть заблокирован.
+func (s *Service) reassignTo(repo *repository.Repository, pr *models.PullRequest, oldReviewerID, newReviewerID string) error {
+	if err := checkEligibleReviewer(repo, pr, newReviewerID); err != nil {
+		return err
+	}
+	if err := repo.ReassignReviewer(pr.PullRequestID, oldReviewerID, newReviewerID); err != nil {
+		if err.Error() == "reviewer is not assigned to this PR" {
+			return fmt.Errorf("NOT_ASSIGNED")
+		}
+		return err
+	}
+	return recordReassignment(repo, pr.PullRequestID, oldReviewerID, newReviewerID)
+}
+
+// enqueueReviewerChange - доменное событие о ручном добавлении или снятии ревьюера
+func enqueueReviewerChange(repo *repository.Repository, eventType models.OutboxEventType, pr *models.PullRequest, reviewerID string) error {
+	return enqueueOutbox(repo, models.AggregatePullRequest, pr.PullRequestID, eventType, models.ReviewerChange{
+		PullRequestID:     pr.PullRequestID,
+		ReviewerID:        reviewerID,
+		NeedMoreReviewers: pr.NeedMoreReviewers,
+	})
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"fmt"
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+	"strings"
+)
+
+// SetReviewRules - правила подбора ревьюеров на PR авторов команды. Пустые правила удаляют запись.
+// Старшие - это seniors из правил и все с уровнем senior или lead, так что require_senior
+// можно включить и без seniors, если в команде есть старшие по уровню.
+func (s *Service) SetReviewRules(rules *models.ReviewRules) (*models.ReviewRules, error) {
+	if err := normalizeReviewRules(rules); err != nil {
+		return nil, err
+	}
+
+	err := s.runInTx(false, func(repo *repository.Repository) error {
+		if _, err := repo.GetTeam(rules.TeamName); err != nil {
+			return err
+		}
+		return saveReviewRules(repo, rules)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// saveReviewRules - проверяю уже нормализованные правила по базе и сохраняю их.
+// Общее для /team/setReviewRules и импорта; команда уже должна существовать.
+func saveReviewRules(repo *repository.Repository, rules *models.ReviewRules) error {
+	// Правила действуют только внутри команды, так что чужие пользователи в них - ошибка, а не пустое правило
+	for _, userID := range rulesUserIDs(rules) {
+		user, err := repo.GetUser(userID)
+		if err != nil {
+			return fmt.Errorf("invalid rules: unknown user %s", userID)
+		}
+		if user.TeamName != rules.TeamName {
+			return fmt.Errorf("invalid rules: user %s is not a member of team %s", userID, rules.TeamName)
+		}
+	}
+	if rules.RequireSenior && len(rules.Seniors) == 0 {
+		members, err := repo.GetActiveUsersByTeams([]string{rules.TeamName})
+		if err != nil {
+			return err
+		}
+		if !anySenior(members[rules.TeamName]) {
+			return fmt.Errorf("invalid rules: require_senior needs seniors or team members with senior or lead seniority")
+		}
+	}
+
+	if len(rules.NeverReview) == 0 && len(rules.PreferredPairs) == 0 && len(rules.Seniors) == 0 &&
+		!rules.RequireSenior && !rules.MatchSkills {
+		return repo.DeleteReviewRules(rules.TeamName)
+	}
+	return repo.SetReviewRules(rules)
+}
+
+// GetReviewRules - правила команды; если их не задавали, отдаю пустые
+func (s *Service) GetReviewRules(teamName string) (*models.ReviewRules, error) {
+	if _, err := s.repo.GetTeam(teamName); err != nil {
+		return nil, err
+	}
+	return teamReviewRules(s.repo, teamName)
+}
+
+// normalizeReviewRules - убираю повторы и проверяю, что правила не противоречат сами себе
+func normalizeReviewRules(rules *models.ReviewRules) error {
+	neverReview := make([]models.UserPair, 0, len(rules.NeverReview))
+	seenPairs := make(map[models.UserPair]bool)
+	for _, pair := range rules.NeverReview {
+		if pair.UserA == "" || pair.UserB == "" || pair.UserA == pair.UserB {
+			return fmt.Errorf("invalid rules: never_review needs two different users")
+		}
+		// Пара симметричная, так что храню её в одном порядке
+		if pair.UserA > pair.UserB {
+			pair.UserA, pair.UserB = pair.UserB, pair.UserA
+		}
+		if !seenPairs[pair] {
+			seenPairs[pair] = true
+			neverReview = append(neverReview, pair)
+		}
+	}
+
+	preferred := make([]models.PreferredPair, 0, len(rules.PreferredPairs))
+	seenPreferred := make(map[models.PreferredPair]bool)
+	for _, pair := range rules.PreferredPairs {
+		if pair.ReviewerID == "" || pair.AuthorID == "" || pair.ReviewerID == pair.AuthorID {
+			return fmt.Errorf("invalid rules: preferred_pairs needs two different users")
+		}
+		if neverReviews(&models.ReviewRules{NeverReview: neverReview}, pair.ReviewerID, pair.AuthorID) {
+			return fmt.Errorf("invalid rules: %s and %s are both preferred and never_review", pair.ReviewerID, pair.AuthorID)
+		}
+		if !seenPreferred[pair] {
+			seenPreferred[pair] = true
+			preferred = append(preferred, pair)
+		}
+	}
+
+	seniors := make([]string, 0, len(rules.Seniors))
+	for _, userID := range rules.Seniors {
+		if userID == "" {
+			return fmt.Errorf("invalid rules: empty user_id in seniors")
+		}
+		if !containsString(seniors, userID) {
+			seniors = append(seniors, userID)
+		}
+	}
+
+	rules.NeverReview = neverReview
+	rules.PreferredPairs = preferred
+	rules.Seniors = seniors
+	return nil
+}
+
+// rulesUserIDs - все пользователи, которые упоминаются в правилах, без повторов
+func rulesUserIDs(rules *models.ReviewRules) []string {
+	userIDs := make([]string, 0)
+	add := func(ids ...string) {
+		for _, id := range ids {
+			if !containsString(userIDs, id) {
+				userIDs = append(userIDs, id)
+			}
+		}
+	}
+	for _, pair := range rules.NeverReview {
+		add(pair.UserA, pair.UserB)
+	}
+	for _, pair := range rules.PreferredPairs {
+		add(pair.ReviewerID, pair.AuthorID)
+	}
+	add(rules.Seniors...)
+	return userIDs
+}
+
+// teamReviewRules - правила команды, пустые, если их не задавали
+func teamReviewRules(repo *repository.Repository, teamName string) (*models.ReviewRules, error) {
+	rules, err := repo.GetReviewRules(teamName)
+	if err != nil {
+		if err.Error() == "review rules not found" {
+			return &models.ReviewRules{
+				TeamName:       teamName,
+				NeverReview:    []models.UserPair{},
+				PreferredPairs: []models.PreferredPair{},
+				Seniors:        []string{},
+			}, nil
+		}
+		return nil, err
+	}
+	return rules, nil
+}
+
+// effectiveReviewRules - правила команды для подбора ревьюеров: к seniors из правил
+// добавляю старших по уровню среди userIDs - кандидатов и тех, кто останется на PR.
+// Они нужны только для require_senior, без него (или без userIDs) не читаю.
+func effectiveReviewRules(repo *repository.Repository, teamName string, userIDs []string) (*models.ReviewRules, error) {
+	rules, err := teamReviewRules(repo, teamName)
+	if err != nil || !rules.RequireSenior || len(userIDs) == 0 {
+		return rules, err
+	}
+	seniors, err := repo.GetSeniorUserIDs(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range seniors {
+		if !containsString(rules.Seniors, userID) {
+			rules.Seniors = append(rules.Seniors, userID)
+		}
+	}
+	return rules, nil
+}
+
+// authorReviewRules - правила действуют по команде автора PR: это его команда решает,
+// кто может смотреть его код. userIDs - как в effectiveReviewRules.
+func authorReviewRules(repo *repository.Repository, authorID string, userIDs []string) (*models.ReviewRules, error) {
+	teamName, err := repo.GetUserTeam(authorID)
+	if err != nil {
+		return nil, err
+	}
+	return effectiveReviewRules(repo, teamName, userIDs)
+}
+
+// seniorityScope - чей уровень важен для require_senior: уже назначенные и все кандидаты
+func seniorityScope(assigned []string, candidates ...[]*models.User) []string {
+	userIDs := append([]string{}, assigned...)
+	for _, users := range candidates {
+		for _, user := range users {
+			userIDs = append(userIDs, user.UserID)
+		}
+	}
+	return userIDs
+}
+
+// selectReviewers - до count ревьюеров на PR из candidates (уже без назначенных и автора) по правилам
+// команды автора. kept - ревьюеры, которые останутся на PR, нужны для правила require_senior.
+// blockedBy - правило, из-за которого выбрать не вышло: never_review отсеял всех, или старшего нет.
+func (s *Service) selectReviewers(repo *repository.Repository, pr *models.PullRequest, candidates []*models.User, kept []string, count int) ([]string, string, error) {
+	rules, err := authorReviewRules(repo, pr.AuthorID, seniorityScope(kept, candidates))
+	if err != nil {
+		return nil, "", err
+	}
+	return s.selectReviewersByRules(repo, pr, rules, candidates, kept, count, nil)
+}
+
+// selectReviewersByRules - selectReviewers с уже загруженными правилами, pending - как в rankCandidates
+func (s *Service) selectReviewersByRules(repo *repository.Repository, pr *models.PullRequest, rules *models.ReviewRules, candidates []*models.User, kept []string, count int, pending map[string]int) ([]string, string, error) {
+	ranked, err := s.rankCandidates(repo, pr, candidates, pending)
+	if err != nil {
+		return nil, "", err
+	}
+	if rules.MatchSkills {
+		ranked = preferSkilled(ranked, candidates, pr.Labels)
+	}
+	selected, blockedBy := pickByRules(rules, pr.AuthorID, ranked, kept, count)
+	return selected, blockedBy, nil
+}
+
+// pickByRules - беру до count из ranked по порядку, но: без тех, кому never_review запрещает
+// смотреть PR автора; предпочтительные ревьюеры автора идут первыми; если команда требует
+// старшего, а среди kept его нет, первым беру старшего, а без него не беру никого.
+func pickByRules(rules *models.ReviewRules, authorID string, ranked, kept []string, count int) ([]string, string) {
+	if count <= 0 {
+		return []string{}, ""
+	}
+
+	allowed := make([]string, 0, len(ranked))
+	for _, userID := range ranked {
+		if !neverReviews(rules, userID, authorID) {
+			allowed = append(allowed, userID)
+		}
+	}
+	if len(allowed) == 0 && len(ranked) > 0 {
+		return []string{}, models.RuleNeverReview
+	}
+
+	// Предпочтительных ставлю вперёд, сохраняя порядок внутри обеих групп
+	ordered := make([]string, 0, len(allowed))
+	for _, userID := range allowed {
+		if isPreferred(rules, userID, authorID) {
+			ordered = append(ordered, userID)
+		}
+	}
+	for _, userID := range allowed {
+		if !isPreferred(rules, userID, authorID) {
+			ordered = append(ordered, userID)
+		}
+	}
+
+	selected := make([]string, 0, count)
+	if rules.RequireSenior && !hasSenior(rules, kept) {
+		senior := ""
+		for _, userID := range ordered {
+			if containsString(rules.Seniors, userID) {
+				senior = userID
+				break
+			}
+		}
+		if senior == "" {
+			return []string{}, models.RuleRequireSenior
+		}
+		selected = append(selected, senior)
+	}
+	for _, userID := range ordered {
+		if len(selected) >= count {
+			break
+		}
+		if !containsString(selected, userID) {
+			selected = append(selected, userID)
+		}
+	}
+	return selected, ""
+}
+
+// neverReviews - запрещено ли правилами ставить reviewerID на PR authorID
+func neverReviews(rules *models.ReviewRules, reviewerID, authorID string) bool {
+	for _, pair := range rules.NeverReview {
+		if (pair.UserA == reviewerID && pair.UserB == authorID) || (pair.UserA == authorID && pair.UserB == reviewerID) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPreferred(rules *models.ReviewRules, reviewerID, authorID string) bool {
+	for _, pair := range rules.PreferredPairs {
+		if pair.ReviewerID == reviewerID && pair.AuthorID == authorID {
+			return true
+		}
+	}
+	return false
+}
+
+func hasSenior(rules *models.ReviewRules, userIDs []string) bool {
+	for _, userID := range userIDs {
+		if containsString(rules.Seniors, userID) {
+			return true
+		}
+	}
+	return false
+}
+
+// noCandidate - NO_CANDIDATE с правилом, из-за которого кандидата не нашлось (если дело в правиле)
+func noCandidate(blockedBy string) error {
+	if blockedBy == "" {
+		return fmt.Errorf("NO_CANDIDATE")
+	}
+	return fmt.Errorf("NO_CANDIDATE: blocked by rule %s", blockedBy)
+}
+
+func isNoCandidate(err error) bool {
+	code, _ := models.ErrorStatus(err)
+	return code == models.ErrorNoCandidate
+}
+
+// blockedRule - правило из ошибки noCandidate, "" если дело не в правиле
+func blockedRule(err error) string {
+	const prefix = "NO_CANDIDATE: blocked by rule "
+	if !strings.HasPrefix(err.Error(), prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(err.Error(), prefix)
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"fmt"
+	"math/rand"
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+	"sort"
+	"time"
+)
+
+// SecurityLabel - PR с этой меткой должен смотреть хотя бы один участник команды безопасности
+const SecurityLabel = "security"
+
+// SetSecurityReviewTeam - команда, из которой обязательно назначаю ревьюера на PR с меткой security.
+// Пустая строка выключает правило.
+func (s *Service) SetSecurityReviewTeam(teamName string) {
+	s.securityReviewTeam = teamName
+}
+
+// needsSecurityReviewer - нужен ли PR ревьюер из команды безопасности сверх обычного выбора.
+// Если автор сам из этой команды, любой его коллега уже подходит.
+func (s *Service) needsSecurityReviewer(pr *models.PullRequest, authorTeam string) bool {
+	if s.securityReviewTeam == "" || authorTeam == s.securityReviewTeam {
+		return false
+	}
+	for _, label := range pr.Labels {
+		if label == SecurityLabel {
+			return true
+		}
+	}
+	return false
+}
+
+// chooseReviewers - выбираю до count ревьюеров на новый PR из candidates (команда автора)
+// с учётом правил команды автора. На PR с меткой security первого беру из команды безопасности,
+// а если там никого нет - NO_CANDIDATE: такой PR без её ревьюера заводить нельзя.
+// Так же NO_CANDIDATE, если команда требует старшего, а поставить некого.
+func (s *Service) chooseReviewers(repo *repository.Repository, pr *models.PullRequest, authorTeam string, candidates []*models.User, count int) ([]string, error) {
+	var securityCandidates []*models.User
+	if s.needsSecurityReviewer(pr, authorTeam) {
+		var err error
+		if securityCandidates, err = repo.GetActiveUsersByTeam(s.securityReviewTeam, pr.AuthorID); err != nil {
+			return nil, err
+		}
+	}
+	rules, err := effectiveReviewRules(repo, authorTeam, seniorityScope(pr.AssignedReviewers, candidates, securityCandidates))
+	if err != nil {
+		return nil, err
+	}
+	return s.chooseReviewersByRules(repo, pr, authorTeam, rules, securityCandidates, candidates, nil, count)
+}
+
+// chooseReviewersByRules - то же, что chooseReviewers, но правила и участники команды безопасности
+// уже загружены: пакетное создание читает их один раз на весь пакет.
+// На PR, где уже есть ревьюеры (добор), они остаются, а возвращаю только новых: ревьюера безопасности
+// ищу, только если среди назначенных его ещё нет. pending - ревью, назначенные раньше в том же пакете,
+// но ещё не записанные в базу.
+func (s *Service) chooseReviewersByRules(repo *repository.Repository, pr *models.PullRequest, authorTeam string, rules *models.ReviewRules, securityCandidates, candidates []*models.User, pending map[string]int, count int) ([]string, error) {
+	reviewers := make([]string, 0, count)
+
+	needsSecurity := s.needsSecurityReviewer(pr, authorTeam)
+	if needsSecurity && len(pr.AssignedReviewers) > 0 {
+		covered, err := s.hasSecurityReviewer(repo, pr.AssignedReviewers)
+		if err != nil {
+			return nil, err
+		}
+		needsSecurity = !covered
+	}
+	if needsSecurity && count > 0 {
+		securityCandidates = s.filterAssignedReviewers(securityCandidates, pr.AssignedReviewers, pr.AuthorID)
+		ranked, err := s.rankCandidates(repo, pr, securityCandidates, pending)
+		if err != nil {
+			return nil, err
+		}
+		// Из команды безопасности действует только never_review: старшего доберу из команды автора
+		selected, _ := pickByRules(&models.ReviewRules{NeverReview: rules.NeverReview}, pr.AuthorID, ranked, nil, 1)
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("NO_CANDIDATE: no active reviewer in security review team")
+		}
+		reviewers = append(reviewers, selected[0])
+		count--
+	}
+
+	// Команды не пересекаются, так что ревьюер безопасности среди candidates не повторится
+	kept := append(append([]string{}, pr.AssignedReviewers...), reviewers...)
+	selected, blockedBy, err := s.selectReviewersByRules(repo, pr, rules, candidates, kept, count, pending)
+	if err != nil {
+		return nil, err
+	}
+	// Если never_review отсеял всех, PR просто остаётся с needMoreReviewers, как в команде без кандидатов.
+	// А без старшего PR не создаю.
+	if blockedBy == models.RuleRequireSenior {
+		return nil, noCandidate(blockedBy)
+	}
+	return append(reviewers, selected...), nil
+}
+
+// hasSecurityReviewer - есть ли среди reviewerIDs участник команды безопасности
+func (s *Service) hasSecurityReviewer(repo *repository.Repository, reviewerIDs []string) (bool, error) {
+	users, err := repo.GetUsersByIDs(reviewerIDs)
+	if err != nil {
+		return false, err
+	}
+	for _, user := range users {
+		if user.TeamName == s.securityReviewTeam {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// rankCandidates - кандидаты в порядке выбора. Обычно порядок случайный,
+// а для urgent первыми идут те, у кого меньше всего открытых ревью (при равенстве - случайно).
+// pending добавляется к открытым ревью из базы, nil - ничего не добавлять.
+func (s *Service) rankCandidates(repo *repository.Repository, pr *models.PullRequest, candidates []*models.User, pending map[string]int) ([]string, error) {
+	ranked := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		ranked = append(ranked, candidate.UserID)
+	}
+	rand.Seed(time.Now().UnixNano())
+	rand.Shuffle(len(ranked), func(i, j int) {
+		ranked[i], ranked[j] = ranked[j], ranked[i]
+	})
+
+	if pr.Priority != models.PriorityUrgent {
+		return ranked, nil
+	}
+	load, err := repo.GetOpenReviewCounts(ranked)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return load[ranked[i]]+pending[ranked[i]] < load[ranked[j]]+pending[ranked[j]]
+	})
+	return ranked, nil
+}
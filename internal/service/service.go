@@ -1,8 +1,8 @@
 package service
 
 import (
+	"errors"
 	"fmt"
-	"math/rand"
 	"pr-reviewer-service/internal/models"
 	"pr-reviewer-service/internal/repository"
 	"time"
@@ -11,10 +11,47 @@ import (
 // Service - тут вся основная логика работы с PR и ревьюерами
 type Service struct {
 	repo *repository.Repository
+	// reassignOnDeactivate - переназначать ли открытые ревью при деактивации одного пользователя,
+	// если в запросе явно не сказано иначе
+	reassignOnDeactivate bool
+	// events - шина событий для /users/stream
+	events *eventBus
+	// securityReviewTeam - из этой команды обязательно назначаю ревьюера на PR с меткой security
+	securityReviewTeam string
 }
 
 func NewService(repo *repository.Repository) *Service {
-	return &Service{repo: repo}
+	s := &Service{repo: repo, reassignOnDeactivate: true, events: newEventBus()}
+	// События пользователей раздаю подписчикам после коммита, в порядке коммитов
+	repo.SetEventPublisher(s.events.publish)
+	return s
+}
+
+// SetReassignOnDeactivate - меняю поведение по умолчанию для SetUserActive
+func (s *Service) SetReassignOnDeactivate(enabled bool) {
+	s.reassignOnDeactivate = enabled
+}
+
+// errDryRun - этой ошибкой откатываю транзакцию в режиме dry_run, наружу она не уходит
+var errDryRun = errors.New("dry run")
+
+// runInTx - выполняю операцию в одной транзакции.
+// В режиме dryRun операция проходит целиком по тому же коду, но в конце всё откатывается.
+// События пользователей, записанные в транзакции, репозиторий отдаёт подписчикам только после коммита.
+func (s *Service) runInTx(dryRun bool, fn func(repo *repository.Repository) error) error {
+	err := s.repo.WithTx(func(tx *repository.Repository) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	return err
 }
 
 // Teams
@@ -31,7 +68,18 @@ func (s *Service) CreateTeam(team *models.Team) error {
 		return err
 	}
 
-	// Создаю или обновляю пользователей в команде
+	if err := upsertTeamMembers(s.repo, team); err != nil {
+		return err
+	}
+
+	// Только что созданная команда всегда первой версии
+	team.Version = 1
+	return nil
+}
+
+// upsertTeamMembers - создаю или обновляю пользователей в команде.
+// Если пользователь был в другой команде, он переезжает в эту.
+func upsertTeamMembers(repo *repository.Repository, team *models.Team) error {
 	for _, member := range team.Members {
 		user := &models.User{
 			UserID:   member.UserID,
@@ -39,11 +87,10 @@ func (s *Service) CreateTeam(team *models.Team) error {
 			TeamName: team.TeamName,
 			IsActive: member.IsActive,
 		}
-		if err := s.repo.CreateOrUpdateUser(user); err != nil {
+		if err := repo.CreateOrUpdateUser(user); err != nil {
 			return err
 		}
 	}
-
 	return nil
 }
 
@@ -56,320 +103,682 @@ func (s *Service) GetTeam(teamName string) (*models.Team, error) {
 }
 
 // Users
-func (s *Service) SetUserActive(userID string, isActive bool) (*models.User, error) {
-	if err := s.repo.UpdateUserActive(userID, isActive); err != nil {
+func (s *Service) GetUser(userID string) (*models.User, error) {
+	return s.repo.GetUser(userID)
+}
+
+// SetUserActive - включаю или выключаю пользователя. При деактивации его открытые ревью
+// переназначаются так же, как при массовой деактивации, если reassignReviews не запрещает
+// (nil - берётся настройка сервиса). Это изменение состава команды, поэтому expectedVersions
+// сверяются с версией команды пользователя (из If-Match, nil - не проверять).
+func (s *Service) SetUserActive(userID string, isActive bool, reassignReviews *bool, expectedVersions []int) (*models.SetUserActiveResult, error) {
+	reassign := s.reassignOnDeactivate
+	if reassignReviews != nil {
+		reassign = *reassignReviews
+	}
+
+	var result *models.SetUserActiveResult
+	err := s.runInTx(false, func(repo *repository.Repository) error {
+		// Транзакция может повториться, поэтому результат собираю заново на каждой попытке
+		result = &models.SetUserActiveResult{
+			ReassignedPRs:    []string{},
+			Reassignments:    []models.ReviewerReassignment{},
+			WithoutCandidate: []models.ReviewerReassignment{},
+		}
+
+		user, err := repo.GetUser(userID)
+		if err != nil {
+			return err
+		}
+
+		// Порядок блокировок как в массовых операциях: команда, пользователь, потом PR.
+		// Команду блокирую и для проверки версии, чтобы её не поменяли между проверкой и ответом.
+		if user.IsActive != isActive || expectedVersions != nil {
+			if err := repo.LockTeam(user.TeamName); err != nil {
+				return err
+			}
+		}
+		if expectedVersions != nil {
+			team, err := repo.GetTeam(user.TeamName)
+			if err != nil {
+				return err
+			}
+			if err := checkVersion(expectedVersions, team.Version); err != nil {
+				return err
+			}
+		}
+
+		// Если статус не меняется, версию команды не трогаю
+		if user.IsActive != isActive {
+			if _, err := repo.LockUsers([]string{userID}); err != nil {
+				return err
+			}
+
+			if !isActive && reassign {
+				result.ReassignedPRs, result.Reassignments, result.WithoutCandidate, err = s.reassignOpenReviews(repo, []string{userID})
+				if err != nil {
+					return err
+				}
+			}
+
+			if err := repo.UpdateUserActive(userID, isActive); err != nil {
+				return err
+			}
+			if !isActive {
+				if err := enqueueUsersDeactivated(repo, user.TeamName, []string{userID}); err != nil {
+					return err
+				}
+			}
+			if err := repo.BumpTeamVersion(user.TeamName); err != nil {
+				return err
+			}
+		}
+
+		result.User, err = repo.GetUser(userID)
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
-	return s.repo.GetUser(userID)
+	return result, nil
 }
 
 // Pull Requests
 
 // CreatePullRequest - логика создания PR и назначения ревьюеров.
-func (s *Service) CreatePullRequest(prID, prName, authorID string) (*models.PullRequest, error) {
-	// Сначала проверяю, нет ли уже PR с таким ID.
-	exists, err := s.repo.PullRequestExists(prID)
+// Выбор ревьюеров и запись идут в одной транзакции, поэтому параллельные запросы
+// с одним ID не создадут два PR, а выбранного кандидата не деактивируют на полпути.
+func (s *Service) CreatePullRequest(prID, prName, authorID string, labels []string, priority models.PullRequestPriority) (*models.PullRequest, error) {
+	labels, err := normalizeLabels(labels)
 	if err != nil {
 		return nil, err
 	}
-	if exists {
-		return nil, fmt.Errorf("PR_EXISTS")
-	}
-
-	// Нахожу автора и его команду.
-	author, err := s.repo.GetUser(authorID)
+	priority, err = normalizePriority(priority)
 	if err != nil {
-		return nil, fmt.Errorf("author not found")
+		return nil, err
 	}
 
-	// Ищу всех активных ребят из его команды, кроме него самого.
-	candidates, err := s.repo.GetActiveUsersByTeam(author.TeamName, authorID)
+	// Сначала проверяю, нет ли уже PR с таким ID. Это просто быстрый путь,
+	// окончательно дубликат отсекается при вставке.
+	exists, err := s.repo.PullRequestExists(prID)
 	if err != nil {
 		return nil, err
 	}
+	if exists {
+		return nil, fmt.Errorf("PR_EXISTS")
+	}
+
+	var created *models.PullRequest
+	err = s.runInTx(false, func(repo *repository.Repository) error {
+		// Нахожу автора и его команду.
+		author, err := repo.GetUser(authorID)
+		if err != nil {
+			return fmt.Errorf("author not found")
+		}
 
-	// Выбираю до 2-х случайных ревьюеров из списка кандидатов.
-	reviewers := s.selectRandomReviewers(candidates, 2)
-	needMoreReviewers := len(reviewers) < 2 // Если нашлось меньше двух, ставлю флаг.
+		// Ищу всех активных ребят из его команды, кроме него самого.
+		candidates, err := repo.GetActiveUsersByTeam(author.TeamName, authorID)
+		if err != nil {
+			return err
+		}
 
-	pr := &models.PullRequest{
-		PullRequestID:     prID,
-		PullRequestName:   prName,
-		AuthorID:          authorID,
-		Status:            models.StatusOpen,
-		AssignedReviewers: reviewers,
-		NeedMoreReviewers: needMoreReviewers,
-	}
+		pr := &models.PullRequest{
+			PullRequestID:   prID,
+			PullRequestName: prName,
+			AuthorID:        authorID,
+			Status:          models.StatusOpen,
+			Labels:          labels,
+			Priority:        priority,
+		}
 
-	// Сохраняю всё в базу.
-	if err := s.repo.CreatePullRequest(pr); err != nil {
+		// Выбираю до 2-х ревьюеров из списка кандидатов: случайно, а для urgent - самых свободных.
+		reviewers, err := s.chooseReviewers(repo, pr, author.TeamName, candidates, 2)
+		if err != nil {
+			return err
+		}
+		pr.AssignedReviewers = reviewers
+		pr.NeedMoreReviewers = len(reviewers) < 2 // Если нашлось меньше двух, ставлю флаг.
+
+		// Сохраняю всё в базу.
+		if err := repo.CreatePullRequest(pr); err != nil {
+			if err.Error() == "pull request already exists" {
+				return fmt.Errorf("PR_EXISTS")
+			}
+			return err
+		}
+		if err := recordEvents(repo, models.UserEventAssigned, prID, reviewers...); err != nil {
+			return err
+		}
+
+		// Возвращаю полный объект PR, чтобы в ответе были все поля.
+		created, err = repo.GetPullRequest(prID)
+		if err != nil {
+			return err
+		}
+		return enqueuePullRequestEvent(repo, models.OutboxPRCreated, created)
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// Возвращаю полный объект PR, чтобы в ответе были все поля.
-	return s.repo.GetPullRequest(prID)
+	return created, nil
 }
 
-func (s *Service) MergePullRequest(prID string) (*models.PullRequest, error) {
+// GetPullRequest - PR вместе с историей эскалаций по SLA, правок и отказов ревьюеров
+func (s *Service) GetPullRequest(prID string) (*models.PullRequest, error) {
 	pr, err := s.repo.GetPullRequest(prID)
 	if err != nil {
 		return nil, err
 	}
-
-	// Если он уже смержен, ничего не делаю, просто возвращаю его. Это для идемпотентности.
-	if pr.Status == models.StatusMerged {
-		return pr, nil
+	if pr.Escalations, err = s.repo.GetEscalations(prID); err != nil {
+		return nil, err
+	}
+	if pr.Audit, err = s.repo.GetAuditEntries(prID); err != nil {
+		return nil, err
 	}
+	if pr.Declines, err = s.repo.GetReviewDeclines(prID); err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+// MergePullRequest - мерж PR. expectedVersions приходят из If-Match (подходит любая), nil - не проверять.
+func (s *Service) MergePullRequest(prID string, expectedVersions []int) (*models.PullRequest, error) {
+	var merged *models.PullRequest
+	err := s.runInTx(false, func(repo *repository.Repository) error {
+		if err := repo.LockPullRequest(prID); err != nil {
+			return err
+		}
+
+		pr, err := repo.GetPullRequest(prID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(expectedVersions, pr.Version); err != nil {
+			return err
+		}
+
+		// Если он уже смержен, ничего не делаю, просто возвращаю его. Это для идемпотентности.
+		if pr.Status == models.StatusMerged {
+			merged = pr
+			return nil
+		}
+		// Закрытый политикой PR мержить уже нельзя
+		if pr.Status == models.StatusClosed {
+			return fmt.Errorf("PR_CLOSED")
+		}
+
+		if err := repo.MergePullRequest(prID); err != nil {
+			return err
+		}
+		if err := recordEvents(repo, models.UserEventMerged, prID, pr.AssignedReviewers...); err != nil {
+			return err
+		}
 
-	if err := s.repo.MergePullRequest(prID); err != nil {
+		merged, err = repo.GetPullRequest(prID)
+		if err != nil {
+			return err
+		}
+		return enqueuePullRequestEvent(repo, models.OutboxPRMerged, merged)
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	return s.repo.GetPullRequest(prID)
+	return merged, nil
 }
 
 // ReassignReviewer - логика переназначения ревьюера.
-func (s *Service) ReassignReviewer(prID, oldReviewerID string) (*models.PullRequest, string, error) {
-	pr, err := s.repo.GetPullRequest(prID)
-	if err != nil {
-		return nil, "", err
-	}
+// Если newReviewerID пустой, замену выбираю сам из команды старого ревьюера,
+// иначе ставлю указанного (после проверки, что его можно назначить).
+// В режиме dryRun возвращаю PR таким, каким он стал бы после замены, но ничего не сохраняю.
+// expectedVersions приходят из If-Match (подходит любая), nil - не проверять.
+func (s *Service) ReassignReviewer(prID, oldReviewerID, requestedReviewerID string, dryRun bool, expectedVersions []int) (*models.PullRequest, string, error) {
+	var updatedPR *models.PullRequest
+	var newReviewerID string
+
+	err := s.runInTx(dryRun, func(repo *repository.Repository) error {
+		// Сначала блокирую PR: пока я выбираю замену, никто другой его ревьюеров не трогает
+		if err := repo.LockPullRequest(prID); err != nil {
+			return err
+		}
 
-	// Нельзя переназначать ревьюеров на смерженном PR.
-	if pr.Status == models.StatusMerged {
-		return nil, "", fmt.Errorf("PR_MERGED")
-	}
+		pr, err := repo.GetPullRequest(prID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(expectedVersions, pr.Version); err != nil {
+			return err
+		}
 
-	// Проверяю, а был ли вообще такой ревьюер на этом PR.
-	found := false
-	for _, reviewerID := range pr.AssignedReviewers {
-		if reviewerID == oldReviewerID {
-			found = true
-			break
+		// Нельзя переназначать ревьюеров на смерженном или закрытом PR.
+		if pr.Status == models.StatusMerged {
+			return fmt.Errorf("PR_MERGED")
 		}
-	}
-	if !found {
-		return nil, "", fmt.Errorf("NOT_ASSIGNED")
+		if pr.Status == models.StatusClosed {
+			return fmt.Errorf("PR_CLOSED")
+		}
+
+		// Проверяю, а был ли вообще такой ревьюер на этом PR.
+		found := false
+		for _, reviewerID := range pr.AssignedReviewers {
+			if reviewerID == oldReviewerID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("NOT_ASSIGNED")
+		}
+
+		if requestedReviewerID != "" {
+			if err := s.reassignTo(repo, pr, oldReviewerID, requestedReviewerID); err != nil {
+				return err
+			}
+			newReviewerID = requestedReviewerID
+		} else {
+			newReviewerID, err = s.replaceReviewer(repo, pr, oldReviewerID)
+			if err != nil {
+				return err
+			}
+		}
+
+		updatedPR, err = repo.GetPullRequest(prID)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
 	}
 
+	return updatedPR, newReviewerID, nil
+}
+
+// replaceReviewer - ставлю вместо oldReviewerID случайного активного коллегу из его команды,
+// который ещё не назначен на PR, не является автором и подходит по правилам команды автора.
+// PR уже должен быть заблокирован.
+func (s *Service) replaceReviewer(repo *repository.Repository, pr *models.PullRequest, oldReviewerID string) (string, error) {
 	// Нахожу команду старого ревьюера, чтобы искать замену в ней же.
-	oldReviewerTeam, err := s.repo.GetUserTeam(oldReviewerID)
+	oldReviewerTeam, err := repo.GetUserTeam(oldReviewerID)
 	if err != nil {
-		return nil, "", fmt.Errorf("old reviewer not found")
+		return "", fmt.Errorf("old reviewer not found")
 	}
 
 	// Ищу кандидатов на замену.
-	candidates, err := s.repo.GetActiveUsersByTeam(oldReviewerTeam, oldReviewerID)
+	candidates, err := repo.GetActiveUsersByTeam(oldReviewerTeam, oldReviewerID)
 	if err != nil {
-		return nil, "", err
+		return "", err
 	}
 
-	// Убираю из кандидатов тех, кто уже назначен на этот PR.
-	availableCandidates := s.filterAssignedReviewers(candidates, pr.AssignedReviewers, pr.AuthorID)
+	// Убираю из кандидатов тех, кто уже назначен на этот PR или отказался от него.
+	unavailable, err := unavailableReviewers(repo, pr.PullRequestID, pr.AssignedReviewers)
+	if err != nil {
+		return "", err
+	}
+	availableCandidates := s.filterAssignedReviewers(candidates, unavailable, pr.AuthorID)
 	if len(availableCandidates) == 0 {
 		// Если некого назначить.
-		return nil, "", fmt.Errorf("NO_CANDIDATE")
+		return "", fmt.Errorf("NO_CANDIDATE")
+	}
+	// Выбираю случайного, а на urgent PR - самого свободного.
+	// Замена идёт из команды старого ревьюера, так что ревьюер безопасности сменится на такого же.
+	selected, blockedBy, err := s.selectReviewers(repo, pr, availableCandidates, withoutString(pr.AssignedReviewers, oldReviewerID), 1)
+	if err != nil {
+		return "", err
+	}
+	if len(selected) == 0 {
+		return "", noCandidate(blockedBy)
 	}
-	// Выбираю случайного.
-	newReviewerID := s.selectRandomReviewer(availableCandidates)
+	newReviewerID := selected[0]
 
 	// Обновляю инфу в базе.
-	if err := s.repo.ReassignReviewer(prID, oldReviewerID, newReviewerID); err != nil {
+	if err := repo.ReassignReviewer(pr.PullRequestID, oldReviewerID, newReviewerID); err != nil {
 		if err.Error() == "reviewer is not assigned to this PR" {
-			return nil, "", fmt.Errorf("NOT_ASSIGNED")
+			return "", fmt.Errorf("NOT_ASSIGNED")
 		}
-		return nil, "", err
+		return "", err
 	}
+	if err := recordReassignment(repo, pr.PullRequestID, oldReviewerID, newReviewerID); err != nil {
+		return "", err
+	}
+	return newReviewerID, nil
+}
+
+// ReviewPullRequest - ревьюер оставляет вердикт по PR. Это нужно для метрик:
+// по первому действию считаю, сколько ревьюер шёл до PR после назначения.
+func (s *Service) ReviewPullRequest(prID, reviewerID string, verdict models.ReviewVerdict) (*models.ReviewAction, error) {
+	switch verdict {
+	case models.VerdictApproved, models.VerdictChangesRequested, models.VerdictCommented:
+	default:
+		return nil, fmt.Errorf("invalid verdict: must be APPROVED, CHANGES_REQUESTED or COMMENTED")
+	}
+
+	var action *models.ReviewAction
+	err := s.runInTx(false, func(repo *repository.Repository) error {
+		// Блокирую PR, чтобы ревьюера не переназначили прямо между проверкой и записью
+		if err := repo.LockPullRequest(prID); err != nil {
+			return err
+		}
+
+		pr, err := repo.GetPullRequest(prID)
+		if err != nil {
+			return err
+		}
+		if pr.Status == models.StatusMerged {
+			return fmt.Errorf("PR_MERGED")
+		}
+		if pr.Status == models.StatusClosed {
+			return fmt.Errorf("PR_CLOSED")
+		}
 
-	updatedPR, err := s.repo.GetPullRequest(prID)
+		found := false
+		for _, assignedID := range pr.AssignedReviewers {
+			if assignedID == reviewerID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("NOT_ASSIGNED")
+		}
+
+		action, err = repo.CreateReviewAction(prID, reviewerID, verdict)
+		return err
+	})
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
-	return updatedPR, newReviewerID, nil
+	return action, nil
 }
 
-func (s *Service) GetPullRequestsByReviewer(reviewerID string) ([]*models.PullRequestShort, error) {
+// GetPullRequestsByReviewer - PR ревьюера. Из фильтра учитываются статус, приоритет и метка.
+func (s *Service) GetPullRequestsByReviewer(reviewerID string, filter models.PullRequestFilter) ([]*models.PullRequestShort, error) {
+	if err := validateFilter(filter); err != nil {
+		return nil, err
+	}
 	// Просто проверяю, что такой юзер есть, перед тем как искать его ревью.
 	_, err := s.repo.GetUser(reviewerID)
 	if err != nil {
 		return nil, fmt.Errorf("user not found")
 	}
 
-	return s.repo.GetPullRequestsByReviewer(reviewerID)
+	return s.repo.GetPullRequestsByReviewer(reviewerID, filter)
 }
 
-// Statistics - просто собираю статистику из репозитория
-func (s *Service) GetStatistics() (*models.StatisticsResponse, error) {
-	userStats, err := s.repo.GetUserReviewStats()
-	if err != nil {
-		return nil, err
+// StreamPullRequestsByReviewer - PR ревьюера по одному, для выгрузки в CSV/NDJSON
+func (s *Service) StreamPullRequestsByReviewer(reviewerID string, filter models.PullRequestFilter, fn func(pr *models.PullRequestShort) error) error {
+	if err := validateFilter(filter); err != nil {
+		return err
+	}
+	if _, err := s.repo.GetUser(reviewerID); err != nil {
+		return fmt.Errorf("user not found")
 	}
 
-	prStats, err := s.repo.GetPRStats()
+	return s.repo.StreamPullRequestsByReviewer(reviewerID, filter, fn)
+}
+
+// ListPullRequests - список PR по фильтру
+func (s *Service) ListPullRequests(filter models.PullRequestFilter) ([]*models.PullRequest, error) {
+	prs := make([]*models.PullRequest, 0)
+	err := s.StreamPullRequests(filter, func(pr *models.PullRequest) error {
+		prs = append(prs, pr)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return prs, nil
+}
 
-	// Преобразую указатели в значения для ответа
-	userStatsValues := make([]models.UserReviewStats, len(userStats))
-	for i, stat := range userStats {
-		userStatsValues[i] = *stat
+// StreamPullRequests - список PR по фильтру по одному, без сборки в память
+func (s *Service) StreamPullRequests(filter models.PullRequestFilter, fn func(pr *models.PullRequest) error) error {
+	if err := validateFilter(filter); err != nil {
+		return err
 	}
 
-	return &models.StatisticsResponse{
-		UserStats: userStatsValues,
-		PRStats:   *prStats,
-	}, nil
+	return s.repo.StreamPullRequests(filter, fn)
 }
 
-// BulkDeactivateTeam - массовая деактивация пользователей команды с безопасной переназначаемостью открытых PR
-func (s *Service) BulkDeactivateTeam(teamName string) ([]string, []string, error) {
-	// Проверяю, что команда существует
-	_, err := s.repo.GetTeam(teamName)
-	if err != nil {
-		return nil, nil, fmt.Errorf("team not found")
+// validateFilter - проверяю значения фильтра, пустые поля - без фильтра
+func validateFilter(filter models.PullRequestFilter) error {
+	switch models.PullRequestStatus(filter.Status) {
+	case "", models.StatusOpen, models.StatusMerged, models.StatusClosed:
+	default:
+		return fmt.Errorf("invalid status: must be OPEN, MERGED or CLOSED")
 	}
+	if filter.Priority != "" {
+		if _, err := normalizePriority(models.PullRequestPriority(filter.Priority)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BulkDeactivateTeam - массовая деактивация пользователей команды с безопасной переназначаемостью открытых PR.
+// В режиме dryRun считаю, кого деактивирую и кем заменю, но ничего не сохраняю.
+// expectedVersions - версии команды из If-Match (подходит любая), nil - не проверять.
+func (s *Service) BulkDeactivateTeam(teamName string, dryRun bool, expectedVersions []int) (*models.BulkDeactivationResult, error) {
+	var result *models.BulkDeactivationResult
+
+	err := s.runInTx(dryRun, func(repo *repository.Repository) error {
+		// Транзакция может повториться, поэтому результат собираю заново на каждой попытке
+		result = &models.BulkDeactivationResult{
+			DeactivatedUserIDs: []string{},
+			ReassignedPRs:      []string{},
+			Reassignments:      []models.ReviewerReassignment{},
+			WithoutCandidate:   []models.ReviewerReassignment{},
+			DryRun:             dryRun,
+		}
 
-	// Сначала получаю список пользователей, которых нужно деактивировать
-	// Делаю это до деактивации, чтобы потом найти открытые PR
-	deactivatedUserIDs, err := s.repo.GetUsersByTeamForDeactivation(teamName)
+		// Проверяю, что команда существует, и блокирую её от параллельной деактивации
+		if err := repo.LockTeam(teamName); err != nil {
+			return err
+		}
+		team, err := repo.GetTeam(teamName)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(expectedVersions, team.Version); err != nil {
+			return err
+		}
+
+		// Сначала получаю список пользователей, которых нужно деактивировать
+		// Делаю это до деактивации, чтобы потом найти открытые PR
+		deactivatedUserIDs, err := repo.GetUsersByTeamForDeactivation(teamName)
+		if err != nil {
+			return err
+		}
+
+		if len(deactivatedUserIDs) == 0 {
+			return nil
+		}
+		result.DeactivatedUserIDs = deactivatedUserIDs
+
+		// Переназначаю их открытые ревью до деактивации, пока они ещё числятся активными
+		result.ReassignedPRs, result.Reassignments, result.WithoutCandidate, err = s.reassignOpenReviews(repo, deactivatedUserIDs)
+		if err != nil {
+			return err
+		}
+
+		// Теперь можно деактивировать пользователей
+		if _, err := repo.BulkDeactivateUsersByTeam(teamName); err != nil {
+			return err
+		}
+		if err := enqueueUsersDeactivated(repo, teamName, deactivatedUserIDs); err != nil {
+			return err
+		}
+		return repo.BumpTeamVersion(teamName)
+	})
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	if len(deactivatedUserIDs) == 0 {
-		return []string{}, []string{}, nil
-	}
+	return result, nil
+}
+
+// reassignOpenReviews - снимаю пользователей со всех их открытых ревью, подбирая замену в команде
+// автора PR. Общая часть для массовой деактивации команды, списка пользователей и одного пользователя.
+// Кому не нашлось замены, остаётся на PR и попадает в withoutCandidate.
+func (s *Service) reassignOpenReviews(repo *repository.Repository, userIDs []string) (reassignedPRs []string, reassignments, withoutCandidate []models.ReviewerReassignment, err error) {
+	reassignedPRs = []string{}
+	reassignments = []models.ReviewerReassignment{}
+	withoutCandidate = []models.ReviewerReassignment{}
 
 	// Ищу все открытые PR, где эти пользователи назначены ревьюерами
-	// Делаю это до деактивации, чтобы переназначение сработало
-	affectedPRIDs, err := s.repo.GetOpenPRsWithReviewers(deactivatedUserIDs)
+	affectedPRIDs, err := repo.GetOpenPRsWithReviewers(userIDs)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+
+	deactivatedMap := make(map[string]bool)
+	for _, id := range userIDs {
+		deactivatedMap[id] = true
 	}
 
-	// Для каждого открытого PR переназначаю ревьюеров до деактивации
-	reassignedPRs := make([]string, 0)
 	reassignedPRsMap := make(map[string]bool) // Чтобы не дублировать PR в списке
 
 	for _, prID := range affectedPRIDs {
-		pr, err := s.repo.GetPullRequest(prID)
+		if err := repo.LockPullRequest(prID); err != nil {
+			return nil, nil, nil, err
+		}
+
+		pr, err := repo.GetPullRequest(prID)
 		if err != nil {
+			return nil, nil, nil, err
+		}
+		// PR могли смержить, пока я ждал блокировку
+		if pr.Status != models.StatusOpen {
 			continue
 		}
 
 		// Получаю автора, чтобы знать его команду для поиска замены
-		author, err := s.repo.GetUser(pr.AuthorID)
+		author, err := repo.GetUser(pr.AuthorID)
 		if err != nil {
 			continue
 		}
 
-		// Прохожу по всем ревьюерам и переназначаю тех, кого нужно деактивировать
-		for _, reviewerID := range pr.AssignedReviewers {
-			for _, deactivatedID := range deactivatedUserIDs {
-				if reviewerID == deactivatedID {
-					// При массовой деактивации ищу замену в команде автора, а не заменяемого ревьюера
-					// Потому что если деактивируем всю команду, то в ней не будет активных для замены
-					newReviewerID, err := s.reassignReviewerForBulkDeactivation(prID, reviewerID, author.TeamName, pr.AssignedReviewers, pr.AuthorID, deactivatedUserIDs)
-					if err == nil && newReviewerID != "" {
-						// Добавляю PR в список только один раз, даже если там несколько ревьюеров переназначил
-						if !reassignedPRsMap[prID] {
-							reassignedPRs = append(reassignedPRs, prID)
-							reassignedPRsMap[prID] = true
-						}
-					}
-					break
+		// Текущий состав ревьюеров обновляю по ходу, чтобы не назначить одного человека дважды
+		currentReviewers := append([]string{}, pr.AssignedReviewers...)
+
+		for i, reviewerID := range pr.AssignedReviewers {
+			if !deactivatedMap[reviewerID] {
+				continue
+			}
+
+			// Ищу замену в команде автора, а не заменяемого ревьюера.
+			// Потому что если деактивируем всю команду, то в ней не будет активных для замены
+			reassignment := models.ReviewerReassignment{PullRequestID: prID, OldReviewerID: reviewerID}
+			newReviewerID, err := s.reassignReviewerForBulkDeactivation(repo, pr, reviewerID, author.TeamName, currentReviewers, deactivatedMap)
+			if err != nil {
+				if isNoCandidate(err) {
+					// Замены нет - ревьюер остаётся на PR, но показываю это в ответе (и какое правило помешало)
+					reassignment.BlockedBy = blockedRule(err)
+					withoutCandidate = append(withoutCandidate, reassignment)
+					continue
 				}
+				return nil, nil, nil, err
 			}
-		}
-	}
 
-	// Теперь можно деактивировать пользователей
-	_, err = s.repo.BulkDeactivateUsersByTeam(teamName)
-	if err != nil {
-		return nil, nil, err
+			currentReviewers[i] = newReviewerID
+			reassignment.NewReviewerID = newReviewerID
+			reassignments = append(reassignments, reassignment)
+			if !reassignedPRsMap[prID] {
+				reassignedPRs = append(reassignedPRs, prID)
+				reassignedPRsMap[prID] = true
+			}
+		}
 	}
 
-	return deactivatedUserIDs, reassignedPRs, nil
+	return reassignedPRs, reassignments, withoutCandidate, nil
 }
 
 // reassignReviewerForBulkDeactivation - переназначение при массовой деактивации
 // Ищу замену в команде автора, потому что в команде заменяемого ревьюера все будут деактивированы
-func (s *Service) reassignReviewerForBulkDeactivation(prID, oldReviewerID, authorTeamName string, currentReviewers []string, authorID string, deactivatedUserIDs []string) (string, error) {
+func (s *Service) reassignReviewerForBulkDeactivation(repo *repository.Repository, pr *models.PullRequest, oldReviewerID, authorTeamName string, currentReviewers []string, deactivatedMap map[string]bool) (string, error) {
+	prID, authorID := pr.PullRequestID, pr.AuthorID
+
 	// Ищу кандидатов в команде автора, как при создании PR
-	candidates, err := s.repo.GetActiveUsersByTeam(authorTeamName, authorID)
+	candidates, err := repo.GetActiveUsersByTeam(authorTeamName, authorID)
 	if err != nil {
 		return "", err
 	}
 
-	// Убираю тех, кто уже назначен на этот PR
-	availableCandidates := s.filterAssignedReviewers(candidates, currentReviewers, authorID)
-	
-	// И еще убираю тех, кого собираюсь деактивировать
-	deactivatedMap := make(map[string]bool)
-	for _, id := range deactivatedUserIDs {
-		deactivatedMap[id] = true
+	// Убираю тех, кто уже назначен на этот PR или отказался от него
+	unavailable, err := unavailableReviewers(repo, prID, currentReviewers)
+	if err != nil {
+		return "", err
 	}
-	
+	availableCandidates := s.filterAssignedReviewers(candidates, unavailable, authorID)
+
+	// И еще убираю тех, кого собираюсь деактивировать
 	finalCandidates := make([]*models.User, 0)
 	for _, candidate := range availableCandidates {
 		if !deactivatedMap[candidate.UserID] {
 			finalCandidates = append(finalCandidates, candidate)
 		}
 	}
-	
+
 	if len(finalCandidates) == 0 {
 		return "", fmt.Errorf("NO_CANDIDATE")
 	}
 
-	// Выбираю случайного из оставшихся
-	newReviewerID := s.selectRandomReviewer(finalCandidates)
+	// Выбираю случайного из оставшихся (для urgent - самого свободного) с учётом правил команды автора
+	selected, blockedBy, err := s.selectReviewers(repo, pr, finalCandidates, withoutString(currentReviewers, oldReviewerID), 1)
+	if err != nil {
+		return "", err
+	}
+	if len(selected) == 0 {
+		return "", noCandidate(blockedBy)
+	}
+	newReviewerID := selected[0]
 
 	// Обновляю в базе
-	if err := s.repo.ReassignReviewer(prID, oldReviewerID, newReviewerID); err != nil {
+	if err := repo.ReassignReviewer(prID, oldReviewerID, newReviewerID); err != nil {
+		return "", err
+	}
+	if err := recordReassignment(repo, prID, oldReviewerID, newReviewerID); err != nil {
 		return "", err
 	}
 
 	return newReviewerID, nil
 }
 
-// --- Вспомогательные методы ---
+// Idempotency keys - тонкая обёртка над репозиторием для middleware в handlers
 
-// selectRandomReviewers - выбираю случайных ревьюеров из списка кандидатов
-func (s *Service) selectRandomReviewers(candidates []*models.User, maxCount int) []string {
-	if len(candidates) == 0 {
-		return []string{}
-	}
+func (s *Service) ClaimIdempotencyKey(key, route, requestHash string, ttl time.Duration) (bool, error) {
+	return s.repo.ClaimIdempotencyKey(key, route, requestHash, ttl)
+}
 
-	count := maxCount
-	if len(candidates) < maxCount {
-		count = len(candidates)
-	}
+func (s *Service) GetIdempotencyRecord(key, route string) (*models.IdempotencyRecord, error) {
+	return s.repo.GetIdempotencyRecord(key, route)
+}
 
-	// Перемешиваю кандидатов, чтобы выбор был случайным
-	mixedCandidates := make([]*models.User, len(candidates))
-	copy(mixedCandidates, candidates)
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(mixedCandidates), func(i, j int) {
-		mixedCandidates[i], mixedCandidates[j] = mixedCandidates[j], mixedCandidates[i]
-	})
+func (s *Service) SaveIdempotencyResponse(key, route string, statusCode int, body []byte, headers map[string]string) error {
+	return s.repo.SaveIdempotencyResponse(key, route, statusCode, body, headers)
+}
 
-	// Беру первых N из перемешанного списка
-	reviewers := make([]string, 0, count)
-	for i := 0; i < count; i++ {
-		reviewers = append(reviewers, mixedCandidates[i].UserID)
-	}
+// ReleaseIdempotencyKey - освобождаю ключ, если запрос упал с 5xx, чтобы повтор выполнился заново
+func (s *Service) ReleaseIdempotencyKey(key, route string) error {
+	return s.repo.DeleteIdempotencyKey(key, route)
+}
 
-	return reviewers
+func (s *Service) CleanupIdempotencyKeys() (int64, error) {
+	return s.repo.DeleteExpiredIdempotencyKeys()
 }
 
-func (s *Service) selectRandomReviewer(candidates []*models.User) string {
-	if len(candidates) == 0 {
-		return ""
+// --- Вспомогательные методы ---
+
+// checkVersion - сверяю версии из If-Match с текущей: должна совпасть хотя бы одна, nil означает "не проверять"
+func checkVersion(expected []int, actual int) error {
+	if expected == nil {
+		return nil
+	}
+	for _, version := range expected {
+		if version == actual {
+			return nil
+		}
 	}
-	rand.Seed(time.Now().UnixNano())
-	return candidates[rand.Intn(len(candidates))].UserID
+	return fmt.Errorf("VERSION_MISMATCH")
 }
 
 // filterAssignedReviewers - убираю из кандидатов тех, кто уже назначен на PR, и автора
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"fmt"
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+	"strconv"
+)
+
+// SetTeamSLA - SLA команды на первое ревью. first_review_hours = 0 выключает SLA.
+// Без action по умолчанию только уведомляю.
+func (s *Service) SetTeamSLA(sla *models.TeamSLA) (*models.TeamSLA, error) {
+	if err := normalizeTeamSLA(sla); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetTeam(sla.TeamName); err != nil {
+		return nil, err
+	}
+	if err := saveTeamSLA(s.repo, sla); err != nil {
+		return nil, err
+	}
+	return sla, nil
+}
+
+// normalizeTeamSLA - проверяю SLA и подставляю действие по умолчанию. Общее для /team/setSLA и импорта.
+func normalizeTeamSLA(sla *models.TeamSLA) error {
+	if sla.FirstReviewHours < 0 {
+		return fmt.Errorf("invalid first_review_hours: must not be negative")
+	}
+	if sla.Action == "" {
+		sla.Action = models.SLANotify
+	}
+	switch sla.Action {
+	case models.SLANotify, models.SLAAddReviewer, models.SLAReassign:
+	default:
+		return fmt.Errorf("invalid action: must be notify, add_reviewer or reassign")
+	}
+	return nil
+}
+
+// saveTeamSLA - first_review_hours = 0 удаляет SLA
+func saveTeamSLA(repo *repository.Repository, sla *models.TeamSLA) error {
+	if sla.FirstReviewHours == 0 {
+		return repo.DeleteTeamSLA(sla.TeamName)
+	}
+	return repo.SetTeamSLA(sla)
+}
+
+func (s *Service) GetTeamSLA(teamName string) (*models.TeamSLA, error) {
+	if _, err := s.repo.GetTeam(teamName); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTeamSLA(teamName)
+}
+
+// EscalateOverdueReviews - один проход планировщика: каждый открытый PR, нарушивший SLA,
+// эскалирую в своей транзакции, чтобы ошибка на одном не откатывала остальные.
+// Возвращаю все сделанные эскалации и первую ошибку, если она была.
+func (s *Service) EscalateOverdueReviews() ([]*models.Escalation, error) {
+	breaches, err := s.repo.GetSLABreaches("")
+	if err != nil {
+		return nil, err
+	}
+
+	escalations := make([]*models.Escalation, 0)
+	var firstErr error
+	for _, breach := range breaches {
+		done, err := s.escalate(breach.PullRequestID)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("escalation of %s: %w", breach.PullRequestID, err)
+			}
+			continue
+		}
+		escalations = append(escalations, done...)
+	}
+	return escalations, firstErr
+}
+
+// escalate - выполняю действие из SLA команды над одним PR и записываю эскалации.
+// Нарушение перепроверяю под блокировкой PR: пока я до него дошёл, его могли отревьюить,
+// смержить или эскалировать с другого экземпляра сервиса.
+func (s *Service) escalate(prID string) ([]*models.Escalation, error) {
+	var escalations []*models.Escalation
+
+	err := s.runInTx(false, func(repo *repository.Repository) error {
+		escalations = nil
+
+		if err := repo.LockPullRequest(prID); err != nil {
+			return err
+		}
+		breaches, err := repo.GetSLABreaches(prID)
+		if err != nil || len(breaches) == 0 {
+			return err
+		}
+		breach := breaches[0]
+
+		pr, err := repo.GetPullRequest(prID)
+		if err != nil {
+			return err
+		}
+
+		reason := fmt.Sprintf("no first review within %sh", strconv.FormatFloat(breach.FirstReviewHours, 'f', -1, 64))
+		// На PR уже maxReviewers ревьюеров - добавлять некуда, поэтому вместо add_reviewer
+		// меняю тех, кто ничего не сделал, а если менять некого, только уведомляю.
+		// В эскалацию пишу то действие, которое реально выполнил.
+		action := breach.Action
+		if action == models.SLAAddReviewer && len(pr.AssignedReviewers) >= maxReviewers {
+			action = models.SLAReassign
+			reason += "; reviewers full, add_reviewer fell back to reassign"
+		}
+		newEscalation := func(action models.SLAAction) *models.Escalation {
+			return &models.Escalation{PullRequestID: prID, Action: action, Reason: reason}
+		}
+
+		var stale []string
+		if action == models.SLAReassign {
+			stale, err = repo.GetStaleReviewers(prID, breach.FirstReviewHours)
+			if err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case action == models.SLANotify,
+			// Всех ревьюеров недавно поменяли вручную - ещё раз их не трогаю, только уведомляю
+			action == models.SLAReassign && len(stale) == 0 && len(pr.AssignedReviewers) > 0:
+			escalations = append(escalations, newEscalation(models.SLANotify))
+
+		case len(stale) > 0:
+			for _, reviewerID := range stale {
+				escalation := newEscalation(models.SLAReassign)
+				escalation.ReviewerID = reviewerID
+				escalation.NewReviewerID, err = s.replaceReviewer(repo, pr, reviewerID)
+				if err != nil {
+					if !isNoCandidate(err) {
+						return err
+					}
+					escalation.Error = err.Error()
+				}
+				escalations = append(escalations, escalation)
+
+				// Следующую замену выбираю уже с учётом только что назначенного
+				if pr, err = repo.GetPullRequest(prID); err != nil {
+					return err
+				}
+			}
+
+		default:
+			// add_reviewer, а также reassign на PR, где вообще нет ревьюеров:
+			// тогда помочь может только ещё один ревьюер
+			escalation := newEscalation(models.SLAAddReviewer)
+			escalation.NewReviewerID, err = s.addReviewerFromAuthorTeam(repo, pr)
+			if err != nil {
+				if !isNoCandidate(err) {
+					return err
+				}
+				escalation.Error = err.Error()
+			}
+			escalations = append(escalations, escalation)
+		}
+
+		for _, escalation := range escalations {
+			if err := repo.CreateEscalation(escalation); err != nil {
+				return err
+			}
+			if err := enqueueOutbox(repo, models.AggregatePullRequest, prID, models.OutboxReviewEscalated, escalation); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return escalations, nil
+}
+
+// addReviewerFromAuthorTeam - добавляю к PR ещё одного активного ревьюера из команды автора,
+// выбранного так же, как при создании PR. Больше maxReviewers не добавляю.
+func (s *Service) addReviewerFromAuthorTeam(repo *repository.Repository, pr *models.PullRequest) (string, error) {
+	if len(pr.AssignedReviewers) >= maxReviewers {
+		return "", fmt.Errorf("REVIEWERS_FULL")
+	}
+	author, err := repo.GetUser(pr.AuthorID)
+	if err != nil {
+		return "", err
+	}
+	candidates, err := repo.GetActiveUsersByTeam(author.TeamName, pr.AuthorID)
+	if err != nil {
+		return "", err
+	}
+	unavailable, err := unavailableReviewers(repo, pr.PullRequestID, pr.AssignedReviewers)
+	if err != nil {
+		return "", err
+	}
+	candidates = s.filterAssignedReviewers(candidates, unavailable, pr.AuthorID)
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("NO_CANDIDATE")
+	}
+
+	selected, blockedBy, err := s.selectReviewers(repo, pr, candidates, pr.AssignedReviewers, 1)
+	if err != nil {
+		return "", err
+	}
+	if len(selected) == 0 {
+		return "", noCandidate(blockedBy)
+	}
+	reviewerID := selected[0]
+	if err := repo.AddReviewer(pr.PullRequestID, reviewerID); err != nil {
+		return "", err
+	}
+	if err := recordEvents(repo, models.UserEventAssigned, pr.PullRequestID, reviewerID); err != nil {
+		return "", err
+	}
+	return reviewerID, nil
+}
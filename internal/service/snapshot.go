@@ -0,0 +1,368 @@
+package service
+
+import (
+	"fmt"
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+	"time"
+)
+
+// ExportSnapshot - все команды, пользователи и PR с ревьюерами, а также профили пользователей,
+// правила подбора, SLA, политики брошенных PR и подписки на сводку. Читаю в одной транзакции,
+// чтобы PR и настройки не ссылались на пользователей, которых в выгрузке ещё нет.
+func (s *Service) ExportSnapshot() (*models.Snapshot, error) {
+	var snapshot *models.Snapshot
+	err := s.runInTx(false, func(repo *repository.Repository) error {
+		teams, err := repo.GetAllTeams()
+		if err != nil {
+			return err
+		}
+
+		prs := make([]*models.PullRequest, 0)
+		err = repo.StreamPullRequests(models.PullRequestFilter{}, func(pr *models.PullRequest) error {
+			prs = append(prs, pr)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		snapshot = &models.Snapshot{
+			FormatVersion: models.SnapshotFormatVersion,
+			ExportedAt:    time.Now().UTC(),
+			Teams:         teams,
+			PullRequests:  prs,
+		}
+		if snapshot.Profiles, err = repo.GetUserProfiles(); err != nil {
+			return err
+		}
+		if snapshot.ReviewRules, err = repo.GetAllReviewRules(); err != nil {
+			return err
+		}
+		if snapshot.SLAs, err = repo.GetAllTeamSLAs(); err != nil {
+			return err
+		}
+		if snapshot.StalePolicies, err = repo.GetAllStalePolicies(); err != nil {
+			return err
+		}
+		snapshot.DigestPreferences, err = repo.GetAllDigestPreferences()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// ImportSnapshot - загружаю выгрузку целиком в одной транзакции: либо всё, либо ничего.
+// mode решает, что делать с командами и PR, которые уже есть:
+// fail - откатить всё с TEAM_EXISTS/PR_EXISTS, skip - оставить как есть, overwrite - перезаписать.
+// Профили, настройки команд и подписки на сводку загружаю только для команд, которые создал
+// или перезаписал (и их участников); то, чего в выгрузке нет, остаётся как было.
+func (s *Service) ImportSnapshot(snapshot *models.Snapshot, mode models.ImportMode) (*models.ImportResult, error) {
+	switch mode {
+	case models.ImportModeFail, models.ImportModeSkip, models.ImportModeOverwrite:
+	default:
+		return nil, fmt.Errorf("invalid mode: must be fail, skip or overwrite")
+	}
+	if err := validateSnapshot(snapshot); err != nil {
+		return nil, err
+	}
+
+	var result *models.ImportResult
+	err := s.runInTx(false, func(repo *repository.Repository) error {
+		result = &models.ImportResult{Mode: mode}
+		importedTeams := make(map[string]bool)
+		importedUsers := make(map[string]bool)
+
+		for _, team := range snapshot.Teams {
+			exists, err := repo.TeamExists(team.TeamName)
+			if err != nil {
+				return err
+			}
+
+			if exists {
+				switch mode {
+				case models.ImportModeFail:
+					return fmt.Errorf("TEAM_EXISTS: %s", team.TeamName)
+				case models.ImportModeSkip:
+					result.TeamsSkipped++
+					continue
+				}
+				if err := repo.LockTeam(team.TeamName); err != nil {
+					return err
+				}
+				if err := repo.BumpTeamVersion(team.TeamName); err != nil {
+					return err
+				}
+				result.TeamsUpdated++
+			} else {
+				if err := repo.CreateTeam(team.TeamName); err != nil {
+					return err
+				}
+				result.TeamsCreated++
+			}
+
+			// Как и в /team/add: участники создаются или переезжают в эту команду
+			if err := upsertTeamMembers(repo, team); err != nil {
+				return err
+			}
+			result.UsersImported += len(team.Members)
+			importedTeams[team.TeamName] = true
+			for _, member := range team.Members {
+				importedUsers[member.UserID] = true
+			}
+		}
+
+		// Профили - до правил: require_senior без seniors проверяется по уровням участников
+		for _, profile := range snapshot.Profiles {
+			if !importedUsers[profile.UserID] {
+				continue
+			}
+			if err := repo.SetUserProfile(profile.UserID, profile.Seniority, profile.Skills); err != nil {
+				return err
+			}
+			result.SettingsImported++
+		}
+
+		for _, pr := range snapshot.PullRequests {
+			// Автор и ревьюеры должны быть либо в выгрузке, либо уже в базе
+			for _, userID := range append([]string{pr.AuthorID}, pr.AssignedReviewers...) {
+				if _, err := repo.GetUser(userID); err != nil {
+					return fmt.Errorf("invalid snapshot: pull request %s references unknown user %s", pr.PullRequestID, userID)
+				}
+			}
+
+			exists, err := repo.PullRequestExists(pr.PullRequestID)
+			if err != nil {
+				return err
+			}
+			if exists {
+				switch mode {
+				case models.ImportModeFail:
+					return fmt.Errorf("PR_EXISTS: %s", pr.PullRequestID)
+				case models.ImportModeSkip:
+					result.PRsSkipped++
+					continue
+				}
+				if err := repo.LockPullRequest(pr.PullRequestID); err != nil {
+					return err
+				}
+				result.PRsUpdated++
+			} else {
+				result.PRsCreated++
+			}
+
+			if err := repo.ImportPullRequest(pr); err != nil {
+				return err
+			}
+		}
+
+		return importSettings(repo, snapshot, importedTeams, importedUsers, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// importSettings - правила подбора, SLA, политики брошенных PR и подписки на сводку
+// загруженных команд и пользователей. Правила проверяю так же, как в /team/setReviewRules.
+func importSettings(repo *repository.Repository, snapshot *models.Snapshot, importedTeams, importedUsers map[string]bool, result *models.ImportResult) error {
+	for _, rules := range snapshot.ReviewRules {
+		if !importedTeams[rules.TeamName] {
+			continue
+		}
+		if err := saveReviewRules(repo, rules); err != nil {
+			return err
+		}
+		result.SettingsImported++
+	}
+	for _, sla := range snapshot.SLAs {
+		if !importedTeams[sla.TeamName] {
+			continue
+		}
+		if err := saveTeamSLA(repo, sla); err != nil {
+			return err
+		}
+		result.SettingsImported++
+	}
+	for _, policy := range snapshot.StalePolicies {
+		if !importedTeams[policy.TeamName] {
+			continue
+		}
+		if err := saveStalePolicy(repo, policy); err != nil {
+			return err
+		}
+		result.SettingsImported++
+	}
+	for _, prefs := range snapshot.DigestPreferences {
+		if !importedUsers[prefs.UserID] {
+			continue
+		}
+		// Включённую сводку считаю только что отправленной, как и при обычной подписке
+		if err := repo.SetDigestPreferences(prefs.UserID, prefs.Email, prefs.DigestEnabled, time.Now().UTC()); err != nil {
+			return err
+		}
+		result.SettingsImported++
+	}
+	return nil
+}
+
+// validateSnapshot - проверяю выгрузку до того, как лезть в базу
+func validateSnapshot(snapshot *models.Snapshot) error {
+	if snapshot.FormatVersion != models.SnapshotFormatVersion {
+		return fmt.Errorf("invalid snapshot: unsupported format_version %d, expected %d", snapshot.FormatVersion, models.SnapshotFormatVersion)
+	}
+
+	teams := make(map[string]bool)
+	users := make(map[string]bool)
+	for _, team := range snapshot.Teams {
+		if team == nil || team.TeamName == "" {
+			return fmt.Errorf("invalid snapshot: team_name is required")
+		}
+		if teams[team.TeamName] {
+			return fmt.Errorf("invalid snapshot: duplicate team %s", team.TeamName)
+		}
+		teams[team.TeamName] = true
+
+		for _, member := range team.Members {
+			if member.UserID == "" || member.Username == "" {
+				return fmt.Errorf("invalid snapshot: user_id and username are required in team %s", team.TeamName)
+			}
+			if users[member.UserID] {
+				return fmt.Errorf("invalid snapshot: user %s is listed more than once", member.UserID)
+			}
+			users[member.UserID] = true
+		}
+	}
+
+	prs := make(map[string]bool)
+	for _, pr := range snapshot.PullRequests {
+		if pr == nil || pr.PullRequestID == "" || pr.PullRequestName == "" || pr.AuthorID == "" {
+			return fmt.Errorf("invalid snapshot: pull_request_id, pull_request_name and author_id are required")
+		}
+		if prs[pr.PullRequestID] {
+			return fmt.Errorf("invalid snapshot: duplicate pull request %s", pr.PullRequestID)
+		}
+		prs[pr.PullRequestID] = true
+
+		switch pr.Status {
+		case models.StatusOpen:
+			if pr.MergedAt != nil {
+				return fmt.Errorf("invalid snapshot: open pull request %s has mergedAt", pr.PullRequestID)
+			}
+		case models.StatusMerged, models.StatusClosed:
+		default:
+			return fmt.Errorf("invalid snapshot: pull request %s has unknown status %q", pr.PullRequestID, pr.Status)
+		}
+		if _, err := normalizePriority(pr.Priority); err != nil {
+			return fmt.Errorf("invalid snapshot: pull request %s has unknown priority %q", pr.PullRequestID, pr.Priority)
+		}
+
+		// Те же правила, что и при обычном назначении: не больше двух, без автора и без повторов
+		if len(pr.AssignedReviewers) > 2 {
+			return fmt.Errorf("invalid snapshot: pull request %s has more than 2 reviewers", pr.PullRequestID)
+		}
+		seen := make(map[string]bool)
+		for _, reviewerID := range pr.AssignedReviewers {
+			if reviewerID == pr.AuthorID {
+				return fmt.Errorf("invalid snapshot: author of pull request %s is its reviewer", pr.PullRequestID)
+			}
+			if seen[reviewerID] {
+				return fmt.Errorf("invalid snapshot: pull request %s lists reviewer %s twice", pr.PullRequestID, reviewerID)
+			}
+			seen[reviewerID] = true
+		}
+	}
+	return validateSnapshotSettings(snapshot, teams, users)
+}
+
+// validateSnapshotSettings - профили, настройки и подписки ссылаются только на команды
+// и пользователей из выгрузки, по одной записи на каждого и с теми же проверками, что в API
+func validateSnapshotSettings(snapshot *models.Snapshot, teams, users map[string]bool) error {
+	seenUsers := make(map[string]bool)
+	for _, profile := range snapshot.Profiles {
+		if profile == nil {
+			return fmt.Errorf("invalid snapshot: empty profile")
+		}
+		if !users[profile.UserID] {
+			return fmt.Errorf("invalid snapshot: profile of user %q that is not in teams", profile.UserID)
+		}
+		if seenUsers[profile.UserID] {
+			return fmt.Errorf("invalid snapshot: profile of user %s is listed more than once", profile.UserID)
+		}
+		seenUsers[profile.UserID] = true
+		if err := normalizeUserProfile(profile); err != nil {
+			return fmt.Errorf("invalid snapshot: user %s: %v", profile.UserID, err)
+		}
+	}
+
+	seenUsers = make(map[string]bool)
+	for _, prefs := range snapshot.DigestPreferences {
+		if prefs == nil {
+			return fmt.Errorf("invalid snapshot: empty digest preferences")
+		}
+		if !users[prefs.UserID] {
+			return fmt.Errorf("invalid snapshot: digest preferences of user %q that is not in teams", prefs.UserID)
+		}
+		if seenUsers[prefs.UserID] {
+			return fmt.Errorf("invalid snapshot: digest preferences of user %s are listed more than once", prefs.UserID)
+		}
+		seenUsers[prefs.UserID] = true
+		if err := normalizeDigestPreferences(prefs); err != nil {
+			return fmt.Errorf("invalid snapshot: user %s: %v", prefs.UserID, err)
+		}
+	}
+
+	// Для каждой команды - не больше одной записи каждого вида
+	checkTeam := func(kind, teamName string, seen map[string]bool) error {
+		if !teams[teamName] {
+			return fmt.Errorf("invalid snapshot: %s of team %q that is not in teams", kind, teamName)
+		}
+		if seen[teamName] {
+			return fmt.Errorf("invalid snapshot: %s of team %s is listed more than once", kind, teamName)
+		}
+		seen[teamName] = true
+		return nil
+	}
+	seenTeams := make(map[string]bool)
+	for _, rules := range snapshot.ReviewRules {
+		if rules == nil {
+			return fmt.Errorf("invalid snapshot: empty review rules")
+		}
+		if err := checkTeam("review rules", rules.TeamName, seenTeams); err != nil {
+			return err
+		}
+		if err := normalizeReviewRules(rules); err != nil {
+			return fmt.Errorf("invalid snapshot: team %s: %v", rules.TeamName, err)
+		}
+	}
+	seenTeams = make(map[string]bool)
+	for _, sla := range snapshot.SLAs {
+		if sla == nil {
+			return fmt.Errorf("invalid snapshot: empty sla")
+		}
+		if err := checkTeam("sla", sla.TeamName, seenTeams); err != nil {
+			return err
+		}
+		if err := normalizeTeamSLA(sla); err != nil {
+			return fmt.Errorf("invalid snapshot: team %s: %v", sla.TeamName, err)
+		}
+	}
+	seenTeams = make(map[string]bool)
+	for _, policy := range snapshot.StalePolicies {
+		if policy == nil {
+			return fmt.Errorf("invalid snapshot: empty stale policy")
+		}
+		if err := checkTeam("stale policy", policy.TeamName, seenTeams); err != nil {
+			return err
+		}
+		if err := normalizeStalePolicy(policy); err != nil {
+			return fmt.Errorf("invalid snapshot: team %s: %v", policy.TeamName, err)
+		}
+	}
+	return nil
+}
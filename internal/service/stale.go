@@ -0,0 +1,166 @@
+package service
+
+import (
+	"fmt"
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+	"time"
+)
+
+// SetStalePolicy - политика команды для брошенных PR. warn_after_days = 0 выключает политику.
+func (s *Service) SetStalePolicy(policy *models.StalePolicy) (*models.StalePolicy, error) {
+	if err := normalizeStalePolicy(policy); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetTeam(policy.TeamName); err != nil {
+		return nil, err
+	}
+	if err := saveStalePolicy(s.repo, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// normalizeStalePolicy - проверяю сроки и чищу метки. Общее для /team/setStalePolicy и импорта.
+func normalizeStalePolicy(policy *models.StalePolicy) error {
+	if policy.WarnAfterDays < 0 || policy.CloseAfterDays < 0 {
+		return fmt.Errorf("invalid policy: days must not be negative")
+	}
+	if policy.CloseAfterDays > 0 && policy.CloseAfterDays <= policy.WarnAfterDays {
+		return fmt.Errorf("invalid policy: close_after_days must be greater than warn_after_days")
+	}
+	labels, err := normalizeLabels(policy.ExcludedLabels)
+	if err != nil {
+		return err
+	}
+	policy.ExcludedLabels = labels
+	return nil
+}
+
+// saveStalePolicy - warn_after_days = 0 удаляет политику
+func saveStalePolicy(repo *repository.Repository, policy *models.StalePolicy) error {
+	if policy.WarnAfterDays == 0 {
+		return repo.DeleteStalePolicy(policy.TeamName)
+	}
+	return repo.SetStalePolicy(policy)
+}
+
+func (s *Service) GetStalePolicy(teamName string) (*models.StalePolicy, error) {
+	if _, err := s.repo.GetTeam(teamName); err != nil {
+		return nil, err
+	}
+	return s.repo.GetStalePolicy(teamName)
+}
+
+// ListStalePullRequests - брошенные PR по политикам команд. Пустой teamName - по всем командам.
+func (s *Service) ListStalePullRequests(teamName string) ([]*models.StalePullRequest, error) {
+	if teamName != "" {
+		if _, err := s.repo.GetTeam(teamName); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.GetStalePullRequests(teamName, "")
+}
+
+// SweepStalePullRequests - один проход политики: PR, которые пора закрыть, закрываю,
+// про остальные брошенные предупреждаю (один раз, пока на PR снова что-нибудь не произойдёт).
+// Каждый PR - в своей транзакции, ошибка на одном не мешает остальным.
+func (s *Service) SweepStalePullRequests() (*models.StaleSweepResult, error) {
+	stale, err := s.repo.GetStalePullRequests("", "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.StaleSweepResult{Warned: []string{}, Closed: []string{}}
+	var firstErr error
+	for _, pr := range stale {
+		var action string
+		switch {
+		case pr.ClosesAt != nil && !pr.ClosesAt.After(time.Now()):
+			action, err = s.closeStalePullRequest(pr.PullRequestID)
+		case pr.WarnedAt == nil:
+			action, err = s.warnStalePullRequest(pr.PullRequestID)
+		default:
+			continue
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("stale policy for %s: %w", pr.PullRequestID, err)
+			}
+			continue
+		}
+
+		switch action {
+		case "closed":
+			result.Closed = append(result.Closed, pr.PullRequestID)
+		case "warned":
+			result.Warned = append(result.Warned, pr.PullRequestID)
+		}
+	}
+	return result, firstErr
+}
+
+// lockStalePullRequest - блокирую PR и перепроверяю, что он всё ещё брошенный:
+// пока шёл проход, на нём могли что-то сделать. nil - трогать его уже не нужно.
+func lockStalePullRequest(repo *repository.Repository, prID string) (*models.StalePullRequest, error) {
+	if err := repo.LockPullRequest(prID); err != nil {
+		return nil, err
+	}
+	stale, err := repo.GetStalePullRequests("", prID)
+	if err != nil || len(stale) == 0 {
+		return nil, err
+	}
+	return stale[0], nil
+}
+
+func (s *Service) warnStalePullRequest(prID string) (string, error) {
+	var action string
+	err := s.runInTx(false, func(repo *repository.Repository) error {
+		action = ""
+
+		stale, err := lockStalePullRequest(repo, prID)
+		if err != nil || stale == nil || stale.WarnedAt != nil {
+			return err
+		}
+
+		if err := repo.MarkStaleWarned(prID); err != nil {
+			return err
+		}
+		if err := enqueueOutbox(repo, models.AggregatePullRequest, prID, models.OutboxPRStaleWarning, stale); err != nil {
+			return err
+		}
+		action = "warned"
+		return nil
+	})
+	return action, err
+}
+
+func (s *Service) closeStalePullRequest(prID string) (string, error) {
+	var action string
+	err := s.runInTx(false, func(repo *repository.Repository) error {
+		action = ""
+
+		stale, err := lockStalePullRequest(repo, prID)
+		if err != nil || stale == nil || stale.ClosesAt == nil || stale.ClosesAt.After(time.Now()) {
+			return err
+		}
+
+		if err := repo.ClosePullRequest(prID); err != nil {
+			return err
+		}
+		closed, err := repo.GetPullRequest(prID)
+		if err != nil {
+			return err
+		}
+		if err := recordEvents(repo, models.UserEventClosed, prID, closed.AssignedReviewers...); err != nil {
+			return err
+		}
+		if err := enqueuePullRequestEvent(repo, models.OutboxPRClosed, closed); err != nil {
+			return err
+		}
+		action = "closed"
+		return nil
+	})
+	return action, err
+}
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"pr-reviewer-service/internal/models"
+	"time"
+)
+
+// maxSeriesBuckets - больше бакетов за раз не отдаю, чтобы случайный запрос за десять лет по дням не положил базу
+const maxSeriesBuckets = 366
+
+// Statistics - просто собираю статистику из репозитория
+func (s *Service) GetStatistics(filter models.StatisticsFilter) (*models.StatisticsResponse, error) {
+	if err := validateStatisticsFilter(filter); err != nil {
+		return nil, err
+	}
+
+	userStats, err := s.repo.GetUserReviewStats(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	prStats, err := s.repo.GetPRStats(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Преобразую указатели в значения для ответа
+	userStatsValues := make([]models.UserReviewStats, len(userStats))
+	for i, stat := range userStats {
+		userStatsValues[i] = *stat
+	}
+
+	return &models.StatisticsResponse{
+		UserStats: userStatsValues,
+		PRStats:   *prStats,
+	}, nil
+}
+
+// StreamUserStats - статистика по пользователям построчно, для выгрузки в CSV/NDJSON
+func (s *Service) StreamUserStats(filter models.StatisticsFilter, fn func(stat *models.UserReviewStats) error) error {
+	if err := validateStatisticsFilter(filter); err != nil {
+		return err
+	}
+	return s.repo.StreamUserReviewStats(filter, fn)
+}
+
+// GetTimeSeries - PR и назначения по дням или неделям
+func (s *Service) GetTimeSeries(interval string, filter models.StatisticsFilter) (*models.TimeSeriesResponse, error) {
+	from, to, err := resolveSeriesRange(interval, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets, err := s.repo.GetTimeSeries(interval, from, to, filter.TeamName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TimeSeriesResponse{
+		Interval: interval,
+		From:     from,
+		To:       to,
+		TeamName: filter.TeamName,
+		Buckets:  buckets,
+	}, nil
+}
+
+// GetReviewerAssignmentSeries - назначения по каждому ревьюеру по дням или неделям
+func (s *Service) GetReviewerAssignmentSeries(interval string, filter models.StatisticsFilter) (*models.ReviewerAssignmentSeriesResponse, error) {
+	from, to, err := resolveSeriesRange(interval, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	reviewers, err := s.repo.GetReviewerAssignmentSeries(interval, from, to, filter.TeamName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ReviewerAssignmentSeriesResponse{
+		Interval:  interval,
+		From:      from,
+		To:        to,
+		TeamName:  filter.TeamName,
+		Reviewers: reviewers,
+	}, nil
+}
+
+// GetTeamStatistics - разбивка по командам за период
+func (s *Service) GetTeamStatistics(filter models.StatisticsFilter) ([]*models.TeamStats, error) {
+	if err := validateStatisticsFilter(filter); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTeamStats(filter)
+}
+
+func validateStatisticsFilter(filter models.StatisticsFilter) error {
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return fmt.Errorf("invalid range: from must be before to")
+	}
+	return nil
+}
+
+// resolveSeriesRange - проверяю интервал и подставляю период по умолчанию:
+// последние 30 дней для day и последние 12 недель для week.
+func resolveSeriesRange(interval string, filter models.StatisticsFilter) (time.Time, time.Time, error) {
+	var step time.Duration
+	switch interval {
+	case "day":
+		step = 24 * time.Hour
+	case "week":
+		step = 7 * 24 * time.Hour
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid interval: must be day or week")
+	}
+
+	to := time.Now().UTC()
+	if filter.To != nil {
+		to = filter.To.UTC()
+	}
+
+	from := to.Add(-30 * 24 * time.Hour)
+	if interval == "week" {
+		from = to.Add(-12 * 7 * 24 * time.Hour)
+	}
+	if filter.From != nil {
+		from = filter.From.UTC()
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid range: from must be before to")
+	}
+	if to.Sub(from)/step > maxSeriesBuckets {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid range: too many buckets, max %d", maxSeriesBuckets)
+	}
+	return from, to, nil
+}
+
+// maxLeaderboardLimit - верхняя граница для limit в лидерборде
+const maxLeaderboardLimit = 100
+
+// GetTurnaround - время до мержа по командам и авторам и время ответа ревьюеров
+func (s *Service) GetTurnaround(filter models.StatisticsFilter) (*models.TurnaroundResponse, error) {
+	if err := validateStatisticsFilter(filter); err != nil {
+		return nil, err
+	}
+
+	overall, err := s.repo.GetTimeToMerge(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	byTeam, err := s.repo.GetTimeToMergeByTeam(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	byAuthor, err := s.repo.GetTimeToMergeByAuthor(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	byReviewer, err := s.repo.GetReviewerResponseTimes(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TurnaroundResponse{
+		From:        filter.From,
+		To:          filter.To,
+		TeamName:    filter.TeamName,
+		TimeToMerge: *overall,
+		ByTeam:      byTeam,
+		ByAuthor:    byAuthor,
+		ByReviewer:  byReviewer,
+	}, nil
+}
+
+// GetLeaderboard - топ ревьюеров за период
+func (s *Service) GetLeaderboard(filter models.StatisticsFilter, limit int) ([]*models.LeaderboardEntry, error) {
+	if err := validateStatisticsFilter(filter); err != nil {
+		return nil, err
+	}
+	if limit < 1 || limit > maxLeaderboardLimit {
+		return nil, fmt.Errorf("invalid limit: must be between 1 and %d", maxLeaderboardLimit)
+	}
+	return s.repo.GetReviewLeaderboard(filter, limit)
+}
+
+const (
+	// overloadRatio и underloadRatio - во сколько раз назначения должны отличаться от ожидаемых,
+	// чтобы считать участника выбросом
+	overloadRatio  = 1.5
+	underloadRatio = 0.5
+	// minExpectedForOutlier - при совсем маленьком ожидании любое отклонение выглядит выбросом, такие не помечаю
+	minExpectedForOutlier = 1.0
+)
+
+// GetFairness - насколько равномерно распределены назначения внутри команд.
+// Ожидаемое число назначений участника пропорционально времени, когда он был активен.
+// По умолчанию смотрю последние 30 дней.
+func (s *Service) GetFairness(filter models.StatisticsFilter) (*models.FairnessResponse, error) {
+	if err := validateStatisticsFilter(filter); err != nil {
+		return nil, err
+	}
+
+	to := time.Now().UTC()
+	if filter.To != nil {
+		to = filter.To.UTC()
+	}
+	from := to.Add(-30 * 24 * time.Hour)
+	if filter.From != nil {
+		from = filter.From.UTC()
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("invalid range: from must be before to")
+	}
+
+	loads, err := s.repo.GetMemberLoad(from, to, filter.TeamName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Строки отсортированы по команде, собираю команды за один проход
+	response := &models.FairnessResponse{From: from, To: to, Teams: []*models.TeamFairness{}}
+	var teamLoads []*models.MemberLoad
+	for i, load := range loads {
+		teamLoads = append(teamLoads, load)
+		if i == len(loads)-1 || loads[i+1].TeamName != load.TeamName {
+			response.Teams = append(response.Teams, buildTeamFairness(load.TeamName, teamLoads))
+			teamLoads = nil
+		}
+	}
+
+	return response, nil
+}
+
+func buildTeamFairness(teamName string, loads []*models.MemberLoad) *models.TeamFairness {
+	team := &models.TeamFairness{TeamName: teamName, Members: make([]*models.MemberFairness, 0, len(loads))}
+
+	totalHours := 0.0
+	for _, load := range loads {
+		team.TotalAssignments += load.Assignments
+		totalHours += load.ActiveHours
+	}
+
+	// Для Джини беру назначения на час активности, иначе тот, кто был в отпуске, выглядит недогруженным
+	var rates []float64
+	for _, load := range loads {
+		member := &models.MemberFairness{
+			UserID:      load.UserID,
+			Username:    load.Username,
+			IsActive:    load.IsActive,
+			Assignments: load.Assignments,
+			ActiveHours: load.ActiveHours,
+		}
+		if team.TotalAssignments > 0 {
+			member.AssignmentShare = float64(load.Assignments) / float64(team.TotalAssignments)
+		}
+		if totalHours > 0 {
+			member.ActiveTimeShare = load.ActiveHours / totalHours
+		}
+		member.ExpectedAssignments = member.ActiveTimeShare * float64(team.TotalAssignments)
+
+		if member.ExpectedAssignments > 0 {
+			ratio := float64(load.Assignments) / member.ExpectedAssignments
+			member.LoadRatio = &ratio
+			if member.ExpectedAssignments >= minExpectedForOutlier {
+				switch {
+				case ratio >= overloadRatio:
+					member.Outlier = "OVERLOADED"
+				case ratio <= underloadRatio:
+					member.Outlier = "UNDERLOADED"
+				}
+			}
+		}
+		if load.ActiveHours > 0 {
+			rates = append(rates, float64(load.Assignments)/load.ActiveHours)
+		}
+
+		team.Members = append(team.Members, member)
+	}
+
+	team.Gini = gini(rates)
+	return team
+}
+
+// gini - коэффициент Джини: 0 - все получают поровну, ближе к 1 - всё достаётся одному
+func gini(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	if sum == 0 {
+		return 0
+	}
+
+	diffs := 0.0
+	for _, a := range values {
+		for _, b := range values {
+			diffs += math.Abs(a - b)
+		}
+	}
+	return diffs / (2 * float64(len(values)) * sum)
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"pr-reviewer-service/internal/models"
+	"pr-reviewer-service/internal/repository"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxNameLength - столько влезает в pull_request_name и target_branch
+	maxNameLength = 255
+	// maxDescriptionLength - описание длиннее почти наверняка вставлено по ошибке
+	maxDescriptionLength = 10000
+)
+
+// UpdatePullRequest - правлю поля открытого PR: название, описание, ссылку, целевую ветку,
+// метки и приоритет. nil в update - поле не трогаю. Каждую правку, которая что-то поменяла,
+// записываю в журнал. Ревьюеров заново не выбираю: новые приоритет и метки действуют
+// при следующих назначениях и заменах. expectedVersions приходят из If-Match (подходит любая), nil - не проверять.
+func (s *Service) UpdatePullRequest(prID string, update models.PullRequestUpdate, expectedVersions []int) (*models.PullRequest, error) {
+	if err := normalizeUpdate(&update); err != nil {
+		return nil, err
+	}
+
+	var updated *models.PullRequest
+	err := s.runInTx(false, func(repo *repository.Repository) error {
+		updated = nil
+
+		if err := repo.LockPullRequest(prID); err != nil {
+			return err
+		}
+
+		pr, err := repo.GetPullRequest(prID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(expectedVersions, pr.Version); err != nil {
+			return err
+		}
+		if pr.Status == models.StatusMerged {
+			return fmt.Errorf("PR_MERGED")
+		}
+		if pr.Status == models.StatusClosed {
+			return fmt.Errorf("PR_CLOSED")
+		}
+		if update.ChangedBy != "" {
+			if _, err := repo.GetUser(update.ChangedBy); err != nil {
+				return fmt.Errorf("user not found")
+			}
+		}
+
+		changes := applyUpdate(pr, update)
+		// Ничего по факту не поменялось - версию не поднимаю и журнал не засоряю
+		if len(changes) == 0 {
+			updated = pr
+			return nil
+		}
+
+		if err := repo.UpdatePullRequest(pr); err != nil {
+			return err
+		}
+		err = repo.CreateAuditEntry(&models.PullRequestAuditEntry{
+			PullRequestID: prID,
+			ChangedBy:     update.ChangedBy,
+			Changes:       changes,
+		})
+		if err != nil {
+			return err
+		}
+
+		updated, err = repo.GetPullRequest(prID)
+		if err != nil {
+			return err
+		}
+		return enqueuePullRequestEvent(repo, models.OutboxPRUpdated, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// normalizeUpdate - проверяю и привожу к единому виду всё, что пришло в update
+func normalizeUpdate(update *models.PullRequestUpdate) error {
+	if update.Name == nil && update.Description == nil && update.URL == nil && update.TargetBranch == nil &&
+		update.Labels == nil && update.Priority == nil {
+		return fmt.Errorf("invalid update: nothing to change")
+	}
+
+	if update.Name != nil {
+		name := strings.TrimSpace(*update.Name)
+		if name == "" || len(name) > maxNameLength {
+			return fmt.Errorf("invalid name: must be 1 to %d characters", maxNameLength)
+		}
+		update.Name = &name
+	}
+	if update.Description != nil {
+		description := strings.TrimSpace(*update.Description)
+		if len(description) > maxDescriptionLength {
+			return fmt.Errorf("invalid description: longer than %d characters", maxDescriptionLength)
+		}
+		update.Description = &description
+	}
+	if update.URL != nil {
+		link := strings.TrimSpace(*update.URL)
+		if link != "" {
+			parsed, err := url.Parse(link)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return fmt.Errorf("invalid url: must be an absolute http(s) URL")
+			}
+		}
+		update.URL = &link
+	}
+	if update.TargetBranch != nil {
+		branch := strings.TrimSpace(*update.TargetBranch)
+		if !validBranchName(branch) {
+			return fmt.Errorf("invalid target_branch: %q is not a valid branch name", branch)
+		}
+		update.TargetBranch = &branch
+	}
+	if update.Labels != nil {
+		labels, err := normalizeLabels(*update.Labels)
+		if err != nil {
+			return err
+		}
+		update.Labels = &labels
+	}
+	if update.Priority != nil {
+		priority, err := normalizePriority(*update.Priority)
+		if err != nil {
+			return err
+		}
+		update.Priority = &priority
+	}
+	return nil
+}
+
+// validBranchName - основные правила git check-ref-format. Пустая ветка - "не указана".
+func validBranchName(branch string) bool {
+	if branch == "" {
+		return true
+	}
+	if len(branch) > maxNameLength || strings.HasPrefix(branch, "-") || strings.HasPrefix(branch, "/") ||
+		strings.HasSuffix(branch, "/") || strings.HasSuffix(branch, ".") || strings.HasSuffix(branch, ".lock") ||
+		strings.Contains(branch, "..") || strings.Contains(branch, "//") || strings.Contains(branch, "@{") {
+		return false
+	}
+	for _, r := range branch {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune("~^:?*[\\", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyUpdate - переношу update в pr и возвращаю, что реально поменялось
+func applyUpdate(pr *models.PullRequest, update models.PullRequestUpdate) map[string]models.FieldChange {
+	changes := make(map[string]models.FieldChange)
+	setString := func(field string, target *string, value *string) {
+		if value == nil || *target == *value {
+			return
+		}
+		changes[field] = models.FieldChange{Old: *target, New: *value}
+		*target = *value
+	}
+
+	setString("pull_request_name", &pr.PullRequestName, update.Name)
+	setString("description", &pr.Description, update.Description)
+	setString("url", &pr.URL, update.URL)
+	setString("target_branch", &pr.TargetBranch, update.TargetBranch)
+
+	if update.Labels != nil && !equalStrings(pr.Labels, *update.Labels) {
+		changes["labels"] = models.FieldChange{Old: pr.Labels, New: *update.Labels}
+		pr.Labels = *update.Labels
+	}
+	if update.Priority != nil && pr.Priority != *update.Priority {
+		changes["priority"] = models.FieldChange{Old: pr.Priority, New: *update.Priority}
+		pr.Priority = *update.Priority
+	}
+	return changes
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,4 @@
+ALTER TABLE pull_requests
+    ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE teams
+    ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
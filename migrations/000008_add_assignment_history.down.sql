@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS reviewer_reassignments;
+DROP INDEX IF EXISTS idx_pull_request_reviewers_assigned_at;
+ALTER TABLE pull_request_reviewers
+    DROP COLUMN IF EXISTS assigned_at;
@@ -0,0 +1,22 @@
+ALTER TABLE pull_request_reviewers
+    ADD COLUMN IF NOT EXISTS assigned_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+-- Для уже существующих назначений лучшее, что есть, - время создания PR
+UPDATE pull_request_reviewers prr
+SET assigned_at = pr.created_at
+FROM pull_requests pr
+WHERE prr.pull_request_id = pr.pull_request_id AND pr.created_at IS NOT NULL;
+
+CREATE INDEX idx_pull_request_reviewers_assigned_at ON pull_request_reviewers(assigned_at);
+
+CREATE TABLE IF NOT EXISTS reviewer_reassignments (
+    id BIGSERIAL PRIMARY KEY,
+    pull_request_id VARCHAR(255) NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+    old_reviewer_id VARCHAR(255) NOT NULL REFERENCES users(user_id) ON DELETE RESTRICT,
+    new_reviewer_id VARCHAR(255) NOT NULL REFERENCES users(user_id) ON DELETE RESTRICT,
+    old_assigned_at TIMESTAMP NOT NULL,
+    reassigned_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_reviewer_reassignments_pull_request_id ON reviewer_reassignments(pull_request_id);
+CREATE INDEX idx_reviewer_reassignments_reassigned_at ON reviewer_reassignments(reassigned_at);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS users_track_activity ON users;
+DROP FUNCTION IF EXISTS track_user_activity();
+DROP TABLE IF EXISTS user_activity_periods;
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS user_activity_periods (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ended_at TIMESTAMP
+);
+
+CREATE INDEX idx_user_activity_periods_user_id ON user_activity_periods(user_id);
+
+-- Историю до этой миграции не знаю, поэтому считаю активных активными с момента создания
+INSERT INTO user_activity_periods (user_id, started_at)
+SELECT user_id, COALESCE(created_at, CURRENT_TIMESTAMP) FROM users WHERE is_active;
+
+-- Периоды веду триггером, чтобы не зависеть от того, какой код меняет is_active
+CREATE OR REPLACE FUNCTION track_user_activity() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        IF NEW.is_active THEN
+            INSERT INTO user_activity_periods (user_id) VALUES (NEW.user_id);
+        END IF;
+    ELSIF NEW.is_active IS DISTINCT FROM OLD.is_active THEN
+        IF NEW.is_active THEN
+            INSERT INTO user_activity_periods (user_id) VALUES (NEW.user_id);
+        ELSE
+            UPDATE user_activity_periods SET ended_at = CURRENT_TIMESTAMP
+            WHERE user_id = NEW.user_id AND ended_at IS NULL;
+        END IF;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_track_activity
+    AFTER INSERT OR UPDATE OF is_active ON users
+    FOR EACH ROW EXECUTE FUNCTION track_user_activity();
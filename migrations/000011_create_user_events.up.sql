@@ -0,0 +1,11 @@
+-- События ревьюера для /users/stream: назначили, сняли, PR смержили.
+-- По id клиент продолжает поток после переподключения (Last-Event-ID).
+CREATE TABLE IF NOT EXISTS user_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    event_type VARCHAR(32) NOT NULL CHECK (event_type IN ('assigned', 'unassigned', 'merged')),
+    pull_request_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_user_events_user_id ON user_events(user_id, id);
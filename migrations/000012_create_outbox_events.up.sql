@@ -0,0 +1,17 @@
+-- Transactional outbox: доменные события пишутся в той же транзакции, что и изменения,
+-- а отдельный релей рассылает их по синкам. Пока событие не опубликовано, published_at пустой.
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    aggregate_type VARCHAR(32) NOT NULL,
+    aggregate_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    published_at TIMESTAMP
+);
+
+-- Релей смотрит только на неопубликованные события, поэтому индекс частичный
+CREATE INDEX idx_outbox_events_pending ON outbox_events(aggregate_type, aggregate_id, id) WHERE published_at IS NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS digest_last_sent_at;
+ALTER TABLE users DROP COLUMN IF EXISTS digest_enabled;
+ALTER TABLE users DROP COLUMN IF EXISTS email;
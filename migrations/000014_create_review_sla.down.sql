@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS review_escalations;
+DROP TABLE IF EXISTS team_review_sla;
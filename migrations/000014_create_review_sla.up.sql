@@ -0,0 +1,22 @@
+-- SLA на первое ревью по командам: за сколько часов PR должен получить первое действие ревьюера
+-- и что делать, если не получил
+CREATE TABLE IF NOT EXISTS team_review_sla (
+    team_name VARCHAR(255) PRIMARY KEY REFERENCES teams(team_name) ON DELETE CASCADE,
+    first_review_hours DOUBLE PRECISION NOT NULL CHECK (first_review_hours > 0),
+    action VARCHAR(32) NOT NULL CHECK (action IN ('notify', 'add_reviewer', 'reassign')),
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Каждая эскалация по PR. После эскалации отсчёт SLA начинается заново.
+CREATE TABLE IF NOT EXISTS review_escalations (
+    id BIGSERIAL PRIMARY KEY,
+    pull_request_id VARCHAR(255) NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+    action VARCHAR(32) NOT NULL,
+    reviewer_id VARCHAR(255) REFERENCES users(user_id) ON DELETE RESTRICT,
+    new_reviewer_id VARCHAR(255) REFERENCES users(user_id) ON DELETE RESTRICT,
+    reason TEXT NOT NULL,
+    error VARCHAR(64),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_review_escalations_pull_request_id ON review_escalations(pull_request_id, created_at);
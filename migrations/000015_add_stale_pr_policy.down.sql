@@ -0,0 +1,17 @@
+DELETE FROM user_events WHERE event_type = 'closed';
+ALTER TABLE user_events DROP CONSTRAINT IF EXISTS user_events_event_type_check;
+ALTER TABLE user_events ADD CONSTRAINT user_events_event_type_check CHECK (event_type IN ('assigned', 'unassigned', 'merged'));
+
+DROP TABLE IF EXISTS team_stale_policy;
+
+DROP INDEX IF EXISTS idx_pull_requests_labels;
+DROP INDEX IF EXISTS idx_pull_requests_open_last_activity;
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS stale_warned_at;
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS last_activity_at;
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS labels;
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS closed_at;
+
+-- Закрытые PR в старой схеме не выразить, считаю их открытыми
+UPDATE pull_requests SET status = 'OPEN' WHERE status = 'CLOSED';
+ALTER TABLE pull_requests DROP CONSTRAINT IF EXISTS pull_requests_status_check;
+ALTER TABLE pull_requests ADD CONSTRAINT pull_requests_status_check CHECK (status IN ('OPEN', 'MERGED'));
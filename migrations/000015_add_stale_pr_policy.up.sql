@@ -0,0 +1,35 @@
+-- Брошенные PR: статус CLOSED, время последней активности, метки для исключений
+-- и политика команды, когда предупреждать и когда закрывать
+ALTER TABLE pull_requests DROP CONSTRAINT IF EXISTS pull_requests_status_check;
+ALTER TABLE pull_requests ADD CONSTRAINT pull_requests_status_check CHECK (status IN ('OPEN', 'MERGED', 'CLOSED'));
+
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS closed_at TIMESTAMP;
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS labels TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS last_activity_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS stale_warned_at TIMESTAMP;
+
+-- Для существующих PR активность - самое позднее из того, что про них известно
+UPDATE pull_requests pr
+SET last_activity_at = GREATEST(
+    COALESCE(pr.created_at, CURRENT_TIMESTAMP),
+    pr.merged_at,
+    (SELECT MAX(prr.assigned_at) FROM pull_request_reviewers prr WHERE prr.pull_request_id = pr.pull_request_id),
+    (SELECT MAX(rr.reassigned_at) FROM reviewer_reassignments rr WHERE rr.pull_request_id = pr.pull_request_id),
+    (SELECT MAX(ra.created_at) FROM review_actions ra WHERE ra.pull_request_id = pr.pull_request_id)
+);
+
+CREATE INDEX idx_pull_requests_open_last_activity ON pull_requests(last_activity_at) WHERE status = 'OPEN';
+CREATE INDEX idx_pull_requests_labels ON pull_requests USING GIN (labels);
+
+CREATE TABLE IF NOT EXISTS team_stale_policy (
+    team_name VARCHAR(255) PRIMARY KEY REFERENCES teams(team_name) ON DELETE CASCADE,
+    warn_after_days INTEGER NOT NULL CHECK (warn_after_days > 0),
+    -- 0 - не закрывать автоматически
+    close_after_days INTEGER NOT NULL DEFAULT 0 CHECK (close_after_days = 0 OR close_after_days > warn_after_days),
+    excluded_labels TEXT[] NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Ревьюерам в /users/stream тоже сообщаю, что PR закрыт
+ALTER TABLE user_events DROP CONSTRAINT IF EXISTS user_events_event_type_check;
+ALTER TABLE user_events ADD CONSTRAINT user_events_event_type_check CHECK (event_type IN ('assigned', 'unassigned', 'merged', 'closed'));
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_pull_requests_priority;
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS priority;
@@ -0,0 +1,5 @@
+-- Приоритет PR: по нему фильтрую списки, а для urgent выбираю самых свободных ревьюеров
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS priority VARCHAR(16) NOT NULL DEFAULT 'normal'
+    CHECK (priority IN ('low', 'normal', 'high', 'urgent'));
+
+CREATE INDEX idx_pull_requests_priority ON pull_requests(priority);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS pull_request_audit;
+
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS target_branch;
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS url;
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS description;
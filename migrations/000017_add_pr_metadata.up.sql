@@ -0,0 +1,15 @@
+-- Редактируемые поля PR и журнал их изменений
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS url TEXT NOT NULL DEFAULT '';
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS target_branch VARCHAR(255) NOT NULL DEFAULT '';
+
+-- changes - {"поле": {"old": ..., "new": ...}} только по тому, что реально поменялось
+CREATE TABLE IF NOT EXISTS pull_request_audit (
+    id BIGSERIAL PRIMARY KEY,
+    pull_request_id VARCHAR(255) NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+    changed_by VARCHAR(255) REFERENCES users(user_id) ON DELETE SET NULL,
+    changes JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_pull_request_audit_pr ON pull_request_audit(pull_request_id, id);
@@ -0,0 +1,13 @@
+-- Отказы ревьюеров от PR. Отказавшегося больше не назначаю на этот PR автоматически.
+-- replaced_by пустой, если замены не нашлось и PR остался с needMoreReviewers.
+CREATE TABLE IF NOT EXISTS review_declines (
+    id BIGSERIAL PRIMARY KEY,
+    pull_request_id VARCHAR(255) NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+    reviewer_id VARCHAR(255) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    replaced_by VARCHAR(255) REFERENCES users(user_id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_review_declines_pr ON review_declines(pull_request_id, id);
+CREATE INDEX idx_review_declines_reviewer ON review_declines(reviewer_id);
@@ -0,0 +1,12 @@
+-- Правила подбора ревьюеров для PR авторов команды:
+-- never_review - [{"user_a": ..., "user_b": ...}], эти двое не ревьюят друг друга;
+-- preferred_pairs - [{"reviewer_id": ..., "author_id": ...}], по возможности ставлю reviewer_id на PR author_id;
+-- require_senior - среди ревьюеров должен быть хотя бы один из seniors.
+CREATE TABLE IF NOT EXISTS team_review_rules (
+    team_name VARCHAR(255) PRIMARY KEY REFERENCES teams(team_name) ON DELETE CASCADE,
+    never_review JSONB NOT NULL DEFAULT '[]',
+    preferred_pairs JSONB NOT NULL DEFAULT '[]',
+    seniors TEXT[] NOT NULL DEFAULT '{}',
+    require_senior BOOLEAN NOT NULL DEFAULT false,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
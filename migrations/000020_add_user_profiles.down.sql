@@ -0,0 +1,4 @@
+ALTER TABLE team_review_rules DROP COLUMN IF EXISTS match_skills;
+
+ALTER TABLE users DROP COLUMN IF EXISTS skills;
+ALTER TABLE users DROP COLUMN IF EXISTS seniority;
@@ -0,0 +1,7 @@
+-- Профиль пользователя для подбора ревьюеров: уровень (пусто - не указан) и навыки
+ALTER TABLE users ADD COLUMN IF NOT EXISTS seniority VARCHAR(16) NOT NULL DEFAULT ''
+    CHECK (seniority IN ('', 'junior', 'middle', 'senior', 'lead'));
+ALTER TABLE users ADD COLUMN IF NOT EXISTS skills TEXT[] NOT NULL DEFAULT '{}';
+
+-- match_skills - при автоподборе первыми беру тех, чьи навыки совпали с метками PR
+ALTER TABLE team_review_rules ADD COLUMN IF NOT EXISTS match_skills BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_user_events_stream_seq;
+DROP INDEX IF EXISTS idx_user_events_user_stream_seq;
+ALTER TABLE user_events DROP COLUMN IF EXISTS stream_seq;
+DROP SEQUENCE IF EXISTS user_events_stream_seq;
@@ -0,0 +1,11 @@
+-- stream_seq - позиция события в потоке /users/stream. В отличие от id, её выдают прямо перед
+-- коммитом под advisory-блокировкой, поэтому она растёт в порядке коммитов, а не вставок.
+-- Старым событиям оставляю их id, чтобы сохранённые клиентами Last-Event-ID продолжили работать.
+CREATE SEQUENCE IF NOT EXISTS user_events_stream_seq;
+ALTER TABLE user_events ADD COLUMN IF NOT EXISTS stream_seq BIGINT;
+UPDATE user_events SET stream_seq = id WHERE stream_seq IS NULL;
+SELECT setval('user_events_stream_seq', GREATEST((SELECT MAX(id) FROM user_events), 1));
+ALTER TABLE user_events ALTER COLUMN stream_seq SET DEFAULT nextval('user_events_stream_seq');
+ALTER TABLE user_events ALTER COLUMN stream_seq SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_events_stream_seq ON user_events(stream_seq);
+CREATE INDEX IF NOT EXISTS idx_user_events_user_stream_seq ON user_events(user_id, stream_seq);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_outbox_events_published;
+DROP INDEX IF EXISTS idx_outbox_events_pending;
+CREATE INDEX idx_outbox_events_pending ON outbox_events(aggregate_type, aggregate_id, id) WHERE published_at IS NULL;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS dead_at;
@@ -0,0 +1,8 @@
+-- dead_at - событие так и не ушло за outboxMaxAttempts попыток. Релей его больше не берёт
+-- и не держит за ним остальные события агрегата, а last_error остаётся для разбора.
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS dead_at TIMESTAMP;
+
+DROP INDEX IF EXISTS idx_outbox_events_pending;
+CREATE INDEX idx_outbox_events_pending ON outbox_events(aggregate_type, aggregate_id, id) WHERE published_at IS NULL AND dead_at IS NULL;
+-- Для чистки старых опубликованных событий
+CREATE INDEX IF NOT EXISTS idx_outbox_events_published ON outbox_events(published_at) WHERE published_at IS NOT NULL;